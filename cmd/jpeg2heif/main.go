@@ -1,25 +1,62 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/ah-its-andy/jpeg2heif/internal/api"
+	"github.com/ah-its-andy/jpeg2heif/internal/blobstore"
+	"github.com/ah-its-andy/jpeg2heif/internal/cluster"
 	"github.com/ah-its-andy/jpeg2heif/internal/converter"
 	"github.com/ah-its-andy/jpeg2heif/internal/db"
+	"github.com/ah-its-andy/jpeg2heif/internal/lock"
+	"github.com/ah-its-andy/jpeg2heif/internal/logging"
+	"github.com/ah-its-andy/jpeg2heif/internal/metrics"
+	"github.com/ah-its-andy/jpeg2heif/internal/progress"
 	"github.com/ah-its-andy/jpeg2heif/internal/util"
 	"github.com/ah-its-andy/jpeg2heif/internal/watcher"
+	"github.com/ah-its-andy/jpeg2heif/internal/webhook"
 	"github.com/ah-its-andy/jpeg2heif/internal/worker"
+	"github.com/ah-its-andy/jpeg2heif/internal/workflowcache"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "prune-cache" {
+		runPruneCache()
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "--migrate-only" {
+		runMigrateOnly()
+		return
+	}
+	if len(os.Args) > 2 && os.Args[1] == "serve" && os.Args[2] == "stream" {
+		runServeStream(os.Args[3:])
+		return
+	}
+	if len(os.Args) > 2 && os.Args[1] == "workflow-run" && os.Args[2] == "follow" {
+		runWorkflowRunFollow(os.Args[3:])
+		return
+	}
+
 	log.Println("Starting JPEG2HEIF...")
 
+	configFlag := flag.String("config", "", "path to a YAML config file (defaults to CONFIG_PATH)")
+	flag.Parse()
+
 	// Load configuration
-	cfg := util.LoadConfig()
+	configPath := util.ResolveConfigPath(*configFlag)
+	cfg := util.LoadConfig(configPath)
 	log.Printf("Configuration loaded:")
 	log.Printf("  Watch Dirs: %v", cfg.WatchDirs)
 	log.Printf("  DB Path: %s", cfg.DBPath)
@@ -27,10 +64,16 @@ func main() {
 	log.Printf("  Max Workers: %d", cfg.MaxWorkers)
 	log.Printf("  Quality: %d", cfg.ConvertQuality)
 	log.Printf("  Preserve Metadata: %t", cfg.PreserveMetadata)
+	log.Printf("  Workflow Concurrency: %d", cfg.WorkflowConcurrency)
 
 	// Check external tools
 	checkExternalTools()
 
+	// Build the structured logger shared by every component, formatted and
+	// filtered per LOG_FORMAT/LOG_LEVEL.
+	baseLogger := logging.New(os.Stdout, cfg.LogFormat, logging.ParseLevel(cfg.LogLevel))
+	converter.SetLogger(baseLogger.With("component", "converter"))
+
 	// Register builtin converters based on environment variable
 	converter.RegisterBuiltinConverters()
 
@@ -47,14 +90,67 @@ func main() {
 		log.Printf("Warning: failed to load workflow converters: %v", err)
 	}
 
+	if cfg.CacheEnabled {
+		cache, err := workflowcache.New(database, cfg.CacheDir, int64(cfg.CacheMaxSizeMB)*1024*1024)
+		if err != nil {
+			log.Printf("Warning: failed to initialize workflow cache: %v", err)
+		} else {
+			converter.SetWorkflowCache(cache)
+			log.Printf("Workflow result cache enabled at %s (max %d MB)", cfg.CacheDir, cfg.CacheMaxSizeMB)
+		}
+	}
+
+	if cfg.BlobStoreEnabled {
+		store, err := blobstore.New(cfg.BlobStoreDir)
+		if err != nil {
+			log.Printf("Warning: failed to initialize blob store: %v", err)
+		} else {
+			converter.SetBlobStore(store)
+			log.Printf("Blob store enabled at %s", cfg.BlobStoreDir)
+		}
+	}
+
 	// Create watcher
-	w, err := watcher.New(cfg.WatchDirs, cfg.MetadataStabilityDelay, cfg.PollInterval)
+	watchFilter := watcher.FilterConfig{
+		Extensions:   cfg.WatchExtensions,
+		IgnoreHidden: cfg.WatchIgnoreHidden,
+		IgnoreGlobs:  cfg.WatchIgnoreGlobs,
+	}
+	w, err := watcher.New(cfg.WatchDirs, cfg.MetadataStabilityDelay, cfg.PollInterval, watcher.Mode(cfg.WatcherMode), watchFilter)
 	if err != nil {
 		log.Fatalf("Failed to create watcher: %v", err)
 	}
 
+	w.SetLogger(baseLogger.With("component", "watcher"))
+
 	// Create worker pool
-	wrk := worker.New(database, cfg.MaxWorkers, cfg.ConvertQuality, cfg.PreserveMetadata, cfg.MD5ChunkSize)
+	wrk := worker.New(database, cfg.MaxWorkers, cfg.ConvertQuality, cfg.PreserveMetadata, cfg.MD5ChunkSize, cfg.HashAlgorithm)
+	wrk.SetReporter(newReporterFromEnv())
+	wrk.SetLogger(baseLogger.With("component", "worker"))
+	wrk.SetStabilityDelay(cfg.MetadataStabilityDelay)
+
+	// Wire up cluster sync if peers are configured, so this node can share
+	// conversions with (and reuse conversions from) the rest of the cluster.
+	var clusterRegistry *cluster.Registry
+	clusterPeers := cluster.ParsePeers(cfg.ClusterPeers)
+	if len(clusterPeers) > 0 {
+		clusterRegistry = cluster.NewRegistry(database, cfg.ClusterNodeID, clusterPeers, cfg.ClusterAuthToken, cfg.ClusterPollInterval)
+		wrk.SetClusterRegistry(clusterRegistry)
+		clusterRegistry.Start()
+		log.Printf("Cluster sync enabled as node %q with %d peer(s)", cfg.ClusterNodeID, len(clusterPeers))
+
+		// With more than one node potentially serving the same watch
+		// directory, the in-process LocalLocker worker.New wires by default
+		// can't prevent two nodes from converting the same file at once;
+		// switch to a lease backed by the shared database instead.
+		wrk.SetLocker(lock.NewSQLiteLocker(database, cfg.ClusterNodeID, cfg.LockLeaseTTL))
+	}
+
+	// Create and start the webhook dispatcher, so file/task/rebuild/workflow
+	// lifecycle events can be delivered to subscriber URLs.
+	dispatcher := webhook.NewDispatcher(database, cfg.WebhookPollInterval)
+	wrk.SetWebhooks(dispatcher)
+	dispatcher.Start()
 
 	// Start worker pool
 	wrk.Start()
@@ -71,14 +167,86 @@ func main() {
 		}
 	}()
 
+	// Re-enqueue anything left in "processing" from an unclean shutdown (a
+	// crash, or a SIGTERM that outran the worker pool's drain grace period),
+	// so those files aren't stuck there forever.
+	if stale, err := database.ResetStaleProcessing(); err != nil {
+		log.Printf("Warning: failed to reset stale processing files: %v", err)
+	} else if len(stale) > 0 {
+		log.Printf("Resuming %d file(s) left processing by a previous run", len(stale))
+		for _, path := range stale {
+			wrk.EnqueueEvent(watcher.FileEvent{Path: path, Operation: "resume", Timestamp: time.Now()})
+		}
+	}
+
 	// Create and start API server
-	server := api.New(database, wrk, w, cfg.WatchDirs)
+	server := api.New(database, wrk, w, cfg.WatchDirs, cfg.RebuildJobTTL, cfg.EnablePprof)
+	server.SetWebhooks(dispatcher)
+	server.SetLogger(baseLogger.With("component", "api"))
+	server.SetClusterRegistry(clusterRegistry)
+	server.SetWorkflowConcurrency(cfg.WorkflowConcurrency)
+	server.SetWorkflowStepTimeout(cfg.WorkflowStepTimeout)
+	if err := server.RecoverInterruptedRuns(); err != nil {
+		log.Printf("Warning: failed to recover interrupted workflow runs: %v", err)
+	}
+
+	// Wire hot reload: a write to configPath re-resolves defaults, the file,
+	// and env vars exactly as the initial load did, validates the result,
+	// and only then swaps it in, so MaxWorkers, ConvertQuality, WatchDirs
+	// and LogLevel can change without restarting the process.
+	configMgr := util.NewConfigManager(cfg, configPath)
+	configMgr.SetLogger(baseLogger.With("component", "config"))
+	configMgr.Subscribe(func(old, next *util.Config) {
+		if old.LogLevel != next.LogLevel {
+			baseLogger.SetLevel(logging.ParseLevel(next.LogLevel))
+			baseLogger.Info("log level changed", "log_level", next.LogLevel)
+		}
+		if old.ConvertQuality != next.ConvertQuality || old.PreserveMetadata != next.PreserveMetadata {
+			wrk.SetQuality(next.ConvertQuality, next.PreserveMetadata)
+		}
+		if old.MaxWorkers != next.MaxWorkers {
+			wrk.SetMaxWorkers(next.MaxWorkers)
+		}
+		// SetWatchDirs only adds directories new to next.WatchDirs, so calling
+		// it unconditionally on every reload is a no-op when nothing changed.
+		w.SetWatchDirs(next.WatchDirs)
+	})
+	if err := configMgr.WatchFile(); err != nil {
+		log.Printf("Warning: config hot reload disabled: %v", err)
+	}
+	server.SetConfigProvider(configMgr.Current)
+
 	go func() {
 		if err := server.Start(cfg.HTTPPort); err != nil {
 			log.Fatalf("Failed to start API server: %v", err)
 		}
 	}()
 
+	if cfg.MetricsListenPort != 0 {
+		go func() {
+			if err := server.StartMetrics(cfg.MetricsListenPort); err != nil {
+				log.Fatalf("Failed to start metrics server: %v", err)
+			}
+		}()
+		log.Printf("Metrics available at: http://localhost:%d/metrics", cfg.MetricsListenPort)
+	}
+
+	pruneCtx, stopPrune := context.WithCancel(context.Background())
+	if cfg.PruneEnabled {
+		pruneOpts := db.PruneOptions{
+			TaskHistoryMaxAge:      cfg.PruneTaskHistoryMaxAge,
+			TaskHistoryMaxRows:     int64(cfg.PruneTaskHistoryMaxRows),
+			WorkflowRunMaxAge:      cfg.PruneWorkflowRunMaxAge,
+			WorkflowRunMaxRows:     int64(cfg.PruneWorkflowRunMaxRows),
+			WorkflowVersionMaxAge:  cfg.PruneWorkflowVersionMaxAge,
+			WorkflowVersionMaxRows: int64(cfg.PruneWorkflowVersionMaxRows),
+			KeepFailedLonger:       cfg.PruneKeepFailedLonger,
+			VacuumReclaimThreshold: int64(cfg.PruneVacuumReclaimPages),
+		}
+		go database.StartPruneScheduler(pruneCtx, pruneOpts, cfg.PruneInterval)
+		log.Printf("History pruning enabled, running every %v", cfg.PruneInterval)
+	}
+
 	log.Println("JPEG2HEIF is running")
 	log.Printf("Web UI available at: http://localhost:%d/", cfg.HTTPPort)
 
@@ -89,13 +257,196 @@ func main() {
 
 	log.Println("Shutting down...")
 
-	// Stop components
+	// Stop components. server.Shutdown cancels every in-flight
+	// rebuild-index/scan-now job first, so the worker pool isn't still
+	// being fed by a rebuild's filepath.Walk while it's trying to drain.
+	configMgr.Close()
+	server.Shutdown()
 	w.Stop()
-	wrk.Stop()
+	wrk.StopWithTimeout(cfg.ShutdownGracePeriod)
+	dispatcher.Stop()
+	stopPrune()
+	if clusterRegistry != nil {
+		clusterRegistry.Stop()
+	}
 
 	log.Println("Shutdown complete")
 }
 
+// runPruneCache implements `jpeg2heif prune-cache`: it removes visual dedup
+// cache entries whose source file no longer exists on disk, so the cache
+// doesn't keep pointing stale conversions at files that were moved or
+// deleted outside of the watcher.
+func runPruneCache() {
+	cfg := util.LoadConfig("")
+
+	database, err := db.New(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	entries, err := database.ListVisualCacheEntries()
+	if err != nil {
+		log.Fatalf("Failed to list visual cache entries: %v", err)
+	}
+
+	pruned := 0
+	for _, entry := range entries {
+		if _, statErr := os.Stat(entry.SourcePath); os.IsNotExist(statErr) {
+			if err := database.DeleteVisualCacheEntry(entry.ID); err != nil {
+				log.Printf("Failed to prune cache entry %d (%s): %v", entry.ID, entry.SourcePath, err)
+				continue
+			}
+			pruned++
+		}
+	}
+
+	log.Printf("Pruned %d stale cache entries out of %d", pruned, len(entries))
+}
+
+// runMigrateOnly implements `jpeg2heif --migrate-only`: db.New already
+// applies any pending schema migrations when it opens the database, so this
+// just reports the resulting status and exits without starting the
+// watcher, worker pool, or API server. Useful for running migrations as a
+// separate deploy step, decoupled from whether the app itself is allowed to
+// start.
+func runMigrateOnly() {
+	cfg := util.LoadConfig("")
+
+	database, err := db.New(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	applied, pending, err := database.MigrationStatus()
+	if err != nil {
+		log.Fatalf("Failed to read migration status: %v", err)
+	}
+
+	log.Printf("%d migrations applied, %d pending", len(applied), len(pending))
+	for _, m := range pending {
+		log.Printf("  pending: %04d_%s", m.Version, m.Name)
+	}
+}
+
+// runServeStream implements `jpeg2heif serve stream`: it reads a JPEG from
+// stdin, converts it to HEIC via the jpeg2heic converter's streaming path,
+// and writes the HEIC bytes to stdout so the command can be used as a Unix
+// filter (e.g. inside a shell pipeline or a containerized sidecar) without
+// the watcher, database, or API server ever starting up. A JSON metadata
+// summary is written to stderr so stdout stays pure HEIC output.
+func runServeStream(args []string) {
+	fs := flag.NewFlagSet("serve stream", flag.ExitOnError)
+	quality := fs.Int("quality", 85, "HEIC encode quality (1-100)")
+	preserveMetadata := fs.Bool("preserve-metadata", true, "attempt best-effort metadata preservation")
+	_ = fs.Parse(args)
+
+	conv := converter.NewJPEG2HEICConverter()
+	streamConv, ok := interface{}(conv).(converter.StreamConverter)
+	if !ok {
+		log.Fatalf("jpeg2heic converter does not support streaming")
+	}
+
+	result, err := streamConv.ConvertStream(context.Background(), os.Stdin, os.Stdout, converter.ConvertOptions{
+		Quality:          *quality,
+		PreserveMetadata: *preserveMetadata,
+	})
+	if err != nil {
+		log.Fatalf("stream conversion failed: %v", err)
+	}
+
+	summary, _ := json.Marshal(map[string]interface{}{
+		"metadata_preserved": result.MetadataPreserved,
+		"metadata_summary":   result.MetadataSummary,
+	})
+	os.Stderr.Write(summary)
+	os.Stderr.Write([]byte("\n"))
+}
+
+// runWorkflowRunFollow implements `jpeg2heif workflow-run follow`: it
+// subscribes to a running WorkflowRun's SSE progress stream and renders a
+// live progress bar with ETA and the current step name to stdout, so an
+// operator can watch a long-running conversion without polling the
+// workflow-run detail endpoint by hand.
+func runWorkflowRunFollow(args []string) {
+	fs := flag.NewFlagSet("workflow-run follow", flag.ExitOnError)
+	runID := fs.Int64("id", 0, "workflow run ID to follow")
+	host := fs.String("host", "http://localhost:8080", "base URL of the running jpeg2heif API server")
+	_ = fs.Parse(args)
+
+	if *runID == 0 {
+		log.Fatalf("--id is required")
+	}
+
+	url := fmt.Sprintf("%s/api/workflows/runs/%d/stream", *host, *runID)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Fatalf("failed to connect to workflow run stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("workflow run stream returned HTTP %d", resp.StatusCode)
+	}
+
+	startTime := time.Now()
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		data := strings.TrimPrefix(line, "data: ")
+
+		var ev progress.JobEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			continue
+		}
+		printWorkflowRunProgress(ev, startTime)
+
+		if ev.Type == progress.JobEventStateChange && (ev.State == "success" || ev.State == "failed" || ev.State == "cancelled") {
+			return
+		}
+	}
+}
+
+// printWorkflowRunProgress renders a single progress.JobEvent as a
+// terminal progress bar line plus an ETA estimated by projecting the
+// elapsed time out to 100% at the event's reported rate of progress.
+func printWorkflowRunProgress(ev progress.JobEvent, startTime time.Time) {
+	const barWidth = 30
+	filled := int(ev.Percent / 100 * barWidth)
+	if filled > barWidth {
+		filled = barWidth
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+
+	eta := "unknown"
+	if ev.Percent > 0 && ev.Percent < 100 {
+		elapsed := time.Since(startTime)
+		remaining := time.Duration(float64(elapsed) * (100 - ev.Percent) / ev.Percent)
+		eta = remaining.Round(time.Second).String()
+	}
+
+	fmt.Printf("\r[%s] %5.1f%% step=%-20s state=%-8s eta=%s", bar, ev.Percent, ev.Message, ev.State, eta)
+	if ev.State == "success" || ev.State == "failed" || ev.State == "cancelled" {
+		fmt.Println()
+	}
+}
+
+// newReporterFromEnv builds the progress.Reporter used by the worker pool
+// based on the PROGRESS_FORMAT environment variable ("text" or "json").
+// Defaults to a terminal renderer on stdout.
+func newReporterFromEnv() progress.Reporter {
+	switch os.Getenv("PROGRESS_FORMAT") {
+	case "json":
+		return progress.NewJSONReporter(os.Stdout)
+	default:
+		return progress.NewTerminalReporter(os.Stdout)
+	}
+}
+
 // checkExternalTools checks if required external tools are available
 func checkExternalTools() {
 	tools := []string{"heif-enc", "exiftool"}
@@ -104,8 +455,10 @@ func checkExternalTools() {
 	for _, name := range tools {
 		if _, err := exec.LookPath(name); err != nil {
 			log.Printf("  ⚠️  %s: NOT FOUND (required for conversion)", name)
+			metrics.ExternalToolMissing.Set(1, name)
 		} else {
 			log.Printf("  ✅ %s: found", name)
+			metrics.ExternalToolMissing.Set(0, name)
 		}
 	}
 }
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,8 +12,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ah-its-andy/jpeg2heif/internal/cluster"
 	"github.com/ah-its-andy/jpeg2heif/internal/db"
+	"github.com/ah-its-andy/jpeg2heif/internal/livelog"
+	"github.com/ah-its-andy/jpeg2heif/internal/logging"
+	"github.com/ah-its-andy/jpeg2heif/internal/metrics"
+	"github.com/ah-its-andy/jpeg2heif/internal/progress"
+	"github.com/ah-its-andy/jpeg2heif/internal/util"
 	"github.com/ah-its-andy/jpeg2heif/internal/watcher"
+	"github.com/ah-its-andy/jpeg2heif/internal/webhook"
 	"github.com/ah-its-andy/jpeg2heif/internal/worker"
 	"github.com/google/uuid"
 )
@@ -24,43 +33,198 @@ type Server struct {
 	watchDirs     []string
 	rebuildJobs   map[string]*RebuildJob
 	rebuildJobsMu sync.RWMutex
+	rebuildJobTTL time.Duration
+	webhooks      webhook.Publisher
+	logger        logging.Logger
+	enablePprof   bool
+	cluster       *cluster.Registry
+
+	workflowRunCancels   map[int64]context.CancelFunc
+	workflowRunCancelsMu sync.Mutex
+	workflowSem          chan struct{}
+	workflowStepTimeout  time.Duration
+
+	configProvider func() *util.Config
+
+	// jobs tracks every in-flight rebuild-index/scan-now job so Shutdown
+	// can cancel all of them at once; see JobManager.
+	jobs       *JobManager
+	scanJobs   map[string]*Job
+	scanJobsMu sync.RWMutex
 }
 
 // RebuildJob represents a rebuild index job
 type RebuildJob struct {
-	ID        string    `json:"id"`
-	Status    string    `json:"status"` // running, completed, failed
-	StartTime time.Time `json:"start_time"`
-	EndTime   time.Time `json:"end_time,omitempty"`
-	Error     string    `json:"error,omitempty"`
+	ID        string             `json:"id"`
+	Status    string             `json:"status"` // running, cancelling, cancelled, completed, failed
+	StartTime time.Time          `json:"start_time"`
+	EndTime   time.Time          `json:"end_time,omitempty"`
+	Error     string             `json:"error,omitempty"`
+	Cancel    context.CancelFunc `json:"-"`
 }
 
-// New creates a new API server
-func New(database *db.DB, w *worker.Worker, watcher *watcher.Watcher, watchDirs []string) *Server {
+// New creates a new API server. rebuildJobTTL controls how long a
+// completed/failed/cancelled rebuild job's record (and its buffered SSE
+// events) is kept around before the janitor evicts it; pass 0 to disable
+// eviction. enablePprof registers net/http/pprof's handlers on the same
+// mux, for profiling hot paths like the MD5 scanner; leave it false in
+// production unless actively debugging, since pprof exposes stack traces
+// and heap contents.
+func New(database *db.DB, w *worker.Worker, watcher *watcher.Watcher, watchDirs []string, rebuildJobTTL time.Duration, enablePprof bool) *Server {
 	return &Server{
-		db:          database,
-		worker:      w,
-		watcher:     watcher,
-		watchDirs:   watchDirs,
-		rebuildJobs: make(map[string]*RebuildJob),
+		db:                 database,
+		worker:             w,
+		watcher:            watcher,
+		watchDirs:          watchDirs,
+		rebuildJobs:        make(map[string]*RebuildJob),
+		rebuildJobTTL:      rebuildJobTTL,
+		webhooks:           webhook.NopPublisher{},
+		logger:             logging.NewNop(),
+		enablePprof:        enablePprof,
+		workflowRunCancels: make(map[int64]context.CancelFunc),
+		workflowSem:        make(chan struct{}, 4),
+		jobs:               NewJobManager(),
+		scanJobs:           make(map[string]*Job),
 	}
 }
 
+// Shutdown cancels every in-flight rebuild-index and scan-now job (see
+// JobManager.Shutdown), so a SIGINT/SIGTERM handler can stop background
+// API-driven work before it starts waiting on the worker pool to drain the
+// conversion it's currently running. It returns immediately rather than
+// waiting for those jobs' goroutines to finish observing the cancellation.
+func (s *Server) Shutdown() {
+	s.jobs.Shutdown()
+}
+
+// SetWorkflowConcurrency bounds how many workflow runs this server executes
+// at once; a run beyond the limit sits at status "pending" until a slot
+// frees up. Must be called before Start. n <= 0 is ignored, leaving the
+// default of 4.
+func (s *Server) SetWorkflowConcurrency(n int) {
+	if n <= 0 {
+		return
+	}
+	s.workflowSem = make(chan struct{}, n)
+}
+
+// SetWorkflowStepTimeout sets the default per-step timeout applied to a
+// workflow run when a YAML step doesn't set its own Timeout (see
+// workflow.ExecutionContext.DefaultStepTimeout). Must be called before
+// Start. Zero leaves such steps unbounded.
+func (s *Server) SetWorkflowStepTimeout(d time.Duration) {
+	s.workflowStepTimeout = d
+}
+
+// SetConfigProvider wires the function handleConfig uses to fetch the
+// currently-active resolved Config, typically util.ConfigManager.Current.
+// Must be called before Start. Leaving it unset makes GET /api/config
+// respond with 503, rather than panicking.
+func (s *Server) SetConfigProvider(fn func() *util.Config) {
+	s.configProvider = fn
+}
+
+// handleConfig handles GET /api/config, returning the application's
+// currently-active resolved configuration (defaults plus any config file
+// and environment variable overrides), reflecting the latest hot reload if
+// one has occurred. See util.ConfigManager.
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.configProvider == nil {
+		http.Error(w, "Config not available", http.StatusServiceUnavailable)
+		return
+	}
+	respondJSON(w, s.configProvider())
+}
+
+// SetWebhooks wires a webhook.Publisher to receive lifecycle events for
+// rebuild and workflow runs triggered through this API server. Must be
+// called before Start.
+func (s *Server) SetWebhooks(p webhook.Publisher) {
+	if p == nil {
+		p = webhook.NopPublisher{}
+	}
+	s.webhooks = p
+}
+
+// SetLogger wires a structured logging.Logger for this server, used for its
+// per-request access log. Must be called before Start.
+func (s *Server) SetLogger(l logging.Logger) {
+	if l == nil {
+		l = logging.NewNop()
+	}
+	s.logger = l
+}
+
+// SetClusterRegistry wires a cluster.Registry to serve /api/cluster/*
+// requests from peers. Pass nil (the default) to leave cluster sync
+// disabled and those routes unregistered. Must be called before Start.
+func (s *Server) SetClusterRegistry(reg *cluster.Registry) {
+	s.cluster = reg
+}
+
 // Start starts the HTTP server
 func (s *Server) Start(port int) error {
 	mux := http.NewServeMux()
 
 	// API routes
 	mux.HandleFunc("/api/files", s.handleFiles)
-	mux.HandleFunc("/api/files/", s.handleFileDetail)
+	mux.HandleFunc("/api/files/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/metadata") {
+			s.handleFileMetadata(w, r)
+			return
+		}
+		s.handleFileDetail(w, r)
+	})
 	mux.HandleFunc("/api/tasks", s.handleTasks)
-	mux.HandleFunc("/api/tasks/", s.handleTaskDetail)
+	mux.HandleFunc("/api/tasks/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/logs/stream"):
+			s.handleTaskLogsStream(w, r)
+		case strings.HasSuffix(r.URL.Path, "/logs"):
+			s.handleTaskLogs(w, r)
+		case strings.HasSuffix(r.URL.Path, "/stream"):
+			s.handleTaskStream(w, r)
+		default:
+			s.handleTaskDetail(w, r)
+		}
+	})
+	mux.HandleFunc("/api/live-tasks", s.handleLiveTasks)
 	mux.HandleFunc("/api/stats", s.handleStats)
 	mux.HandleFunc("/api/converters", s.handleConverters)
 	mux.HandleFunc("/api/converters/", s.handleConverterDetail)
 	mux.HandleFunc("/api/rebuild-index", s.handleRebuildIndex)
-	mux.HandleFunc("/api/rebuild-status/", s.handleRebuildStatus)
+	mux.HandleFunc("/api/rebuild-status/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/stream"):
+			s.handleRebuildStatusStream(w, r)
+		case strings.HasSuffix(r.URL.Path, "/cancel"):
+			s.handleRebuildCancel(w, r)
+		default:
+			s.handleRebuildStatus(w, r)
+		}
+	})
 	mux.HandleFunc("/api/scan-now", s.handleScanNow)
+	mux.HandleFunc("/api/scan-now/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/cancel"):
+			s.handleScanCancel(w, r)
+		default:
+			s.handleScanStatus(w, r)
+		}
+	})
+	mux.HandleFunc("/api/config", s.handleConfig)
+
+	// Cluster sync routes (see internal/cluster)
+	if s.cluster != nil {
+		mux.HandleFunc("/api/cluster/gossip", s.cluster.HandleGossip)
+		mux.HandleFunc("/api/cluster/index", s.cluster.HandleIndexDelta)
+		mux.HandleFunc("/api/cluster/lookup", s.cluster.HandleLookup)
+		mux.HandleFunc("/api/cluster/fetch", s.cluster.HandleFetch)
+	}
 
 	// Workflow API routes
 	mux.HandleFunc("/api/workflows", s.handleWorkflows)
@@ -68,23 +232,109 @@ func (s *Server) Start(port int) error {
 		path := r.URL.Path
 		if strings.Contains(path, "/validate") {
 			s.handleWorkflowValidate(w, r)
-		} else if strings.Contains(path, "/run") {
-			s.handleWorkflowRun(w, r)
+		} else if strings.HasSuffix(path, "/cancel") {
+			s.handleWorkflowRunCancel(w, r)
+		} else if strings.HasSuffix(path, "/pause") {
+			s.handleWorkflowRunPause(w, r)
+		} else if strings.HasSuffix(path, "/resume") {
+			s.handleWorkflowRunResume(w, r)
+		} else if strings.HasSuffix(path, "/stream") {
+			s.handleWorkflowRunStream(w, r)
 		} else if strings.Contains(path, "/runs/") {
 			s.handleWorkflowRunDetail(w, r)
 		} else if strings.HasSuffix(path, "/runs") {
 			s.handleWorkflowRuns(w, r)
+		} else if strings.Contains(path, "/run") {
+			s.handleWorkflowRun(w, r)
 		} else {
 			s.handleWorkflowDetail(w, r)
 		}
 	})
 
+	// Webhook API routes
+	mux.HandleFunc("/api/webhooks", s.handleWebhooks)
+	mux.HandleFunc("/api/webhooks/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/deliveries") {
+			s.handleWebhookDeliveries(w, r)
+		} else {
+			s.handleWebhookDetail(w, r)
+		}
+	})
+
+	// Metrics
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	if s.enablePprof {
+		registerPprofRoutes(mux)
+	}
+
 	// Static files
 	mux.Handle("/", http.FileServer(http.Dir("static")))
 
 	addr := fmt.Sprintf(":%d", port)
 	log.Printf("Starting HTTP server on %s", addr)
-	return http.ListenAndServe(addr, s.corsMiddleware(mux))
+	return http.ListenAndServe(addr, s.accessLogMiddleware(s.corsMiddleware(mux)))
+}
+
+// requestIDKey is the context key under which the current request's
+// correlation id is stored.
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request id attached by
+// accessLogMiddleware, or "" if none is present (e.g. outside a request).
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// statusRecorder captures the status code and bytes written so
+// accessLogMiddleware can log them after the handler returns.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// accessLogMiddleware assigns a request id (honoring an incoming
+// X-Request-ID header if present), attaches it to the request context, and
+// emits one structured access log entry per request via s.logger.
+func (s *Server) accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+		r = r.WithContext(ctx)
+
+		rec := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+
+		s.logger.Info("http request",
+			"request_id", requestID,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	})
 }
 
 // corsMiddleware adds CORS headers
@@ -159,6 +409,33 @@ func (s *Server) handleFileDetail(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "File not found", http.StatusNotFound)
 }
 
+// handleFileMetadata handles GET /api/files/{id}/metadata, returning the
+// tag-by-tag metadata preservation diff recorded for that file's most
+// recent successful conversion (see db.FileMetadata). An empty array means
+// either the file hasn't converted successfully yet, or its converter
+// doesn't support tag-level comparison.
+func (s *Server) handleFileMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimSuffix(r.URL.Path[len("/api/files/"):], "/metadata")
+	fileIndexID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid file ID", http.StatusBadRequest)
+		return
+	}
+
+	tags, err := s.db.ListFileMetadata(fileIndexID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, tags)
+}
+
 // handleTasks handles GET /api/tasks
 func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -219,7 +496,25 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, stats)
+	blobStats, err := s.db.GetBlobStats()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, struct {
+		*db.Stats
+		BlobChunkCount         int64   `json:"blob_chunk_count"`
+		BlobUniqueBytes        int64   `json:"blob_unique_bytes"`
+		BlobLogicalBytes       int64   `json:"blob_logical_bytes"`
+		BlobDeduplicationRatio float64 `json:"blob_deduplication_ratio"`
+	}{
+		Stats:                  stats,
+		BlobChunkCount:         blobStats.ChunkCount,
+		BlobUniqueBytes:        blobStats.UniqueBytes,
+		BlobLogicalBytes:       blobStats.LogicalBytes,
+		BlobDeduplicationRatio: blobStats.DeduplicationRatio(),
+	})
 }
 
 // handleRebuildIndex handles POST /api/rebuild-index
@@ -240,35 +535,86 @@ func (s *Server) handleRebuildIndex(w http.ResponseWriter, r *http.Request) {
 
 	// Create rebuild job
 	jobID := uuid.New().String()
+	jobCtx, cancel := context.WithCancel(context.Background())
 	job := &RebuildJob{
 		ID:        jobID,
 		Status:    "running",
 		StartTime: time.Now(),
+		Cancel:    cancel,
 	}
 
 	s.rebuildJobsMu.Lock()
 	s.rebuildJobs[jobID] = job
 	s.rebuildJobsMu.Unlock()
+	metrics.RebuildJobs.Inc("running")
+	s.jobs.Register(&Job{ID: jobID, Kind: "rebuild-index", Status: "running", StartTime: job.StartTime, Cancel: cancel})
 
 	// Start rebuild in background
+	pub := s.worker.RebuildEvents(jobID)
+	pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "running", Timestamp: time.Now()})
+
 	go func() {
-		err := s.worker.RebuildIndex(s.watchDirs, req.Converter)
+		// Always release jobCtx's resources on every completion path
+		// (success, failure, or cancel), mirroring the standard
+		// "caller of WithCancel always calls cancel" invariant.
+		defer cancel()
 
-		s.rebuildJobsMu.Lock()
-		defer s.rebuildJobsMu.Unlock()
+		err := s.worker.RebuildIndex(jobCtx, s.watchDirs, req.Converter, pub)
 
+		s.rebuildJobsMu.Lock()
 		job.EndTime = time.Now()
-		if err != nil {
+		switch {
+		case err == context.Canceled:
+			job.Status = "cancelled"
+		case err != nil:
 			job.Status = "failed"
 			job.Error = err.Error()
-		} else {
+		default:
 			job.Status = "completed"
 		}
+		status := job.Status
+		s.rebuildJobsMu.Unlock()
+		metrics.RebuildJobs.Dec("running")
+		metrics.RebuildJobs.Inc(status)
+
+		switch status {
+		case "cancelled":
+			pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "cancelled", Timestamp: time.Now()})
+		case "failed":
+			pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "failed", Message: job.Error, Timestamp: time.Now()})
+		default:
+			pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "completed", Timestamp: time.Now()})
+			s.webhooks.Publish(webhook.EventRebuildCompleted, map[string]interface{}{
+				"job_id":    jobID,
+				"converter": req.Converter,
+				"timestamp": job.EndTime,
+			})
+		}
+
+		s.jobs.Unregister(jobID)
+		s.scheduleRebuildJobEviction(jobID)
 	}()
 
 	respondJSON(w, map[string]string{"job_id": jobID})
 }
 
+// scheduleRebuildJobEviction arranges for jobID's RebuildJob record and
+// buffered SSE events to be dropped rebuildJobTTL after it finishes, so a
+// long-running server doesn't accumulate one entry per rebuild forever. A
+// zero TTL disables eviction (useful for tests that want to inspect a
+// finished job's state).
+func (s *Server) scheduleRebuildJobEviction(jobID string) {
+	if s.rebuildJobTTL <= 0 {
+		return
+	}
+	time.AfterFunc(s.rebuildJobTTL, func() {
+		s.rebuildJobsMu.Lock()
+		delete(s.rebuildJobs, jobID)
+		s.rebuildJobsMu.Unlock()
+		s.worker.RemoveRebuildEvents(jobID)
+	})
+}
+
 // handleRebuildStatus handles GET /api/rebuild-status/{job_id}
 func (s *Server) handleRebuildStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -294,15 +640,287 @@ func (s *Server) handleRebuildStatus(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, job)
 }
 
-// handleScanNow handles POST /api/scan-now
+// handleRebuildCancel handles POST /api/rebuild-status/{job_id}/cancel. It
+// invokes the job's CancelFunc and marks it "cancelling"; the goroutine
+// running RebuildIndex transitions it to its final "cancelled" status once
+// the walk actually stops.
+func (s *Server) handleRebuildCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/rebuild-status/"), "/cancel")
+	if jobID == "" {
+		http.Error(w, "Job ID required", http.StatusBadRequest)
+		return
+	}
+
+	s.rebuildJobsMu.Lock()
+	job, exists := s.rebuildJobs[jobID]
+	if exists && (job.Status == "running" || job.Status == "cancelling") {
+		job.Status = "cancelling"
+	}
+	s.rebuildJobsMu.Unlock()
+
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	job.Cancel()
+	s.worker.RebuildEvents(jobID).Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "cancelling", Timestamp: time.Now()})
+
+	respondJSON(w, job)
+}
+
+// handleRebuildStatusStream handles GET /api/rebuild-status/{job_id}/stream,
+// an SSE stream of progress.JobEvents for the rebuild job, replaying any
+// buffered events the client missed since Last-Event-ID.
+func (s *Server) handleRebuildStatusStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/rebuild-status/"), "/stream")
+	if jobID == "" {
+		http.Error(w, "Job ID required", http.StatusBadRequest)
+		return
+	}
+
+	pub := s.worker.RebuildEvents(jobID)
+	serveSSE(w, r, pub)
+}
+
+// handleTaskStream handles GET /api/tasks/{path}/stream, an SSE stream of
+// live progress.JobEvents for an in-progress conversion task. Tasks are
+// keyed by source file path rather than the numeric task ID used elsewhere,
+// since TaskHistory only gets an ID once the task completes and there's
+// nothing stable to key on while it's still running.
+func (s *Server) handleTaskStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filePath := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/stream")
+	if filePath == "" {
+		http.Error(w, "File path required", http.StatusBadRequest)
+		return
+	}
+
+	pub := s.worker.TaskEvents(filePath)
+	serveSSE(w, r, pub)
+}
+
+// handleTaskLogs handles GET /api/tasks/{path}/logs, returning a snapshot of
+// the raw live log text accumulated so far for an in-progress conversion or
+// workflow run (e.g. container step output), for a client that just wants
+// the current state without subscribing to the stream.
+func (s *Server) handleTaskLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filePath := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/logs")
+	if filePath == "" {
+		http.Error(w, "File path required", http.StatusBadRequest)
+		return
+	}
+
+	log, exists := livelog.GetManager().GetLog(filePath)
+	if !exists {
+		http.Error(w, "No active log for this file", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, log)
+}
+
+// handleTaskLogsStream handles GET /api/tasks/{path}/logs/stream, an SSE
+// stream of livelog.Manager's start/log/end events for filePath, so a UI can
+// render container step output live instead of polling handleTaskLogs.
+func (s *Server) handleTaskLogsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filePath := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/logs/stream")
+	if filePath == "" {
+		http.Error(w, "File path required", http.StatusBadRequest)
+		return
+	}
+
+	serveSSE(w, r, livelog.GetManager().Events(filePath))
+}
+
+// handleLiveTasks handles GET /api/live-tasks, listing every file with a
+// currently-active live log, so a UI can show what's running right now
+// without polling handleTaskLogs for every file path it knows about.
+func (s *Server) handleLiveTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	respondJSON(w, livelog.GetManager().GetAllActiveLogs())
+}
+
+// serveSSE streams pub's JobEvents to w as Server-Sent Events until the
+// client disconnects. It honors Last-Event-ID (header or ?last_event_id=
+// query fallback, for browser EventSource clients that can't set custom
+// headers) to replay events missed across a reconnect, and sends a periodic
+// heartbeat comment so idle proxies don't time the connection out.
+func serveSSE(w http.ResponseWriter, r *http.Request, pub progress.Publisher) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var lastEventID int64
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		lastEventID, _ = strconv.ParseInt(id, 10, 64)
+	} else if id := r.URL.Query().Get("last_event_id"); id != "" {
+		lastEventID, _ = strconv.ParseInt(id, 10, 64)
+	}
+
+	events, unsubscribe := pub.Subscribe(lastEventID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// handleScanNow handles POST /api/scan-now. Unlike the old fire-and-forget
+// TriggerScan, the scan runs under a job ID registered with both scanJobs
+// and the JobManager, so its status can be polled, it can be cancelled
+// mid-walk via handleScanCancel, and a SIGINT/SIGTERM shutdown cancels it
+// along with every other in-flight job instead of letting it run to
+// completion in the background.
 func (s *Server) handleScanNow(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	s.watcher.TriggerScan()
-	respondJSON(w, map[string]string{"status": "scan triggered"})
+	jobID := uuid.New().String()
+	jobCtx, cancel := context.WithCancel(context.Background())
+	job := &Job{ID: jobID, Kind: "scan-now", Status: "running", StartTime: time.Now(), Cancel: cancel}
+
+	s.scanJobsMu.Lock()
+	s.scanJobs[jobID] = job
+	s.scanJobsMu.Unlock()
+	s.jobs.Register(job)
+
+	go func() {
+		defer cancel()
+
+		err := s.watcher.ScanNow(jobCtx)
+
+		s.scanJobsMu.Lock()
+		job.EndTime = time.Now()
+		switch {
+		case errors.Is(err, context.Canceled), errors.Is(err, watcher.ErrWatcherNotRunning):
+			job.Status = "cancelled"
+		case err != nil:
+			job.Status = "failed"
+			job.Error = err.Error()
+		default:
+			job.Status = "completed"
+		}
+		s.scanJobsMu.Unlock()
+
+		s.jobs.Unregister(jobID)
+	}()
+
+	respondJSON(w, map[string]string{"job_id": jobID})
+}
+
+// handleScanStatus handles GET /api/scan-now/{job_id}.
+func (s *Server) handleScanStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := r.URL.Path[len("/api/scan-now/"):]
+	if jobID == "" {
+		http.Error(w, "Job ID required", http.StatusBadRequest)
+		return
+	}
+
+	s.scanJobsMu.RLock()
+	job, exists := s.scanJobs[jobID]
+	s.scanJobsMu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, job)
+}
+
+// handleScanCancel handles POST /api/scan-now/{job_id}/cancel, mirroring
+// handleRebuildCancel: it invokes the job's CancelFunc and marks it
+// "cancelling", and the goroutine running ScanNow transitions it to
+// "cancelled" once the walk actually stops.
+func (s *Server) handleScanCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/scan-now/"), "/cancel")
+	if jobID == "" {
+		http.Error(w, "Job ID required", http.StatusBadRequest)
+		return
+	}
+
+	s.scanJobsMu.Lock()
+	job, exists := s.scanJobs[jobID]
+	if exists && (job.Status == "running" || job.Status == "cancelling") {
+		job.Status = "cancelling"
+	}
+	s.scanJobsMu.Unlock()
+
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	job.Cancel()
+	respondJSON(w, job)
 }
 
 // respondJSON sends a JSON response
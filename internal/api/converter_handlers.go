@@ -2,8 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/ah-its-andy/jpeg2heif/internal/converter"
 	"github.com/ah-its-andy/jpeg2heif/internal/db"
@@ -11,12 +13,13 @@ import (
 
 // ConverterResponse represents a unified converter response (builtin or workflow)
 type ConverterResponse struct {
-	Name         string `json:"name"`
-	Type         string `json:"type"` // "builtin" or "workflow"
-	TargetFormat string `json:"target_format"`
-	Enabled      bool   `json:"enabled"`
-	Description  string `json:"description,omitempty"`
-	WorkflowID   int64  `json:"workflow_id,omitempty"`
+	Name            string `json:"name"`
+	Type            string `json:"type"` // "builtin" or "workflow"
+	TargetFormat    string `json:"target_format"`
+	Enabled         bool   `json:"enabled"`
+	Description     string `json:"description,omitempty"`
+	WorkflowID      int64  `json:"workflow_id,omitempty"`
+	ClusterEligible bool   `json:"cluster_eligible"` // true if this server has cluster sync enabled, so this converter's outputs may be fetched from (or served to) a peer instead of recomputed
 }
 
 // handleConverters handles GET /api/converters - returns all converters (builtin + workflow)
@@ -27,6 +30,7 @@ func (s *Server) handleConverters(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var allConverters []ConverterResponse
+	clusterEligible := s.cluster != nil
 
 	// Get builtin converters
 	builtinConverters := converter.ListInfo()
@@ -36,10 +40,11 @@ func (s *Server) handleConverters(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		allConverters = append(allConverters, ConverterResponse{
-			Name:         c.Name,
-			Type:         "builtin",
-			TargetFormat: c.TargetFormat,
-			Enabled:      c.Enabled,
+			Name:            c.Name,
+			Type:            "builtin",
+			TargetFormat:    c.TargetFormat,
+			Enabled:         c.Enabled,
+			ClusterEligible: clusterEligible,
 		})
 	}
 
@@ -48,12 +53,13 @@ func (s *Server) handleConverters(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		for _, wf := range workflows {
 			allConverters = append(allConverters, ConverterResponse{
-				Name:         "workflow:" + wf.Name,
-				Type:         "workflow",
-				TargetFormat: extractTargetFormat(wf.Name),
-				Enabled:      wf.Enabled,
-				Description:  wf.Description,
-				WorkflowID:   wf.ID,
+				Name:            "workflow:" + wf.Name,
+				Type:            "workflow",
+				TargetFormat:    extractTargetFormat(wf.Name),
+				Enabled:         wf.Enabled,
+				Description:     wf.Description,
+				WorkflowID:      wf.ID,
+				ClusterEligible: clusterEligible,
 			})
 		}
 	}
@@ -89,6 +95,22 @@ func (s *Server) handleConverterUpdate(w http.ResponseWriter, r *http.Request, n
 		return
 	}
 
+	// Refuse to flip Enabled while a conversion lease against this converter
+	// is still outstanding (internal/lock), since a worker may be mid-run
+	// relying on the old value; ?force=true bypasses this for an operator
+	// who knows better (e.g. the holder is known to be stuck).
+	if r.URL.Query().Get("force") != "true" {
+		activeLock, err := s.db.FindActiveLockForConverter(name, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if activeLock != nil {
+			http.Error(w, fmt.Sprintf("converter %q has an in-flight conversion lease held by %q; retry once it completes, or pass ?force=true", name, activeLock.Owner), http.StatusConflict)
+			return
+		}
+	}
+
 	// Check if it's a workflow converter
 	if strings.HasPrefix(name, "workflow:") {
 		workflowName := strings.TrimPrefix(name, "workflow:")
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Job is one long-running background operation tracked by JobManager:
+// currently rebuild-index (see handleRebuildIndex) and scan-now (see
+// handleScanNow), with future bulk-reprocess jobs meant to register here
+// too rather than growing their own bespoke signal-handling code.
+type Job struct {
+	ID        string
+	Kind      string // "rebuild-index", "scan-now", ...
+	Status    string // running, cancelling, cancelled, completed, failed
+	StartTime time.Time
+	EndTime   time.Time
+	Error     string
+	Cancel    context.CancelFunc `json:"-"`
+}
+
+// JobManager tracks every in-flight background Job across the API server,
+// so a SIGINT/SIGTERM handler can cancel all of them at once on shutdown
+// instead of each job type needing its own. It doesn't replace a job
+// type's own status tracking (e.g. Server.rebuildJobs) — a job registers
+// here in addition to wherever its type-specific state already lives, and
+// unregisters once it reaches a terminal status.
+type JobManager struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewJobManager creates an empty JobManager.
+func NewJobManager() *JobManager {
+	return &JobManager{jobs: make(map[string]*Job)}
+}
+
+// Register starts tracking job under its ID. Call Unregister once it
+// reaches a terminal status.
+func (m *JobManager) Register(job *Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ID] = job
+}
+
+// Unregister stops tracking the job with the given ID.
+func (m *JobManager) Unregister(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, id)
+}
+
+// Len returns how many jobs are currently tracked, mainly for Shutdown's
+// caller to log how much in-flight work it's about to cancel.
+func (m *JobManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.jobs)
+}
+
+// Shutdown marks every currently tracked job "cancelling" and invokes its
+// CancelFunc, so a SIGINT/SIGTERM handler can stop all in-flight rebuild/
+// scan/bulk-reprocess work without knowing about each job type
+// individually. It returns immediately; it does not wait for a job's
+// goroutine to actually observe the cancellation and finish — callers that
+// need that should wait on worker.Worker.StopWithTimeout or an equivalent
+// signal for whatever work the job was driving.
+func (m *JobManager) Shutdown() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.Status == "running" {
+			job.Status = "cancelling"
+		}
+		job.Cancel()
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/converter"
+	"github.com/ah-its-andy/jpeg2heif/internal/metrics"
+)
+
+// handleMetrics handles GET /metrics, exposing the process's counters,
+// gauges, and histograms in Prometheus text exposition format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.refreshSnapshotMetrics()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(metrics.Render()))
+}
+
+// StartMetrics starts a second HTTP listener exposing only GET /metrics, on
+// a port separate from Start's main API listener. This lets a scraper be
+// pointed at the process without also exposing /api/* and /debug/pprof/*,
+// per util.Config.MetricsListenPort.
+func (s *Server) StartMetrics(port int) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	addr := fmt.Sprintf(":%d", port)
+	return http.ListenAndServe(addr, mux)
+}
+
+// refreshSnapshotMetrics sets the gauges that reflect current state rather
+// than an accumulated/observed event (pending/processing file counts, and
+// which converters are presently enabled) from db.Stats and
+// converter.ListInfo()/db.Workflow.Enabled, just before a scrape. These
+// can't be updated incrementally like ConversionsTotal since nothing calls
+// into this package when a converter is toggled or a file's status changes
+// elsewhere.
+func (s *Server) refreshSnapshotMetrics() {
+	if stats, err := s.db.GetStats(); err == nil {
+		metrics.PendingFiles.Set(float64(stats.PendingCount))
+		metrics.ProcessingFiles.Set(float64(stats.ProcessingCount))
+	}
+
+	for _, c := range converter.ListInfo() {
+		if strings.HasPrefix(c.Name, "workflow:") {
+			continue
+		}
+		enabled := 0.0
+		if c.Enabled {
+			enabled = 1.0
+		}
+		metrics.ConverterEnabled.Set(enabled, c.Name)
+	}
+
+	if workflows, err := s.db.ListWorkflows(1000, 0); err == nil {
+		for _, wf := range workflows {
+			enabled := 0.0
+			if wf.Enabled {
+				enabled = 1.0
+			}
+			metrics.ConverterEnabled.Set(enabled, "workflow:"+wf.Name)
+		}
+	}
+}
+
+// registerPprofRoutes wires the standard net/http/pprof handlers onto mux,
+// for profiling hot paths like the MD5 scanner. Only called when the
+// enablePprof flag passed to New is set.
+func registerPprofRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/db"
+)
+
+// handleWebhooks handles GET /api/webhooks - list all webhooks, and
+// POST /api/webhooks - create a new webhook
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		s.listWebhooks(w, r)
+	} else if r.Method == http.MethodPost {
+		s.createWebhook(w, r)
+	} else {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleWebhookDetail handles GET/PUT/DELETE /api/webhooks/{id}
+func (s *Server) handleWebhookDetail(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/webhooks/")
+	if idx := strings.Index(id, "/"); idx != -1 {
+		id = id[:idx]
+	}
+
+	webhookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.getWebhook(w, r, webhookID)
+	case http.MethodPut:
+		s.updateWebhook(w, r, webhookID)
+	case http.MethodDelete:
+		s.deleteWebhook(w, r, webhookID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listWebhooks lists all webhook subscriptions
+func (s *Server) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.db.ListWebhooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, webhooks)
+}
+
+// getWebhook gets a single webhook
+func (s *Server) getWebhook(w http.ResponseWriter, r *http.Request, id int64) {
+	wh, err := s.db.GetWebhook(id)
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, wh)
+}
+
+// createWebhook creates a new webhook subscription
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var wh db.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&wh); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if wh.URL == "" || wh.Events == "" {
+		http.Error(w, "url and events are required", http.StatusBadRequest)
+		return
+	}
+
+	if wh.MaxAttempts <= 0 {
+		wh.MaxAttempts = 8
+	}
+
+	if err := s.db.CreateWebhook(&wh); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, wh)
+}
+
+// updateWebhook updates an existing webhook's configuration
+func (s *Server) updateWebhook(w http.ResponseWriter, r *http.Request, id int64) {
+	var wh db.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&wh); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	wh.ID = id
+
+	if err := s.db.UpdateWebhook(&wh); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, wh)
+}
+
+// deleteWebhook removes a webhook
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request, id int64) {
+	if err := s.db.DeleteWebhook(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, map[string]string{"status": "deleted"})
+}
+
+// handleWebhookDeliveries handles GET /api/webhooks/{id}/deliveries
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/webhooks/")
+	id = strings.TrimSuffix(id, "/deliveries")
+
+	webhookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if limit <= 0 {
+		limit = 50
+	}
+
+	deliveries, err := s.db.ListWebhookDeliveries(webhookID, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, deliveries)
+}
@@ -1,14 +1,19 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ah-its-andy/jpeg2heif/internal/converter"
 	"github.com/ah-its-andy/jpeg2heif/internal/db"
+	"github.com/ah-its-andy/jpeg2heif/internal/webhook"
 	"github.com/ah-its-andy/jpeg2heif/internal/workflow"
 )
 
@@ -263,11 +268,362 @@ func (s *Server) handleWorkflowRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: Implement actual workflow execution through worker queue
-	// For now, return a placeholder
+	spec, err := workflow.ParseWorkflow(wf.YAML)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jobParams, err := json.Marshal(req.Variables)
+	if err != nil {
+		jobParams = []byte("{}")
+	}
+
+	run := &db.WorkflowRun{
+		WorkflowID:   wf.ID,
+		WorkflowName: wf.Name,
+		FilePath:     req.FilePath,
+		Status:       "pending",
+		StartTime:    time.Now(),
+		JobParams:    string(jobParams),
+	}
+	if err := s.db.CreateWorkflowRun(run); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.workflowRunCancelsMu.Lock()
+	s.workflowRunCancels[run.ID] = cancel
+	s.workflowRunCancelsMu.Unlock()
+
+	go s.runWorkflow(runCtx, cancel, spec, run, req.Variables, 0)
+
+	respondJSON(w, map[string]interface{}{
+		"status":  "pending",
+		"run_id":  run.ID,
+		"message": fmt.Sprintf("Workflow '%s' queued for %s", wf.Name, req.FilePath),
+	})
+}
+
+// RecoverInterruptedRuns re-queues every workflow run left at status
+// "running" by a prior process that crashed or was killed before it could
+// reach a terminal status, resuming each from its last persisted checkpoint.
+// Call once at startup, after SetWorkflowConcurrency/SetWorkflowStepTimeout
+// and before Start, so recovered runs compete for the same bounded pool as
+// new ones.
+func (s *Server) RecoverInterruptedRuns() error {
+	runs, err := s.db.ListAllWorkflowRuns("running", 1000, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		wf, err := s.db.GetWorkflow(run.WorkflowID)
+		if err != nil {
+			s.logger.Error("failed to recover workflow run: workflow not found", "run_id", run.ID, "workflow_id", run.WorkflowID, "error", err)
+			continue
+		}
+
+		spec, err := workflow.ParseWorkflow(wf.YAML)
+		if err != nil {
+			s.logger.Error("failed to recover workflow run: invalid workflow spec", "run_id", run.ID, "error", err)
+			continue
+		}
+
+		var variables map[string]string
+		if run.JobParams != "" {
+			_ = json.Unmarshal([]byte(run.JobParams), &variables)
+		}
+		var checkpoint workflowCheckpoint
+		if run.Checkpoint != "" {
+			_ = json.Unmarshal([]byte(run.Checkpoint), &checkpoint)
+		}
+
+		runCtx, cancel := context.WithCancel(context.Background())
+		s.workflowRunCancelsMu.Lock()
+		s.workflowRunCancels[run.ID] = cancel
+		s.workflowRunCancelsMu.Unlock()
+
+		s.logger.Info("resuming interrupted workflow run", "run_id", run.ID, "workflow_id", run.WorkflowID, "resume_from", checkpoint.CompletedSteps)
+		go s.runWorkflow(runCtx, cancel, spec, run, variables, checkpoint.CompletedSteps)
+	}
+
+	return nil
+}
+
+// workflowCheckpoint is the JSON shape persisted to WorkflowRun.Checkpoint,
+// recording just enough of a run's progress for a resumed execution to skip
+// the steps a prior, paused execution already completed.
+type workflowCheckpoint struct {
+	CompletedSteps int `json:"completed_steps"`
+}
+
+// runWorkflow executes spec against run.FilePath in the background and
+// persists the outcome via UpdateWorkflowRun. cancel is always invoked on
+// every exit path, whether the run finished, failed, paused, or was
+// cancelled through handleWorkflowRunCancel. resumeFromStep is 0 for a fresh
+// run, or the number of steps a prior paused execution of this same run
+// already completed, for one started through handleWorkflowRunResume.
+func (s *Server) runWorkflow(ctx context.Context, cancel context.CancelFunc, spec *workflow.WorkflowSpec, run *db.WorkflowRun, variables map[string]string, resumeFromStep int) {
+	defer cancel()
+	defer func() {
+		s.workflowRunCancelsMu.Lock()
+		delete(s.workflowRunCancels, run.ID)
+		s.workflowRunCancelsMu.Unlock()
+	}()
+
+	// Wait for a free slot in the bounded workflow worker pool before doing
+	// any work. A fresh run sits at status "pending" until it acquires one;
+	// a resumed run is already "running" in the database (see
+	// handleWorkflowRunResume) and just waits here without changing status.
+	select {
+	case s.workflowSem <- struct{}{}:
+		defer func() { <-s.workflowSem }()
+	case <-ctx.Done():
+		if run.Status == "pending" {
+			run.Status = "cancelled"
+			s.db.UpdateWorkflowRun(run)
+		}
+		return
+	}
+
+	if run.Status == "pending" {
+		run.Status = "running"
+		if err := s.db.UpdateWorkflowRun(run); err != nil {
+			s.finishWorkflowRun(run, nil, fmt.Errorf("failed to mark run running: %w", err))
+			return
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "workflow-run-*")
+	if err != nil {
+		s.finishWorkflowRun(run, nil, fmt.Errorf("failed to create temp dir: %w", err))
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	outputFile := filepath.Join(tmpDir, filepath.Base(run.FilePath))
+	execCtx := &workflow.ExecutionContext{
+		WorkflowName:       run.WorkflowName,
+		InputFile:          run.FilePath,
+		OutputFile:         outputFile,
+		TempDir:            tmpDir,
+		Quality:            85,
+		Variables:          variables,
+		ResumeFromStep:     resumeFromStep,
+		DefaultStepTimeout: s.workflowStepTimeout,
+		PauseCheck: func() bool {
+			current, err := s.db.GetWorkflowRun(run.ID)
+			return err == nil && current.PauseRequested
+		},
+		Checkpoint: func(stepIndex int) {
+			cp, _ := json.Marshal(workflowCheckpoint{CompletedSteps: stepIndex + 1})
+			run.Checkpoint = string(cp)
+			if err := s.db.UpdateWorkflowRun(run); err != nil {
+				fmt.Printf("Warning: failed to persist checkpoint for workflow run %d: %v\n", run.ID, err)
+			}
+		},
+	}
+
+	executor := workflow.NewExecutor(spec, ctx, execCtx)
+	result, execErr := executor.Execute()
+
+	if result != nil && result.Paused {
+		now := time.Now()
+		run.Status = "paused"
+		run.PausedAt = &now
+		run.Logs = result.Logs
+		run.DurationMs = time.Since(run.StartTime).Milliseconds()
+		cp, _ := json.Marshal(workflowCheckpoint{CompletedSteps: result.PausedAtStep})
+		run.Checkpoint = string(cp)
+		if err := s.db.UpdateWorkflowRun(run); err != nil {
+			fmt.Printf("Warning: failed to update paused workflow run %d: %v\n", run.ID, err)
+		}
+		s.publishWorkflowRunFinished(run)
+		return
+	}
+
+	if ctx.Err() == context.Canceled {
+		run.Status = "cancelled"
+		run.Logs = result.Logs
+		endTime := time.Now()
+		run.EndTime = &endTime
+		run.DurationMs = time.Since(run.StartTime).Milliseconds()
+		if err := s.db.UpdateWorkflowRun(run); err != nil {
+			fmt.Printf("Warning: failed to update cancelled workflow run %d: %v\n", run.ID, err)
+		}
+		s.publishWorkflowRunFinished(run)
+		return
+	}
+
+	s.finishWorkflowRun(run, result, execErr)
+}
+
+// finishWorkflowRun records the terminal state of a workflow run. result may
+// be nil if execution never produced one (e.g. failed before Execute ran).
+func (s *Server) finishWorkflowRun(run *db.WorkflowRun, result *workflow.ExecutionResult, execErr error) {
+	endTime := time.Now()
+	run.EndTime = &endTime
+	run.DurationMs = time.Since(run.StartTime).Milliseconds()
+
+	if result != nil {
+		run.Stdout = converter.CombineStepOutputs(result.StepResults, true)
+		run.Stderr = converter.CombineStepOutputs(result.StepResults, false)
+		run.Logs = result.Logs
+		run.MetadataPreserved = result.MetadataPreserved
+		run.MetadataSummary = result.MetadataSummary
+		run.Summary = result.Summary
+		if annotationsJSON, err := json.Marshal(result.Annotations); err == nil {
+			run.Annotations = string(annotationsJSON)
+		}
+		exitCode := result.ExitCode
+		run.ExitCode = &exitCode
+	}
+
+	if execErr != nil {
+		run.Status = "failed"
+	} else {
+		run.Status = "success"
+	}
+
+	if err := s.db.UpdateWorkflowRun(run); err != nil {
+		fmt.Printf("Warning: failed to update workflow run %d: %v\n", run.ID, err)
+	}
+	s.publishWorkflowRunFinished(run)
+}
+
+// publishWorkflowRunFinished notifies webhook subscribers that an
+// API-triggered workflow run reached a terminal state.
+func (s *Server) publishWorkflowRunFinished(run *db.WorkflowRun) {
+	s.webhooks.Publish(webhook.EventWorkflowRunFinish, map[string]interface{}{
+		"run_id":        run.ID,
+		"workflow_id":   run.WorkflowID,
+		"workflow_name": run.WorkflowName,
+		"status":        run.Status,
+		"timestamp":     time.Now(),
+	})
+}
+
+// handleWorkflowRunCancel handles POST /api/workflows/{id}/runs/{run_id}/cancel
+func (s *Server) handleWorkflowRunCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/cancel")
+	runIDStr := path[strings.LastIndex(path, "/")+1:]
+	runID, err := strconv.ParseInt(runIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid run ID", http.StatusBadRequest)
+		return
+	}
+
+	s.workflowRunCancelsMu.Lock()
+	cancel, exists := s.workflowRunCancels[runID]
+	s.workflowRunCancelsMu.Unlock()
+
+	if !exists {
+		http.Error(w, "Run not found or already finished", http.StatusNotFound)
+		return
+	}
+
+	cancel()
+	respondJSON(w, map[string]interface{}{"status": "cancelling", "run_id": runID})
+}
+
+// handleWorkflowRunPause handles POST /api/workflows/runs/{run_id}/pause.
+// Unlike cancel, pausing doesn't cancel the run's context: it sets
+// pause_requested so the runner honors it cooperatively at its next step
+// boundary (see runWorkflow's PauseCheck), persisting a checkpoint so
+// handleWorkflowRunResume can continue from there later.
+func (s *Server) handleWorkflowRunPause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/pause")
+	runIDStr := path[strings.LastIndex(path, "/")+1:]
+	runID, err := strconv.ParseInt(runIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid run ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.PauseWorkflowRun(runID); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	respondJSON(w, map[string]interface{}{"status": "pausing", "run_id": runID})
+}
+
+// handleWorkflowRunResume handles POST /api/workflows/runs/{run_id}/resume.
+// It reloads the paused run's workflow spec, job parameters, and
+// checkpoint, then starts a new execution that skips the steps the prior,
+// paused execution already completed.
+func (s *Server) handleWorkflowRunResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/resume")
+	runIDStr := path[strings.LastIndex(path, "/")+1:]
+	runID, err := strconv.ParseInt(runIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.db.GetWorkflowRun(runID)
+	if err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.db.ResumeWorkflowRun(runID, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	run.Status = "running"
+
+	wf, err := s.db.GetWorkflow(run.WorkflowID)
+	if err != nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+
+	spec, err := workflow.ParseWorkflow(wf.YAML)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var variables map[string]string
+	if run.JobParams != "" {
+		_ = json.Unmarshal([]byte(run.JobParams), &variables)
+	}
+
+	var checkpoint workflowCheckpoint
+	if run.Checkpoint != "" {
+		_ = json.Unmarshal([]byte(run.Checkpoint), &checkpoint)
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.workflowRunCancelsMu.Lock()
+	s.workflowRunCancels[run.ID] = cancel
+	s.workflowRunCancelsMu.Unlock()
+
+	go s.runWorkflow(runCtx, cancel, spec, run, variables, checkpoint.CompletedSteps)
+
 	respondJSON(w, map[string]interface{}{
-		"status":  "queued",
-		"message": fmt.Sprintf("Workflow '%s' queued for execution on %s", wf.Name, req.FilePath),
+		"status":      "running",
+		"run_id":      run.ID,
+		"resume_from": checkpoint.CompletedSteps,
+		"message":     fmt.Sprintf("Workflow run %d resumed for %s", run.ID, run.FilePath),
 	})
 }
 
@@ -293,12 +649,13 @@ func (s *Server) handleWorkflowRuns(w http.ResponseWriter, r *http.Request) {
 
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	status := r.URL.Query().Get("status")
 
 	if limit <= 0 {
 		limit = 50
 	}
 
-	runs, err := s.db.ListWorkflowRuns(workflowID, limit, offset)
+	runs, err := s.db.ListWorkflowRuns(workflowID, status, limit, offset)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -335,3 +692,25 @@ func (s *Server) handleWorkflowRunDetail(w http.ResponseWriter, r *http.Request)
 
 	respondJSON(w, run)
 }
+
+// handleWorkflowRunStream handles GET /api/workflows/runs/{run_id}/stream,
+// an SSE stream of step-level progress.JobEvents for a running WorkflowRun,
+// so a client can watch a long conversion's progress live instead of
+// polling handleWorkflowRunDetail. Replays any buffered events the client
+// missed since Last-Event-ID, same as the rebuild-status and task streams.
+func (s *Server) handleWorkflowRunStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/stream")
+	runIDStr := path[strings.LastIndex(path, "/")+1:]
+	runID, err := strconv.ParseInt(runIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid run ID", http.StatusBadRequest)
+		return
+	}
+
+	serveSSE(w, r, converter.WorkflowRunEvents(runID))
+}
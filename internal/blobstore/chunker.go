@@ -0,0 +1,59 @@
+// Package blobstore splits files into content-defined chunks and stores
+// them content-addressed on disk, so that near-identical files (the same
+// JPEG re-saved, or re-exported with only its EXIF block changed) share
+// every chunk outside the changed region instead of being stored as two
+// unrelated copies.
+package blobstore
+
+import (
+	"io"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/contenthash"
+)
+
+const (
+	// minChunkSize, targetChunkSize and maxChunkSize bound a chunk's size:
+	// a boundary found before minChunkSize is ignored, and a chunk is cut
+	// unconditionally at maxChunkSize even if the rolling hash never finds
+	// one, so a pathological input (e.g. a long run of identical bytes)
+	// can't grow a single chunk without bound. targetChunkSize is the
+	// average size the rolling hash aims for, not a hard limit.
+	minChunkSize    = 512 * 1024
+	targetChunkSize = 1024 * 1024
+	maxChunkSize    = 8 * 1024 * 1024
+)
+
+// fileChunker is the contenthash.Chunker used to find chunk boundaries in
+// whole file contents, the same rolling-hash content-defined chunking
+// contenthash.VisualDigest uses over decoded pixel streams (see chunk0-2);
+// only Min/MaxSize are set explicitly, to the bounds Store uses for on-disk
+// chunks: MinSize is raised from contenthash's default of AvgSize/4 to
+// 512 KiB, and MaxSize equals its default of AvgSize*8.
+var fileChunker = newFileChunker()
+
+func newFileChunker() *contenthash.Chunker {
+	c := contenthash.NewChunker(targetChunkSize)
+	c.MinSize = minChunkSize
+	c.MaxSize = maxChunkSize
+	return c
+}
+
+// splitChunks reads r to completion and returns the byte content of each
+// content-defined chunk, boundaries found by fileChunker.
+func splitChunks(r io.Reader) ([][]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	boundaries := fileChunker.Chunk(data)
+
+	chunks := make([][]byte, 0, len(boundaries))
+	start := 0
+	for _, end := range boundaries {
+		chunks = append(chunks, data[start:end])
+		start = end
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,158 @@
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ChunkRef identifies one chunk of a file's content by the SHA-256 digest
+// of its bytes and its length, the unit Store dedups and stores content-
+// addressed under Store.Dir/blobs/<sha256[0:2]>/<sha256>.
+type ChunkRef struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Manifest is the ordered list of chunks a file splits into; concatenating
+// their contents in this order reproduces the file exactly. It's stored as
+// JSON in db.FileIndex.OutputManifest.
+type Manifest struct {
+	Chunks []ChunkRef `json:"chunks"`
+}
+
+// ManifestFromJSON decodes a manifest previously produced by Manifest.JSON.
+// An empty string decodes to an empty Manifest (no chunks) rather than an
+// error, so a FileIndex row written before this feature existed is simply
+// treated as having nothing to reassemble or GC.
+func ManifestFromJSON(s string) (Manifest, error) {
+	if s == "" {
+		return Manifest{}, nil
+	}
+	var m Manifest
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return Manifest{}, err
+	}
+	return m, nil
+}
+
+// JSON encodes m for storage in a single TEXT column.
+func (m Manifest) JSON() (string, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// TotalSize returns the sum of every chunk's size, i.e. the size of the
+// file the manifest reassembles into.
+func (m Manifest) TotalSize() int64 {
+	var total int64
+	for _, c := range m.Chunks {
+		total += c.Size
+	}
+	return total
+}
+
+// Store is a content-addressed blob store rooted at Dir, used to split
+// workflow inputs/outputs into content-defined chunks (see splitChunks) and
+// reassemble a file from its Manifest. It only deals in bytes on disk; the
+// refcounting that makes GC possible (db.UpsertBlob/GetBlobStats, and the
+// decrement in db.DeleteFileIndex) is the caller's job, same as
+// workflowcache.Cache leaves eviction bookkeeping to its own db-backed
+// index rather than folding it into the on-disk layout.
+type Store struct {
+	Dir string
+}
+
+// New returns a Store rooted at dir, creating it if necessary.
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob store dir: %w", err)
+	}
+	return &Store{Dir: dir}, nil
+}
+
+// BlobPath returns the on-disk path chunkID's content is (or would be)
+// stored at, e.g. so a caller can remove it once db.GetBlob reports its
+// refcount has reached zero.
+func (s *Store) BlobPath(chunkID string) string {
+	return filepath.Join(s.Dir, "blobs", chunkID[:2], chunkID)
+}
+
+// Split reads path and writes every chunk it doesn't already have on disk
+// under Dir/blobs, returning the ordered Manifest describing how to
+// reassemble it. Split doesn't touch refcounts: the caller is expected to
+// call db.UpsertBlob for every ChunkRef a successful Split returns,
+// including ones that already existed on disk (that's what makes
+// deduplication visible as a shrinking logical-to-unique byte ratio).
+func (s *Store) Split(path string) (Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer f.Close()
+
+	chunks, err := splitChunks(f)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest := Manifest{Chunks: make([]ChunkRef, 0, len(chunks))}
+	for _, data := range chunks {
+		sum := sha256.Sum256(data)
+		chunkID := hex.EncodeToString(sum[:])
+
+		blobPath := s.BlobPath(chunkID)
+		if _, err := os.Stat(blobPath); os.IsNotExist(err) {
+			if err := os.MkdirAll(filepath.Dir(blobPath), 0o755); err != nil {
+				return Manifest{}, err
+			}
+			if err := os.WriteFile(blobPath, data, 0o644); err != nil {
+				return Manifest{}, err
+			}
+		}
+
+		manifest.Chunks = append(manifest.Chunks, ChunkRef{SHA256: chunkID, Size: int64(len(data))})
+	}
+
+	return manifest, nil
+}
+
+// Assemble reconstructs a file at dstPath by concatenating manifest's
+// chunks, in order, from disk.
+func (s *Store) Assemble(manifest Manifest, dstPath string) error {
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	for _, c := range manifest.Chunks {
+		if err := s.appendChunk(out, c.SHA256); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) appendChunk(dst io.Writer, chunkID string) error {
+	in, err := os.Open(s.BlobPath(chunkID))
+	if err != nil {
+		return fmt.Errorf("missing chunk %s: %w", chunkID, err)
+	}
+	defer in.Close()
+
+	_, err = io.Copy(dst, in)
+	return err
+}
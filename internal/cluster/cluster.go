@@ -0,0 +1,19 @@
+// Package cluster lets multiple jpeg2heif nodes share their converted
+// outputs, so a file already converted by one node doesn't have to be
+// recomputed by another that happens to watch an identical copy. Each node
+// periodically gossips its own recent conversions to its configured peers
+// and pulls any deltas it missed, keeping a local peer index it consults
+// before running a conversion (see Registry.Consult).
+package cluster
+
+// AuthHeader carries the shared token peers present to each other's
+// /api/cluster/* endpoints. A Registry with no configured token accepts any
+// request, so auth is opt-in for deployments that don't need it (e.g. a
+// trusted private network).
+const AuthHeader = "X-Cluster-Token"
+
+// Peer is one other jpeg2heif node this registry gossips with.
+type Peer struct {
+	Name string
+	URL  string
+}
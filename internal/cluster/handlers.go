@@ -0,0 +1,196 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/db"
+)
+
+// HandleGossip handles POST /api/cluster/gossip: a peer pushing its recent
+// conversions to us. Entries are stored in our peer index, the same way a
+// reconciliation pull would store them.
+func (r *Registry) HandleGossip(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !r.authenticate(req) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload gossipRequest
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if payload.NodeID == "" {
+		http.Error(w, "node_id is required", http.StatusBadRequest)
+		return
+	}
+
+	for _, e := range payload.Entries {
+		if err := r.db.UpsertPeerIndexEntry(&db.PeerIndexEntry{
+			PeerNode:      payload.NodeID,
+			FilePath:      e.FilePath,
+			FileMD5:       e.FileMD5,
+			HashAlgo:      e.HashAlgo,
+			Status:        e.Status,
+			ConverterName: e.ConverterName,
+			TargetPath:    e.TargetPath,
+			UpdatedAt:     e.UpdatedAt,
+		}); err != nil {
+			log.Printf("cluster: failed to store gossiped entry from %s: %v", payload.NodeID, err)
+		}
+	}
+
+	respondJSON(w, map[string]int{"stored": len(payload.Entries)})
+}
+
+// HandleIndexDelta handles GET /api/cluster/index?since=<RFC3339>&limit=N: a
+// peer reconciling its local peer index against our own recent outputs.
+func (r *Registry) HandleIndexDelta(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !r.authenticate(req) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	since := time.Time{}
+	if s := req.URL.Query().Get("since"); s != "" {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			http.Error(w, "invalid since parameter", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	limit := reconcileBatchSize
+	if l := req.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	outputs, err := r.db.ListClusterOutputsSince(since, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]GossipEntry, 0, len(outputs))
+	for _, o := range outputs {
+		entries = append(entries, outputToGossipEntry(o))
+	}
+
+	respondJSON(w, indexDeltaResponse{Entries: entries})
+}
+
+// HandleLookup handles GET /api/cluster/lookup?md5=...&hash_algo=...&converter=...:
+// a peer asking whether this node already has a successful conversion it
+// can fetch instead of recomputing. It only ever consults this node's own
+// cluster_outputs, never the transitively-learned peer_index, so a lookup
+// can't loop through the cluster indefinitely.
+func (r *Registry) HandleLookup(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !r.authenticate(req) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	fileMD5, hashAlgo, converterName, ok := parseLookupParams(req)
+	if !ok {
+		http.Error(w, "md5, hash_algo and converter are required", http.StatusBadRequest)
+		return
+	}
+
+	output, err := r.db.GetClusterOutput(fileMD5, hashAlgo, converterName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if output == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, outputToGossipEntry(output))
+}
+
+// HandleFetch handles GET /api/cluster/fetch?md5=...&hash_algo=...&converter=...,
+// streaming the converted artifact this node holds for that content hash
+// and converter, for a peer that found it via HandleLookup or its local
+// peer index.
+func (r *Registry) HandleFetch(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !r.authenticate(req) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	fileMD5, hashAlgo, converterName, ok := parseLookupParams(req)
+	if !ok {
+		http.Error(w, "md5, hash_algo and converter are required", http.StatusBadRequest)
+		return
+	}
+
+	output, err := r.db.GetClusterOutput(fileMD5, hashAlgo, converterName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if output == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	f, err := os.Open(output.TargetPath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("output no longer available: %v", err), http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := io.Copy(w, f); err != nil {
+		log.Printf("cluster: failed to stream fetch response for %s/%s: %v", fileMD5, converterName, err)
+	}
+}
+
+func parseLookupParams(req *http.Request) (fileMD5, hashAlgo, converterName string, ok bool) {
+	q := req.URL.Query()
+	fileMD5 = q.Get("md5")
+	hashAlgo = q.Get("hash_algo")
+	converterName = q.Get("converter")
+	if fileMD5 == "" || hashAlgo == "" || converterName == "" {
+		return "", "", "", false
+	}
+	return fileMD5, hashAlgo, converterName, true
+}
+
+// respondJSON writes data as a JSON response, mirroring api.respondJSON but
+// kept local to avoid an import cycle (internal/api imports this package to
+// register these handlers, not the other way around).
+func respondJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("cluster: failed to encode JSON response: %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/db"
+	"github.com/ah-its-andy/jpeg2heif/internal/util"
+)
+
+// gossipBatchSize bounds how many of this node's own recent outputs are
+// pushed to a peer per tick, mirroring webhook.deliveryBatchSize.
+const gossipBatchSize = 200
+
+// reconcileBatchSize bounds how many delta rows are requested from a peer's
+// index per reconciliation tick.
+const reconcileBatchSize = 200
+
+// Registry gossips this node's successful conversion outputs to its
+// configured peers and serves their equivalent requests. Call Start to
+// begin the background push/pull loop; a Registry with no peers configured
+// still records local outputs (RecordOutput) and serves incoming requests,
+// it just never has anyone to gossip with.
+type Registry struct {
+	db        *db.DB
+	nodeID    string
+	peers     []Peer
+	authToken string
+	client    *http.Client
+	interval  time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// ParsePeers parses "name=url" entries (as produced by util.Config's
+// CLUSTER_PEERS env var, comma-separated and already trimmed) into Peers,
+// skipping malformed entries rather than failing startup over one typo.
+func ParsePeers(raw []string) []Peer {
+	peers := make([]Peer, 0, len(raw))
+	for _, entry := range raw {
+		name, url, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || url == "" {
+			log.Printf("cluster: ignoring malformed peer entry %q (want name=url)", entry)
+			continue
+		}
+		peers = append(peers, Peer{Name: name, URL: strings.TrimSuffix(url, "/")})
+	}
+	return peers
+}
+
+// NewRegistry creates a Registry backed by database. Call Start to begin
+// the background gossip push / reconciliation pull loop.
+func NewRegistry(database *db.DB, nodeID string, peers []Peer, authToken string, interval time.Duration) *Registry {
+	return &Registry{
+		db:        database,
+		nodeID:    nodeID,
+		peers:     peers,
+		authToken: authToken,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		interval:  interval,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start begins the background loop that gossips this node's outputs to its
+// peers and pulls any deltas it missed. A no-op if there are no peers.
+func (r *Registry) Start() {
+	if len(r.peers) == 0 {
+		return
+	}
+	go r.run()
+}
+
+// Stop signals the background loop to exit and waits for it to finish. A
+// no-op if there are no peers (Start never started a loop to stop).
+func (r *Registry) Stop() {
+	if len(r.peers) == 0 {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
+
+func (r *Registry) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.pushToPeers()
+			r.pullFromPeers()
+		}
+	}
+}
+
+// GossipEntry is one of this node's own conversions, as gossiped to a peer
+// or served from its index on a reconciliation pull.
+type GossipEntry struct {
+	FilePath      string    `json:"file_path"`
+	FileMD5       string    `json:"file_md5"`
+	HashAlgo      string    `json:"hash_algo"`
+	Status        string    `json:"status"`
+	ConverterName string    `json:"converter_name"`
+	TargetPath    string    `json:"target_path"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// gossipRequest is the body POSTed to a peer's /api/cluster/gossip.
+type gossipRequest struct {
+	NodeID  string        `json:"node_id"`
+	Entries []GossipEntry `json:"entries"`
+}
+
+// indexDeltaResponse is returned by a peer's GET /api/cluster/index.
+type indexDeltaResponse struct {
+	Entries []GossipEntry `json:"entries"`
+}
+
+// pushToPeers gossips this node's most recent conversions to every
+// configured peer. A peer being unreachable only logs a warning; the next
+// tick will try again.
+func (r *Registry) pushToPeers() {
+	outputs, err := r.db.ListRecentClusterOutputs(gossipBatchSize)
+	if err != nil {
+		log.Printf("cluster: failed to list recent outputs to gossip: %v", err)
+		return
+	}
+	if len(outputs) == 0 {
+		return
+	}
+
+	entries := make([]GossipEntry, 0, len(outputs))
+	for _, o := range outputs {
+		entries = append(entries, outputToGossipEntry(o))
+	}
+
+	body, err := json.Marshal(gossipRequest{NodeID: r.nodeID, Entries: entries})
+	if err != nil {
+		log.Printf("cluster: failed to marshal gossip payload: %v", err)
+		return
+	}
+
+	for _, peer := range r.peers {
+		r.postGossip(peer, body)
+	}
+}
+
+func (r *Registry) postGossip(peer Peer, body []byte) {
+	req, err := http.NewRequest(http.MethodPost, peer.URL+"/api/cluster/gossip", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("cluster: failed to build gossip request to %s: %v", peer.Name, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	r.setAuth(req)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		log.Printf("cluster: gossip push to %s failed: %v", peer.Name, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("cluster: gossip push to %s returned status %d", peer.Name, resp.StatusCode)
+	}
+}
+
+// pullFromPeers reconciles this node's peer index against every configured
+// peer's own index, picking up anything that wasn't (or couldn't be)
+// gossiped directly.
+func (r *Registry) pullFromPeers() {
+	for _, peer := range r.peers {
+		r.reconcilePeer(peer)
+	}
+}
+
+func (r *Registry) reconcilePeer(peer Peer) {
+	watermark, err := r.db.GetPeerWatermark(peer.Name)
+	if err != nil {
+		log.Printf("cluster: failed to load watermark for %s: %v", peer.Name, err)
+		return
+	}
+
+	url := fmt.Sprintf("%s/api/cluster/index?since=%s&limit=%d", peer.URL, watermark.UTC().Format(time.RFC3339), reconcileBatchSize)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		log.Printf("cluster: failed to build index request to %s: %v", peer.Name, err)
+		return
+	}
+	r.setAuth(req)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		log.Printf("cluster: index pull from %s failed: %v", peer.Name, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("cluster: index pull from %s returned status %d", peer.Name, resp.StatusCode)
+		return
+	}
+
+	var delta indexDeltaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&delta); err != nil {
+		log.Printf("cluster: failed to decode index delta from %s: %v", peer.Name, err)
+		return
+	}
+
+	newWatermark := watermark
+	for _, e := range delta.Entries {
+		if err := r.db.UpsertPeerIndexEntry(&db.PeerIndexEntry{
+			PeerNode:      peer.Name,
+			FilePath:      e.FilePath,
+			FileMD5:       e.FileMD5,
+			HashAlgo:      e.HashAlgo,
+			Status:        e.Status,
+			ConverterName: e.ConverterName,
+			TargetPath:    e.TargetPath,
+			UpdatedAt:     e.UpdatedAt,
+		}); err != nil {
+			log.Printf("cluster: failed to store peer index entry from %s: %v", peer.Name, err)
+			continue
+		}
+		if e.UpdatedAt.After(newWatermark) {
+			newWatermark = e.UpdatedAt
+		}
+	}
+
+	if newWatermark.After(watermark) {
+		if err := r.db.SetPeerWatermark(peer.Name, newWatermark); err != nil {
+			log.Printf("cluster: failed to persist watermark for %s: %v", peer.Name, err)
+		}
+	}
+}
+
+func (r *Registry) setAuth(req *http.Request) {
+	if r.authToken != "" {
+		req.Header.Set(AuthHeader, r.authToken)
+	}
+}
+
+func (r *Registry) authenticate(req *http.Request) bool {
+	if r.authToken == "" {
+		return true
+	}
+	return req.Header.Get(AuthHeader) == r.authToken
+}
+
+func (r *Registry) peerURL(name string) string {
+	for _, p := range r.peers {
+		if p.Name == name {
+			return p.URL
+		}
+	}
+	return ""
+}
+
+func outputToGossipEntry(o *db.ClusterOutput) GossipEntry {
+	return GossipEntry{
+		FilePath:      o.FilePath,
+		FileMD5:       o.FileMD5,
+		HashAlgo:      o.HashAlgo,
+		Status:        "success",
+		ConverterName: o.ConverterName,
+		TargetPath:    o.TargetPath,
+		UpdatedAt:     o.UpdatedAt,
+	}
+}
+
+// Consult looks up the local peer index (populated by gossip and
+// reconciliation) for a peer that has already converted (fileMD5, hashAlgo)
+// through converterName, without making a live network call. It returns
+// (nil, false) on a miss.
+func (r *Registry) Consult(fileMD5, hashAlgo, converterName string) (*db.PeerIndexEntry, bool) {
+	entry, err := r.db.GetPeerIndexEntry(fileMD5, hashAlgo, converterName)
+	if err != nil || entry == nil || entry.Status != "success" {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Fetch downloads entry's converted output from its origin peer to
+// dstPath, verifying the downloaded bytes hash to entry.FileMD5 (under
+// entry.HashAlgo) before moving it into place. On any failure (peer
+// unreachable, hash mismatch, I/O error) dstPath is left untouched and an
+// error is returned, so callers can fall back to a normal local conversion.
+func (r *Registry) Fetch(ctx context.Context, entry *db.PeerIndexEntry, dstPath string) error {
+	baseURL := r.peerURL(entry.PeerNode)
+	if baseURL == "" {
+		return fmt.Errorf("cluster: unknown peer %q", entry.PeerNode)
+	}
+
+	url := fmt.Sprintf("%s/api/cluster/fetch?md5=%s&hash_algo=%s&converter=%s", baseURL, entry.FileMD5, entry.HashAlgo, entry.ConverterName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	r.setAuth(req)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cluster: fetch from %s failed: %w", entry.PeerNode, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cluster: fetch from %s returned status %d", entry.PeerNode, resp.StatusCode)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(dstPath), ".cluster-fetch-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("cluster: failed to write fetched output: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	hasher, err := util.NewFileHasher(entry.HashAlgo)
+	if err != nil {
+		return err
+	}
+	got, err := hasher.Hash(tmpPath, 0)
+	if err != nil {
+		return fmt.Errorf("cluster: failed to hash fetched output: %w", err)
+	}
+	if got != entry.FileMD5 {
+		return fmt.Errorf("cluster: fetched output hash mismatch: got %s, want %s", got, entry.FileMD5)
+	}
+
+	return os.Rename(tmpPath, dstPath)
+}
+
+// RecordOutput records a successful local conversion so it can be gossiped
+// to peers and served via HandleLookup/HandleFetch.
+func (r *Registry) RecordOutput(filePath, fileMD5, hashAlgo, converterName, targetPath string) error {
+	return r.db.UpsertClusterOutput(&db.ClusterOutput{
+		FilePath:      filePath,
+		FileMD5:       fileMD5,
+		HashAlgo:      hashAlgo,
+		ConverterName: converterName,
+		TargetPath:    targetPath,
+	})
+}
+
+// RecordHit records a cluster cache hit (a job satisfied by fetching a
+// peer's output instead of converting locally), surfaced via
+// Stats.PeerHitCount.
+func (r *Registry) RecordHit(fileMD5, converterName, peerNode string) {
+	if err := r.db.InsertClusterPeerHit(fileMD5, converterName, peerNode); err != nil {
+		log.Printf("cluster: failed to record peer hit: %v", err)
+	}
+}
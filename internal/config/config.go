@@ -18,6 +18,7 @@ type Config struct {
 	PreserveMetadata       bool
 	MetadataStabilityDelay int
 	MD5ChunkSize           int64
+	MetadataBackend        string // "native", "exiftool", or "auto"
 }
 
 func Load() *Config {
@@ -32,6 +33,7 @@ func Load() *Config {
 	cfg.PreserveMetadata = getEnvBool("PRESERVE_METADATA", true)
 	cfg.MetadataStabilityDelay = getEnvInt("METADATA_STABILITY_DELAY", 1)
 	cfg.MD5ChunkSize = getEnvInt64("MD5_CHUNK_SIZE", 4*1024*1024)
+	cfg.MetadataBackend = getEnv("METADATA_BACKEND", "exiftool")
 	return cfg
 }
 
@@ -0,0 +1,131 @@
+// Package contenthash computes a "visual content" digest for an image that
+// is stable across re-encodes (different EXIF/container bytes, same
+// pixels). It chunks the decoded pixel stream with a simple rolling hash
+// (content-defined chunking, à la restic's chunker) so that a digest can be
+// formed from the sorted set of chunk hashes rather than a single
+// whole-file hash.
+package contenthash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// DefaultAvgChunkSize is the target average chunk size used when none is
+// configured: 1 MiB.
+const DefaultAvgChunkSize = 1 << 20
+
+// defaultPolynomial is an arbitrary odd 64-bit constant used to mix bytes
+// into the rolling hash. It has no special mathematical properties beyond
+// being fixed, so the same input always produces the same chunk boundaries.
+const defaultPolynomial uint64 = 0x3DA3358B4DC173
+
+// Chunker splits a byte stream into content-defined chunks: a boundary is
+// declared whenever a rolling hash of the last windowSize bytes matches a
+// mask derived from AvgSize, so that inserting or removing bytes only
+// perturbs the chunks near the edit instead of every chunk after it.
+type Chunker struct {
+	Polynomial uint64
+	AvgSize    int
+	MinSize    int
+	MaxSize    int
+}
+
+// NewChunker creates a Chunker with the given average chunk size. A zero or
+// negative avgSize falls back to DefaultAvgChunkSize.
+func NewChunker(avgSize int) *Chunker {
+	if avgSize <= 0 {
+		avgSize = DefaultAvgChunkSize
+	}
+	return &Chunker{
+		Polynomial: defaultPolynomial,
+		AvgSize:    avgSize,
+		MinSize:    avgSize / 4,
+		MaxSize:    avgSize * 8,
+	}
+}
+
+const windowSize = 64
+
+// mask is chosen so that, for uniformly random data, a boundary is declared
+// on average every AvgSize bytes (the hash has log2(AvgSize) relevant bits).
+func (c *Chunker) mask() uint64 {
+	bits := 0
+	for sz := c.AvgSize; sz > 1; sz >>= 1 {
+		bits++
+	}
+	if bits == 0 {
+		bits = 1
+	}
+	return (uint64(1) << bits) - 1
+}
+
+// Chunk splits data into content-defined chunks and returns the byte
+// offsets where each chunk ends (exclusive), i.e. len(data) is always the
+// last boundary.
+func (c *Chunker) Chunk(data []byte) []int {
+	if len(data) == 0 {
+		return nil
+	}
+
+	mask := c.mask()
+	var boundaries []int
+	var roll uint64
+	chunkStart := 0
+
+	// outFactor is Polynomial^windowSize: the weight carried by the byte
+	// that leaves the window once it has filled.
+	outFactor := uint64(1)
+	for k := 0; k < windowSize; k++ {
+		outFactor *= c.Polynomial
+	}
+
+	for i, b := range data {
+		roll = roll*c.Polynomial + uint64(b)
+		if i-chunkStart >= windowSize {
+			roll -= uint64(data[i-windowSize]) * outFactor
+		}
+
+		chunkLen := i - chunkStart + 1
+		if chunkLen < c.MinSize {
+			continue
+		}
+
+		if chunkLen >= c.MaxSize || (roll&mask) == mask {
+			boundaries = append(boundaries, i+1)
+			chunkStart = i + 1
+			roll = 0
+		}
+	}
+
+	if chunkStart < len(data) {
+		boundaries = append(boundaries, len(data))
+	}
+
+	return boundaries
+}
+
+// Digest computes the stable content digest of data: a SHA-256 over the
+// sorted list of per-chunk SHA-256 hashes. Because the hashes are sorted
+// before being combined, the digest doesn't depend on chunk order, only on
+// the multiset of chunk contents.
+func (c *Chunker) Digest(data []byte) string {
+	boundaries := c.Chunk(data)
+
+	chunkHashes := make([]string, 0, len(boundaries))
+	start := 0
+	for _, end := range boundaries {
+		sum := sha256.Sum256(data[start:end])
+		chunkHashes = append(chunkHashes, hex.EncodeToString(sum[:]))
+		start = end
+	}
+
+	sort.Strings(chunkHashes)
+
+	combined := sha256.New()
+	for _, h := range chunkHashes {
+		combined.Write([]byte(h))
+	}
+	return hex.EncodeToString(combined.Sum(nil))
+}
@@ -0,0 +1,110 @@
+package contenthash
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+)
+
+// CacheEntry is a single dedup cache record: a visual digest maps to the
+// previously produced output for a given converter+quality pair.
+type CacheEntry struct {
+	VisualDigest string
+	Converter    string
+	Quality      int
+	OutputPath   string
+}
+
+// Stats holds dedup cache hit/miss counters for reporting.
+type Stats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Size      int
+}
+
+// Manager is an in-memory LRU cache of CacheEntry, keyed by
+// (visual digest, converter, quality). It is a front for the persistent
+// dedup table in the database: a miss here doesn't necessarily mean the
+// database has no record, only that it isn't hot in memory.
+type Manager struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+
+	hits, misses, evictions int64
+}
+
+// NewManager creates a dedup cache manager holding up to capacity entries
+// in memory before evicting the least recently used one.
+func NewManager(capacity int) *Manager {
+	if capacity <= 0 {
+		capacity = 1024
+	}
+	return &Manager{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func cacheKey(digest, converter string, quality int) string {
+	return digest + "|" + converter + "|" + strconv.Itoa(quality)
+}
+
+// Get looks up a cached entry, promoting it to most-recently-used on a hit.
+func (m *Manager) Get(digest, converter string, quality int) (CacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := cacheKey(digest, converter, quality)
+	el, ok := m.items[key]
+	if !ok {
+		m.misses++
+		return CacheEntry{}, false
+	}
+
+	m.ll.MoveToFront(el)
+	m.hits++
+	return el.Value.(CacheEntry), true
+}
+
+// Put inserts or refreshes a cache entry, evicting the least recently used
+// one if the manager is at capacity.
+func (m *Manager) Put(entry CacheEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := cacheKey(entry.VisualDigest, entry.Converter, entry.Quality)
+	if el, ok := m.items[key]; ok {
+		el.Value = entry
+		m.ll.MoveToFront(el)
+		return
+	}
+
+	el := m.ll.PushFront(entry)
+	m.items[key] = el
+
+	if m.ll.Len() > m.capacity {
+		oldest := m.ll.Back()
+		if oldest != nil {
+			m.ll.Remove(oldest)
+			oldKey := cacheKey(oldest.Value.(CacheEntry).VisualDigest, oldest.Value.(CacheEntry).Converter, oldest.Value.(CacheEntry).Quality)
+			delete(m.items, oldKey)
+			m.evictions++
+		}
+	}
+}
+
+// Stats returns a snapshot of cache hit/miss/eviction counters.
+func (m *Manager) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Stats{
+		Hits:      m.hits,
+		Misses:    m.misses,
+		Evictions: m.evictions,
+		Size:      m.ll.Len(),
+	}
+}
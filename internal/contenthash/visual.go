@@ -0,0 +1,46 @@
+package contenthash
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// VisualDigest decodes the image at path and returns a content digest of
+// its raw pixel stream. Two files that decode to the same pixels (e.g. a
+// JPEG re-encoded with different EXIF data or quality settings) produce the
+// same digest even though their file bytes differ.
+func VisualDigest(path string, chunker *Chunker) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open for visual digest: %w", err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("decode image: %w", err)
+	}
+
+	return chunker.Digest(pixelBytes(img)), nil
+}
+
+// pixelBytes flattens an image.Image into a raw RGBA byte stream in
+// row-major order, independent of the source format's internal encoding.
+func pixelBytes(img image.Image) []byte {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	buf := make([]byte, 0, width*height*4)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			buf = append(buf, byte(r>>8), byte(g>>8), byte(b>>8), byte(a>>8))
+		}
+	}
+
+	return buf
+}
@@ -2,7 +2,13 @@ package converter
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/progress"
 )
 
 // ConvertOptions holds configuration for a conversion operation
@@ -12,6 +18,29 @@ type ConvertOptions struct {
 	TempDir          string            // Temporary directory for intermediate files
 	Timeout          time.Duration     // Conversion timeout
 	ExtraArgs        map[string]string // Converter-specific arguments
+	// TaskID correlates this conversion with the originating watcher/worker
+	// task (currently the source file path) so logs emitted by the
+	// converter can be grepped alongside the rest of that task's activity.
+	TaskID string
+	// ContentHash and HashAlgorithm are the source file's already-computed
+	// content digest (see util.FileHasher), passed through so a converter
+	// that caches its output (e.g. WorkflowConverter) doesn't need to
+	// re-hash the file itself. Both are empty if the caller didn't compute
+	// one (e.g. CanConvert's throwaway probe).
+	ContentHash   string
+	HashAlgorithm string
+}
+
+// OptionsHash derives a stable digest of the settings that can change a
+// conversion's output for otherwise-identical input bytes (currently
+// Quality and PreserveMetadata), so db.ConvertedArtifact lookups for the
+// same file content don't reuse an output produced under different
+// settings. Per-invocation fields (TempDir, Timeout, TaskID, ContentHash,
+// HashAlgorithm, ExtraArgs) are deliberately excluded: they vary call to
+// call without changing what the converter actually produces.
+func (o ConvertOptions) OptionsHash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%t", o.Quality, o.PreserveMetadata)))
+	return hex.EncodeToString(sum[:])
 }
 
 // MetaResult contains metadata preservation results
@@ -21,6 +50,25 @@ type MetaResult struct {
 	SourceMD5         string // MD5 of source file
 	OutputMD5         string // MD5 of output file
 	ConversionLog     string // Detailed conversion log
+	// Annotations is a JSON array of workflow.Annotation raised via
+	// "::error::"/"::warning::"/"::notice::"/"::debug::" during conversion.
+	// Empty for converters that don't support workflow commands.
+	Annotations string
+	// TagDiffs is the tag-by-tag metadata preservation outcome; see
+	// TagDiff. Empty for converters that don't support tag-level
+	// comparison, or for streaming conversions with no destination file to
+	// re-extract from.
+	TagDiffs []TagDiff
+}
+
+// TagDiff is one metadata tag's preservation outcome, comparing the value
+// extractMetadata read from the source file against the same tag in the
+// converted output.
+type TagDiff struct {
+	Tag         string
+	SourceValue string
+	OutputValue string
+	Preserved   bool
 }
 
 // Converter defines the interface for format converters
@@ -44,6 +92,31 @@ type Converter interface {
 	Convert(ctx context.Context, srcPath string, dstPath string, opts ConvertOptions) (MetaResult, error)
 }
 
+// StreamConverter is implemented by converters that can also run directly
+// against an io.Reader/io.Writer pair instead of file paths, so they can be
+// used as a Unix filter in shell pipelines or as a sidecar in containerized
+// image pipelines that never touches the caller's filesystem.
+type StreamConverter interface {
+	// ConvertStream reads a source image from in and writes the converted
+	// output to out. Metadata preservation in this mode is best-effort:
+	// tools that require a file path (e.g. exiftool) can't run directly
+	// against a stream, so MetaResult.MetadataSummary should say so
+	// explicitly when preservation falls back to a reduced, in-process copy.
+	ConvertStream(ctx context.Context, in io.Reader, out io.Writer, opts ConvertOptions) (MetaResult, error)
+}
+
+// ProgressConverter is implemented by converters that can publish live
+// progress.JobEvents (log lines as they're produced) while converting, for
+// callers that hold a progress.Publisher for the current task (e.g. the
+// worker pool's per-task SSE stream). Converters that don't implement this
+// just run via Convert, exactly as before.
+type ProgressConverter interface {
+	// ConvertWithProgress behaves like Convert, but publishes JobEventLog
+	// events to pub as the underlying tool produces output, instead of only
+	// returning it in MetaResult.ConversionLog once the call finishes.
+	ConvertWithProgress(ctx context.Context, srcPath string, dstPath string, opts ConvertOptions, pub progress.Publisher) (MetaResult, error)
+}
+
 // ConverterInfo provides information about a registered converter
 type ConverterInfo struct {
 	Name               string   `json:"name"`
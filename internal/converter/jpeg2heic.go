@@ -1,18 +1,43 @@
 package converter
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/ah-its-andy/jpeg2heif/internal/metadata"
+	"github.com/ah-its-andy/jpeg2heif/internal/metrics"
+	"github.com/ah-its-andy/jpeg2heif/internal/progress"
 	"github.com/rwcarlsen/goexif/exif"
 )
 
+// metadataExtractor is the pluggable backend extractMetadata uses to read
+// a file's full tag set; see metadata.NewExtractor. "auto" tries exiftool
+// first and falls back to the dependency-free goexif reader, matching
+// checkExternalTools' own preference for exiftool when it's available.
+var metadataExtractor = metadata.NewExtractor("auto")
+
+// gracefulCancel makes cmd respond to its context being canceled (e.g. the
+// conversion's internal/lock.Lease was lost to another owner) by sending
+// SIGTERM instead of Go's exec default of SIGKILL, giving heif-enc a chance
+// to clean up its own temp files; cmd is still force-killed if it hasn't
+// exited 5 seconds after that.
+func gracefulCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = 5 * time.Second
+}
+
 // JPEG2HEICConverter converts JPEG files to HEIC format
 type JPEG2HEICConverter struct{}
 
@@ -35,6 +60,10 @@ func (c *JPEG2HEICConverter) TargetFormat() string {
 }
 
 func (c *JPEG2HEICConverter) Convert(ctx context.Context, srcPath string, dstPath string, opts ConvertOptions) (MetaResult, error) {
+	tlog := logger.With("task_id", opts.TaskID)
+	tlog.Debug("jpeg2heic conversion starting", "src", srcPath, "dst", dstPath)
+	start := time.Now()
+
 	result := MetaResult{
 		MetadataPreserved: false,
 		ConversionLog:     "",
@@ -66,15 +95,20 @@ func (c *JPEG2HEICConverter) Convert(ctx context.Context, srcPath string, dstPat
 	}
 
 	encCmd := exec.CommandContext(ctx, "heif-enc", "-q", fmt.Sprintf("%d", quality), "-o", tmpFile, srcPath)
+	gracefulCancel(encCmd)
 	output, err := encCmd.CombinedOutput()
 	result.ConversionLog += fmt.Sprintf("heif-enc output:\n%s\n", string(output))
+	tlog.Debug("heif-enc output", "output", string(output))
 
 	if err != nil {
+		tlog.Error("heif-enc failed", "error", err.Error())
+		metrics.RecordConversion(c.Name(), metrics.ResultHeifEncFailed, time.Since(start), result.MetadataPreserved)
 		return result, fmt.Errorf("heif-enc failed: %w, output: %s", err, string(output))
 	}
 
 	// Verify temporary file was created
 	if _, err := os.Stat(tmpFile); err != nil {
+		metrics.RecordConversion(c.Name(), metrics.ResultHeifEncFailed, time.Since(start), result.MetadataPreserved)
 		return result, fmt.Errorf("heif-enc did not create output file: %w", err)
 	}
 
@@ -96,33 +130,252 @@ func (c *JPEG2HEICConverter) Convert(ctx context.Context, srcPath string, dstPat
 		}
 	}
 
-	// Verify DateTimeOriginal was preserved
+	// Verify DateTimeOriginal was preserved, and record the full tag-by-tag
+	// diff so callers can see preservation at a finer grain than this one
+	// date field (see db.FileMetadata).
 	targetMeta, err := extractMetadata(tmpFile)
 	if err == nil && sourceMeta != nil && targetMeta != nil {
-		srcTime := sourceMeta["DateTimeOriginal"]
-		dstTime := targetMeta["DateTimeOriginal"]
+		srcTime := lookupTag(sourceMeta, "DateTimeOriginal")
+		dstTime := lookupTag(targetMeta, "DateTimeOriginal")
 		if srcTime != "" && srcTime == dstTime {
 			result.ConversionLog += fmt.Sprintf("Verified: DateTimeOriginal preserved (%s)\n", srcTime)
 		} else {
 			result.ConversionLog += fmt.Sprintf("Warning: DateTimeOriginal mismatch (src: %s, dst: %s)\n", srcTime, dstTime)
 		}
+		result.TagDiffs = diffMetadataTags(sourceMeta, targetMeta)
 	}
 
 	// Create destination directory if needed
 	dstDir := filepath.Dir(dstPath)
 	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		metrics.RecordConversion(c.Name(), metrics.ResultCopyFailed, time.Since(start), result.MetadataPreserved)
 		return result, fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
 	// Copy file to destination (handles cross-device moves)
 	if err := copyFile(tmpFile, dstPath); err != nil {
+		metrics.RecordConversion(c.Name(), metrics.ResultCopyFailed, time.Since(start), result.MetadataPreserved)
 		return result, fmt.Errorf("failed to copy output to destination: %w", err)
 	}
 
 	result.ConversionLog += fmt.Sprintf("Conversion completed successfully: %s -> %s\n", srcPath, dstPath)
+	tlog.Debug("jpeg2heic conversion completed", "metadata_preserved", result.MetadataPreserved)
+	metrics.RecordConversion(c.Name(), metrics.ResultSuccess, time.Since(start), result.MetadataPreserved)
+	recordConversionBytes(c.Name(), srcPath, dstPath)
 	return result, nil
 }
 
+// ConvertWithProgress behaves exactly like Convert, except heif-enc's
+// stdout/stderr are streamed line-by-line to pub as JobEventLog events
+// while the encoder runs, instead of only being available in
+// MetaResult.ConversionLog once the call returns. This gives a client
+// attached to the task's SSE stream visibility into a slow conversion as
+// it happens, rather than a single burst of output at the end.
+func (c *JPEG2HEICConverter) ConvertWithProgress(ctx context.Context, srcPath string, dstPath string, opts ConvertOptions, pub progress.Publisher) (MetaResult, error) {
+	tlog := logger.With("task_id", opts.TaskID)
+	tlog.Debug("jpeg2heic conversion starting", "src", srcPath, "dst", dstPath)
+	start := time.Now()
+
+	result := MetaResult{
+		MetadataPreserved: false,
+		ConversionLog:     "",
+	}
+
+	if err := checkExternalTools(); err != nil {
+		return result, fmt.Errorf("external tools check failed: %w", err)
+	}
+
+	sourceMeta, err := extractMetadata(srcPath)
+	if err != nil {
+		result.ConversionLog += fmt.Sprintf("Warning: failed to extract source metadata: %v\n", err)
+	}
+
+	tmpDir := opts.TempDir
+	if tmpDir == "" {
+		tmpDir = os.TempDir()
+	}
+	tmpFile := filepath.Join(tmpDir, fmt.Sprintf("jpeg2heif_%d.heic", time.Now().UnixNano()))
+	defer os.Remove(tmpFile)
+
+	quality := opts.Quality
+	if quality <= 0 || quality > 100 {
+		quality = 85
+	}
+
+	encCmd := exec.CommandContext(ctx, "heif-enc", "-q", fmt.Sprintf("%d", quality), "-o", tmpFile, srcPath)
+	gracefulCancel(encCmd)
+	stdout, err := encCmd.StdoutPipe()
+	if err != nil {
+		return result, fmt.Errorf("failed to open heif-enc stdout: %w", err)
+	}
+	encCmd.Stderr = encCmd.Stdout
+	if err := encCmd.Start(); err != nil {
+		return result, fmt.Errorf("failed to start heif-enc: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		result.ConversionLog += line + "\n"
+		tlog.Debug("heif-enc output", "line", line)
+		pub.Publish(progress.JobEvent{
+			Type:      progress.JobEventLog,
+			Path:      srcPath,
+			Message:   line,
+			Timestamp: time.Now(),
+		})
+	}
+
+	if err := encCmd.Wait(); err != nil {
+		tlog.Error("heif-enc failed", "error", err.Error())
+		metrics.RecordConversion(c.Name(), metrics.ResultHeifEncFailed, time.Since(start), result.MetadataPreserved)
+		return result, fmt.Errorf("heif-enc failed: %w, output: %s", err, result.ConversionLog)
+	}
+
+	if _, err := os.Stat(tmpFile); err != nil {
+		metrics.RecordConversion(c.Name(), metrics.ResultHeifEncFailed, time.Since(start), result.MetadataPreserved)
+		return result, fmt.Errorf("heif-enc did not create output file: %w", err)
+	}
+
+	if opts.PreserveMetadata {
+		if err := injectMetadata(srcPath, tmpFile); err != nil {
+			result.ConversionLog += fmt.Sprintf("Warning: metadata injection failed: %v\n", err)
+		} else {
+			result.MetadataPreserved = true
+			result.MetadataSummary = "Full EXIF/XMP metadata preserved"
+		}
+	} else {
+		if err := preserveDateTimeOriginal(srcPath, tmpFile, sourceMeta); err != nil {
+			result.ConversionLog += fmt.Sprintf("Warning: DateTimeOriginal preservation failed: %v\n", err)
+		} else {
+			result.MetadataPreserved = true
+			result.MetadataSummary = "DateTimeOriginal preserved"
+		}
+	}
+
+	targetMeta, err := extractMetadata(tmpFile)
+	if err == nil && sourceMeta != nil && targetMeta != nil {
+		srcTime := lookupTag(sourceMeta, "DateTimeOriginal")
+		dstTime := lookupTag(targetMeta, "DateTimeOriginal")
+		if srcTime != "" && srcTime == dstTime {
+			result.ConversionLog += fmt.Sprintf("Verified: DateTimeOriginal preserved (%s)\n", srcTime)
+		} else {
+			result.ConversionLog += fmt.Sprintf("Warning: DateTimeOriginal mismatch (src: %s, dst: %s)\n", srcTime, dstTime)
+		}
+		result.TagDiffs = diffMetadataTags(sourceMeta, targetMeta)
+	}
+
+	dstDir := filepath.Dir(dstPath)
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		metrics.RecordConversion(c.Name(), metrics.ResultCopyFailed, time.Since(start), result.MetadataPreserved)
+		return result, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	if err := copyFile(tmpFile, dstPath); err != nil {
+		metrics.RecordConversion(c.Name(), metrics.ResultCopyFailed, time.Since(start), result.MetadataPreserved)
+		return result, fmt.Errorf("failed to copy output to destination: %w", err)
+	}
+
+	result.ConversionLog += fmt.Sprintf("Conversion completed successfully: %s -> %s\n", srcPath, dstPath)
+	tlog.Debug("jpeg2heic conversion completed", "metadata_preserved", result.MetadataPreserved)
+	metrics.RecordConversion(c.Name(), metrics.ResultSuccess, time.Since(start), result.MetadataPreserved)
+	recordConversionBytes(c.Name(), srcPath, dstPath)
+	return result, nil
+}
+
+// ConvertStream converts a JPEG read from in to HEIC written to out, without
+// touching the filesystem beyond what heif-enc itself requires. It exists so
+// this converter can be used as a Unix filter (e.g. `jpeg2heif serve stream`)
+// or as a sidecar in a containerized pipeline.
+//
+// Metadata preservation is necessarily reduced here: exiftool, used for full
+// EXIF/XMP preservation in the file-based Convert, requires a real file path
+// for both its source and destination and so can't run against a stream.
+// Instead we read DateTimeOriginal in-process via goexif and report it in
+// MetadataSummary; we do not attempt to write EXIF into the HEIC container,
+// since that requires ISOBMFF box surgery well beyond a "small writer". This
+// degradation from file-mode preservation is intentional and called out in
+// the returned MetaResult.
+func (c *JPEG2HEICConverter) ConvertStream(ctx context.Context, in io.Reader, out io.Writer, opts ConvertOptions) (MetaResult, error) {
+	result := MetaResult{}
+
+	if err := checkExternalTools(); err != nil {
+		return result, fmt.Errorf("external tools check failed: %w", err)
+	}
+
+	srcBytes, err := io.ReadAll(in)
+	if err != nil {
+		return result, fmt.Errorf("failed to read source stream: %w", err)
+	}
+
+	quality := opts.Quality
+	if quality <= 0 || quality > 100 {
+		quality = 85
+	}
+
+	var outBuf, logBuf bytes.Buffer
+	encCmd := exec.CommandContext(ctx, "heif-enc", "-q", fmt.Sprintf("%d", quality), "-o", "-", "-")
+	gracefulCancel(encCmd)
+	encCmd.Stdin = bytes.NewReader(srcBytes)
+	encCmd.Stdout = &outBuf
+	encCmd.Stderr = &logBuf
+	if err := encCmd.Run(); err != nil {
+		return result, fmt.Errorf("heif-enc failed: %w, output: %s", err, logBuf.String())
+	}
+	result.ConversionLog = fmt.Sprintf("heif-enc output:\n%s\n", logBuf.String())
+
+	if _, err := out.Write(outBuf.Bytes()); err != nil {
+		return result, fmt.Errorf("failed to write converted stream: %w", err)
+	}
+
+	if opts.PreserveMetadata {
+		dateTimeOriginal, exifErr := readDateTimeOriginalFromBytes(srcBytes)
+		if exifErr != nil {
+			result.MetadataSummary = fmt.Sprintf("streaming mode: source EXIF unreadable (%v); metadata not preserved", exifErr)
+		} else if dateTimeOriginal == "" {
+			result.MetadataSummary = "streaming mode: source has no DateTimeOriginal; metadata not preserved"
+		} else {
+			result.MetadataSummary = fmt.Sprintf(
+				"streaming mode: detected source DateTimeOriginal=%s via in-process EXIF read, but it was not embedded in the output "+
+					"(exiftool requires file-based I/O and was not used); degraded from file-mode preservation",
+				dateTimeOriginal,
+			)
+		}
+	} else {
+		result.MetadataSummary = "metadata preservation disabled"
+	}
+
+	return result, nil
+}
+
+// readDateTimeOriginalFromBytes decodes EXIF from in-memory JPEG bytes and
+// returns the DateTimeOriginal tag, without needing a file on disk.
+func readDateTimeOriginalFromBytes(jpegData []byte) (string, error) {
+	x, err := exif.Decode(bytes.NewReader(jpegData))
+	if err != nil {
+		return "", err
+	}
+
+	dt, err := x.DateTime()
+	if err != nil {
+		return "", nil
+	}
+
+	return dt.Format("2006:01:02 15:04:05"), nil
+}
+
+// recordConversionBytes updates the bytes in/out counters from srcPath and
+// dstPath's final on-disk sizes. Sizing errors are ignored; metrics are
+// best-effort and must never affect conversion outcome.
+func recordConversionBytes(converterName, srcPath, dstPath string) {
+	if srcInfo, err := os.Stat(srcPath); err == nil {
+		metrics.ConversionBytesIn.Add(float64(srcInfo.Size()), converterName)
+	}
+	if dstInfo, err := os.Stat(dstPath); err == nil {
+		metrics.ConversionBytesOut.Add(float64(dstInfo.Size()), converterName)
+	}
+}
+
 // copyFile copies a file from src to dst
 func copyFile(src, dst string) error {
 	sourceFile, err := os.Open(src)
@@ -156,38 +409,60 @@ func checkExternalTools() error {
 	return nil
 }
 
-// extractMetadata extracts EXIF metadata from a file
+// extractMetadata extracts every metadata tag metadataExtractor can find in
+// a file (GPS, camera model, lens, ICC profile, orientation, all date
+// fields, and more), keyed by the backend's own tag names (see
+// metadata.Extractor). For a HEIC/HEIF file it also probes for an embedded
+// ICC profile via heif-info and adds it as "ICCProfileName", since that's
+// more reliable for this container than what exiftool reports.
 func extractMetadata(filePath string) (map[string]string, error) {
-	metadata := make(map[string]string)
-
-	// Try using exiftool first (more reliable)
-	cmd := exec.Command("exiftool", "-s", "-s", "-s", "-DateTimeOriginal", "-CreateDate", "-ModifyDate", filePath)
-	output, err := cmd.Output()
-	if err == nil {
-		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-		if len(lines) > 0 && lines[0] != "" {
-			metadata["DateTimeOriginal"] = strings.TrimSpace(lines[0])
-		}
-	}
-
-	// Also try using goexif as backup
-	f, err := os.Open(filePath)
+	tags, err := metadataExtractor.Extract(filePath)
 	if err != nil {
-		return metadata, err
+		return tags, err
 	}
-	defer f.Close()
 
-	x, err := exif.Decode(f)
-	if err != nil {
-		return metadata, err
+	if ext := strings.ToLower(filepath.Ext(filePath)); ext == ".heic" || ext == ".heif" {
+		if name, iccErr := metadata.HeifICCProfileName(filePath); iccErr == nil && name != "" {
+			tags["ICCProfileName"] = name
+		}
 	}
 
-	// Get DateTimeOriginal
-	if dt, err := x.DateTime(); err == nil {
-		metadata["DateTimeOriginal"] = dt.Format("2006:01:02 15:04:05")
+	return tags, nil
+}
+
+// lookupTag returns the value of the first tag in tags whose name, ignoring
+// any backend-specific "Group:" prefix, equals name, so callers that only
+// care about one tag (e.g. DateTimeOriginal) don't need to know which group
+// the active extractor filed it under.
+func lookupTag(tags map[string]string, name string) string {
+	if v, ok := tags[name]; ok {
+		return v
 	}
+	for key, value := range tags {
+		if idx := strings.LastIndex(key, ":"); idx >= 0 && key[idx+1:] == name {
+			return value
+		}
+	}
+	return ""
+}
 
-	return metadata, nil
+// diffMetadataTags compares every tag extractMetadata read from the source
+// against the same tag (matched by full key, group prefix included) in the
+// converted output, returning one TagDiff per tag the source exposed. A tag
+// missing from the output entirely counts as not preserved.
+func diffMetadataTags(sourceMeta, targetMeta map[string]string) []TagDiff {
+	diffs := make([]TagDiff, 0, len(sourceMeta))
+	for tag, srcVal := range sourceMeta {
+		dstVal := targetMeta[tag]
+		diffs = append(diffs, TagDiff{
+			Tag:         tag,
+			SourceValue: srcVal,
+			OutputValue: dstVal,
+			Preserved:   srcVal != "" && srcVal == dstVal,
+		})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Tag < diffs[j].Tag })
+	return diffs
 }
 
 // injectMetadata copies all metadata from source to destination
@@ -206,16 +481,16 @@ func preserveDateTimeOriginal(srcPath, dstPath string, sourceMeta map[string]str
 
 	// Try to get from extracted metadata
 	if sourceMeta != nil {
-		dateTime = sourceMeta["DateTimeOriginal"]
+		dateTime = lookupTag(sourceMeta, "DateTimeOriginal")
 	}
 
 	// If not found, try extracting again
 	if dateTime == "" {
 		meta, err := extractMetadata(srcPath)
-		if err != nil || meta["DateTimeOriginal"] == "" {
+		if err != nil || lookupTag(meta, "DateTimeOriginal") == "" {
 			return fmt.Errorf("could not find DateTimeOriginal in source file")
 		}
-		dateTime = meta["DateTimeOriginal"]
+		dateTime = lookupTag(meta, "DateTimeOriginal")
 	}
 
 	// Inject DateTimeOriginal into destination
@@ -0,0 +1,18 @@
+package converter
+
+import "github.com/ah-its-andy/jpeg2heif/internal/logging"
+
+// logger is shared by every converter implementation in this package, so a
+// conversion's log lines can be correlated with the rest of its task's
+// activity via ConvertOptions.TaskID without threading a Logger through
+// every Converter's constructor.
+var logger logging.Logger = logging.NewNop()
+
+// SetLogger wires a structured logging.Logger for all converters in this
+// package. Call before converting; defaults to discarding everything.
+func SetLogger(l logging.Logger) {
+	if l == nil {
+		l = logging.NewNop()
+	}
+	logger = l
+}
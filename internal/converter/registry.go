@@ -2,9 +2,19 @@ package converter
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 )
 
+// MatchPrioritizer is implemented by converters that want a say in
+// deterministic priority ordering when more than one enabled converter can
+// handle the same file (e.g. WorkflowConverter's inputs.match rules letting
+// a user declare per-folder policies). Converters that don't implement it
+// are treated as priority 0.
+type MatchPrioritizer interface {
+	MatchPriority(srcPath string, srcMime string) (matched bool, priority int)
+}
+
 var (
 	registry = make(map[string]Converter)
 	mu       sync.RWMutex
@@ -52,21 +62,54 @@ func ListInfo() []ConverterInfo {
 	return infos
 }
 
-// FindConverter finds the first enabled converter that can handle the given file
+// candidate pairs an enabled converter able to handle a file with its
+// priority, so FindConverter can pick deterministically among several
+// matches instead of depending on map iteration order.
+type candidate struct {
+	conv     Converter
+	priority int
+}
+
+// FindConverter finds the enabled converter best suited to handle the given
+// file. Converters implementing MatchPrioritizer (e.g. workflows with an
+// inputs.match block) are ranked by priority, highest first; ties and
+// converters without an opinion (priority 0) are broken by name for
+// deterministic results across runs.
 func FindConverter(srcPath string, srcMime string) (Converter, error) {
 	mu.RLock()
 	defer mu.RUnlock()
 
+	var candidates []candidate
 	for name, c := range registry {
 		if disabled[name] {
 			continue
 		}
-		if c.CanConvert(srcPath, srcMime) {
-			return c, nil
+		if !c.CanConvert(srcPath, srcMime) {
+			continue
+		}
+
+		priority := 0
+		if mp, ok := c.(MatchPrioritizer); ok {
+			if matched, p := mp.MatchPriority(srcPath, srcMime); matched {
+				priority = p
+			}
 		}
+
+		candidates = append(candidates, candidate{conv: c, priority: priority})
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no converter found for file: %s (mime: %s)", srcPath, srcMime)
 	}
 
-	return nil, fmt.Errorf("no converter found for file: %s (mime: %s)", srcPath, srcMime)
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].priority != candidates[j].priority {
+			return candidates[i].priority > candidates[j].priority
+		}
+		return candidates[i].conv.Name() < candidates[j].conv.Name()
+	})
+
+	return candidates[0].conv, nil
 }
 
 // Enable enables a converter by name
@@ -2,16 +2,107 @@ package converter
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ah-its-andy/jpeg2heif/internal/blobstore"
 	"github.com/ah-its-andy/jpeg2heif/internal/db"
+	"github.com/ah-its-andy/jpeg2heif/internal/livelog"
+	"github.com/ah-its-andy/jpeg2heif/internal/metrics"
+	"github.com/ah-its-andy/jpeg2heif/internal/progress"
 	"github.com/ah-its-andy/jpeg2heif/internal/workflow"
+	"github.com/ah-its-andy/jpeg2heif/internal/workflowcache"
 )
 
+// workflowRunEvents buffers step-level progress events per WorkflowRun.ID,
+// so the API layer's SSE stream endpoint can serve live progress without a
+// registry being threaded through every caller. Mirrors livelog.Manager's
+// singleton, keyed by run ID instead of file path.
+var workflowRunEvents = progress.NewRegistry(200)
+
+// WorkflowRunEvents returns the Publisher carrying live step-progress
+// events for the WorkflowRun with the given ID, for use by the API layer's
+// workflow-run stream endpoint.
+func WorkflowRunEvents(runID int64) progress.Publisher {
+	return workflowRunEvents.Get(strconv.FormatInt(runID, 10))
+}
+
+// resultCache is the process-wide workflow result cache, wired up by
+// SetWorkflowCache at startup (see cmd/jpeg2heif/main.go). A nil cache
+// (the default) disables caching: Convert always runs the workflow.
+var (
+	workflowCacheMu sync.RWMutex
+	resultCache     *workflowcache.Cache
+)
+
+// SetWorkflowCache wires the workflow result cache used by
+// WorkflowConverter.Convert to skip re-running a workflow when an
+// identical (source content hash, workflow definition, quality) has
+// already been produced. Pass nil to disable caching.
+func SetWorkflowCache(cache *workflowcache.Cache) {
+	workflowCacheMu.Lock()
+	defer workflowCacheMu.Unlock()
+	resultCache = cache
+}
+
+func getWorkflowCache() *workflowcache.Cache {
+	workflowCacheMu.RLock()
+	defer workflowCacheMu.RUnlock()
+	return resultCache
+}
+
+// blobStore is the process-wide content-addressed chunk store, wired up by
+// SetBlobStore at startup (see cmd/jpeg2heif/main.go). A nil store (the
+// default) disables it: Convert falls back to a plain file copy for the
+// primary output, same as before blob storage existed.
+var (
+	blobStoreMu sync.RWMutex
+	blobStore   *blobstore.Store
+)
+
+// SetBlobStore wires the content-addressed blob store WorkflowConverter.Convert
+// uses to split the primary output into content-defined chunks instead of
+// copying it whole, deduplicating chunks shared with previously produced
+// outputs. Pass nil to disable it.
+func SetBlobStore(store *blobstore.Store) {
+	blobStoreMu.Lock()
+	defer blobStoreMu.Unlock()
+	blobStore = store
+}
+
+func getBlobStore() *blobstore.Store {
+	blobStoreMu.RLock()
+	defer blobStoreMu.RUnlock()
+	return blobStore
+}
+
+// currentDatabase is the process-wide database handle, wired up by
+// SetDatabase (via LoadWorkflowConverters, at startup). A nil database (the
+// default) means runtime workflow lookup hasn't been set up yet.
+var (
+	currentDatabaseMu sync.RWMutex
+	currentDatabase   *db.DB
+)
+
+// SetDatabase wires the database handle used for runtime workflow lookup.
+func SetDatabase(database *db.DB) {
+	currentDatabaseMu.Lock()
+	defer currentDatabaseMu.Unlock()
+	currentDatabase = database
+}
+
+func getDatabase() *db.DB {
+	currentDatabaseMu.RLock()
+	defer currentDatabaseMu.RUnlock()
+	return currentDatabase
+}
+
 // WorkflowConverter implements converter using YAML workflows
 type WorkflowConverter struct {
 	workflow *db.Workflow
@@ -65,8 +156,24 @@ func (c *WorkflowConverter) TargetFormat() string {
 	return "unknown"
 }
 
+// MatchPriority reports whether this workflow's inputs.match rules select
+// srcPath, and the priority to use when more than one converter matches the
+// same file. It implements converter.MatchPrioritizer.
+func (c *WorkflowConverter) MatchPriority(srcPath string, srcMime string) (bool, int) {
+	return c.spec.MatchPriority(srcPath, srcMime)
+}
+
 // CanConvert checks if this converter can handle the input file
 func (c *WorkflowConverter) CanConvert(srcPath string, srcMime string) bool {
+	// inputs.match further restricts (or, if can_convert is absent, wholly
+	// determines) which files this workflow applies to.
+	if c.spec.Inputs != nil && c.spec.Inputs.Match != nil {
+		matched, _ := c.spec.MatchPriority(srcPath, srcMime)
+		if !matched {
+			return false
+		}
+	}
+
 	// Create a temporary execution context for checking
 	tmpDir, err := os.MkdirTemp("", "workflow-check-*")
 	if err != nil {
@@ -95,8 +202,20 @@ func (c *WorkflowConverter) CanConvert(srcPath string, srcMime string) bool {
 	return canConvert
 }
 
-// Convert performs the conversion
+// Convert performs the conversion. If a workflow result cache is wired up
+// (see SetWorkflowCache) and opts carries the source file's content hash,
+// a cache hit copies the previously produced output straight to dstPath
+// and returns, skipping execution of the workflow entirely.
 func (c *WorkflowConverter) Convert(ctx context.Context, srcPath, dstPath string, opts ConvertOptions) (MetaResult, error) {
+	var cacheKey string
+	cache := getWorkflowCache()
+	if cache != nil && opts.ContentHash != "" {
+		cacheKey = workflowcache.Key(opts.ContentHash, opts.HashAlgorithm, c.workflow.YAML, opts.Quality)
+		if metaResult, ok := c.tryCacheHit(cache, cacheKey, srcPath, dstPath, opts); ok {
+			return metaResult, nil
+		}
+	}
+
 	// Create temporary directory
 	tmpDir, err := os.MkdirTemp(opts.TempDir, "workflow-*")
 	if err != nil {
@@ -113,6 +232,9 @@ func (c *WorkflowConverter) Convert(ctx context.Context, srcPath, dstPath string
 		Quality:      opts.Quality,
 		Variables:    make(map[string]string),
 	}
+	if store := getBlobStore(); store != nil {
+		execCtx.BlobDir = store.Dir
+	}
 
 	// Add any custom variables from opts (future extension)
 	// For now, standard variables are auto-populated
@@ -131,19 +253,63 @@ func (c *WorkflowConverter) Convert(ctx context.Context, srcPath, dstPath string
 		return MetaResult{}, fmt.Errorf("failed to create workflow run: %w", err)
 	}
 
-	// Execute workflow
+	// Execute workflow, publishing start/step-boundary/end events to
+	// livelog.Manager so a client following srcPath via the live-log SSE
+	// endpoint sees this run's progress as it happens rather than only once
+	// it's written to WorkflowRun on completion.
+	livelog.GetManager().StartTask(srcPath)
+	defer livelog.GetManager().EndTask(srcPath)
+
+	runEvents := WorkflowRunEvents(run.ID)
+	runEvents.Publish(progress.JobEvent{
+		Type:      progress.JobEventStateChange,
+		State:     "running",
+		Timestamp: time.Now(),
+	})
+	execCtx.StepProgress = func(ev workflow.StepProgressEvent) {
+		message := ev.StepName
+		if ev.Phase != "start" {
+			message = fmt.Sprintf("%s (stdout=%dB stderr=%dB in %s)", ev.StepName, ev.StdoutBytes, ev.StderrBytes, ev.Duration.Round(time.Millisecond))
+		}
+		runEvents.Publish(progress.JobEvent{
+			Type:      progress.JobEventProgress,
+			Processed: ev.StepIndex + 1,
+			Total:     ev.TotalSteps,
+			Percent:   stepPercent(ev.StepIndex, ev.TotalSteps, run.StartTime, c.spec.Timeout),
+			Message:   message,
+			State:     ev.Phase,
+			Timestamp: time.Now(),
+		})
+	}
+
 	executor := workflow.NewExecutor(c.spec, ctx, execCtx)
 	result, execErr := executor.Execute()
 
+	if execErr == nil && len(result.OutputManifest.Chunks) > 0 {
+		if err := c.recordOutputManifest(srcPath, result.OutputManifest); err != nil {
+			fmt.Printf("Warning: failed to record output manifest: %v\n", err)
+		}
+	}
+
+	if execErr == nil && len(result.StepDeps) > 0 {
+		if err := c.recordStepDeps(run.ID, result.StepDeps); err != nil {
+			fmt.Printf("Warning: failed to record step dependency info: %v\n", err)
+		}
+	}
+
 	// Update run record
 	endTime := time.Now()
 	run.EndTime = &endTime
 	run.DurationMs = result.Duration.Milliseconds()
-	run.Stdout = combineStepOutputs(result.StepResults, true)
-	run.Stderr = combineStepOutputs(result.StepResults, false)
+	run.Stdout = CombineStepOutputs(result.StepResults, true)
+	run.Stderr = CombineStepOutputs(result.StepResults, false)
 	run.Logs = result.Logs
 	run.MetadataPreserved = result.MetadataPreserved
 	run.MetadataSummary = result.MetadataSummary
+	run.Summary = result.Summary
+	if annotationsJSON, err := json.Marshal(result.Annotations); err == nil {
+		run.Annotations = string(annotationsJSON)
+	}
 
 	if execErr != nil {
 		run.Status = "failed"
@@ -160,11 +326,37 @@ func (c *WorkflowConverter) Convert(ctx context.Context, srcPath, dstPath string
 		fmt.Printf("Warning: failed to update workflow run: %v\n", err)
 	}
 
+	metrics.RecordWorkflowRun(c.workflow.Name, run.Status)
+	for _, step := range result.StepResults {
+		metrics.WorkflowStepDuration.Observe(step.Duration.Seconds(), c.workflow.Name, step.StepName)
+	}
+	conversionResult := "failed"
+	if run.Status == "success" {
+		conversionResult = metrics.ResultSuccess
+	}
+	metrics.RecordConversion(c.Name(), conversionResult, result.Duration, result.MetadataPreserved)
+
+	runEvents.Publish(progress.JobEvent{
+		Type:      progress.JobEventStateChange,
+		State:     run.Status,
+		Percent:   100,
+		Timestamp: time.Now(),
+	})
+
 	// Return result
 	metaResult := MetaResult{
 		MetadataPreserved: result.MetadataPreserved,
 		MetadataSummary:   result.MetadataSummary,
 		ConversionLog:     result.Logs,
+		Annotations:       run.Annotations,
+	}
+
+	if execErr == nil && cache != nil && cacheKey != "" {
+		if metaJSON, err := json.Marshal(metaResult); err == nil {
+			if _, err := cache.Store(cacheKey, dstPath, string(metaJSON)); err != nil {
+				fmt.Printf("Warning: failed to store workflow cache entry: %v\n", err)
+			}
+		}
 	}
 
 	if execErr != nil {
@@ -174,8 +366,140 @@ func (c *WorkflowConverter) Convert(ctx context.Context, srcPath, dstPath string
 	return metaResult, nil
 }
 
-// combineStepOutputs combines stdout or stderr from all steps
-func combineStepOutputs(steps []workflow.StepResult, stdout bool) string {
+// recordOutputManifest persists manifest, the blobstore.Manifest the
+// primary output was split into, against srcPath's FileIndex row and
+// increments every chunk's refcount, including ones the blob store already
+// had on disk (that's what makes deduplication visible in GetBlobStats).
+func (c *WorkflowConverter) recordOutputManifest(srcPath string, manifest blobstore.Manifest) error {
+	for _, chunk := range manifest.Chunks {
+		if err := c.database.UpsertBlob(chunk.SHA256, chunk.Size); err != nil {
+			return fmt.Errorf("failed to record chunk %s: %w", chunk.SHA256, err)
+		}
+	}
+
+	manifestJSON, err := manifest.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode output manifest: %w", err)
+	}
+
+	return c.database.UpdateFileIndexManifest(srcPath, manifestJSON)
+}
+
+// recordStepDeps indexes deps, the workflow.StepDepRecords Execute wrote to
+// the run's "<OutputFile>.rec" sidecar, into db.StepDep against runID, so
+// they can be queried by workflow run without reparsing that file.
+func (c *WorkflowConverter) recordStepDeps(runID int64, deps []workflow.StepDepRecord) error {
+	for _, dep := range deps {
+		envJSON, err := json.Marshal(dep.Env)
+		if err != nil {
+			return fmt.Errorf("failed to encode step env for %q: %w", dep.StepName, err)
+		}
+		inputHashesJSON, err := json.Marshal(dep.InputHashes)
+		if err != nil {
+			return fmt.Errorf("failed to encode input hashes for %q: %w", dep.StepName, err)
+		}
+		createdHashesJSON, err := json.Marshal(dep.CreatedHashes)
+		if err != nil {
+			return fmt.Errorf("failed to encode created hashes for %q: %w", dep.StepName, err)
+		}
+
+		row := db.StepDep{
+			WorkflowRunID: runID,
+			StepName:      dep.StepName,
+			Command:       dep.Command,
+			Workdir:       dep.Workdir,
+			Env:           string(envJSON),
+			ExitCode:      dep.ExitCode,
+			StartTime:     dep.StartTime,
+			EndTime:       dep.EndTime,
+			InputHashes:   string(inputHashesJSON),
+			CreatedHashes: string(createdHashesJSON),
+		}
+		if err := c.database.InsertStepDep(runID, row); err != nil {
+			return fmt.Errorf("failed to insert step dep for %q: %w", dep.StepName, err)
+		}
+	}
+	return nil
+}
+
+// tryCacheHit copies a cached workflow output to dstPath and records a
+// WorkflowRun for it, so the result cache is transparent to anything
+// reading run history. It reports false (doing nothing) on a cache miss,
+// or if the cached file can no longer be copied or its stored MetaResult
+// can no longer be decoded, so the caller falls back to a normal run.
+func (c *WorkflowConverter) tryCacheHit(cache *workflowcache.Cache, cacheKey, srcPath, dstPath string, opts ConvertOptions) (MetaResult, bool) {
+	cachedPath, metaJSON, ok := cache.Lookup(cacheKey)
+	if !ok {
+		return MetaResult{}, false
+	}
+
+	var metaResult MetaResult
+	if err := json.Unmarshal([]byte(metaJSON), &metaResult); err != nil {
+		return MetaResult{}, false
+	}
+
+	if err := copyFile(cachedPath, dstPath); err != nil {
+		return MetaResult{}, false
+	}
+
+	now := time.Now()
+	exitCode := 0
+	run := &db.WorkflowRun{
+		WorkflowID:        c.workflow.ID,
+		WorkflowName:      c.workflow.Name,
+		FilePath:          srcPath,
+		Status:            "success",
+		StartTime:         now,
+		EndTime:           &now,
+		ExitCode:          &exitCode,
+		Logs:              fmt.Sprintf("Reused cached output for workflow result cache key %s", cacheKey),
+		MetadataPreserved: metaResult.MetadataPreserved,
+		MetadataSummary:   metaResult.MetadataSummary,
+		JobParams:         fmt.Sprintf(`{"quality": %d}`, opts.Quality),
+	}
+	if err := c.database.CreateWorkflowRun(run); err != nil {
+		fmt.Printf("Warning: failed to record cached workflow run: %v\n", err)
+	}
+
+	metrics.RecordWorkflowRun(c.workflow.Name, run.Status)
+	metrics.RecordConversion(c.Name(), metrics.ResultSuccess, 0, metaResult.MetadataPreserved)
+
+	return metaResult, true
+}
+
+// stepPercent estimates how far through a run a step boundary represents,
+// as the larger of step-index progress (stepIndex/totalSteps) and elapsed
+// time against the workflow's configured timeout (in seconds; 0 means no
+// timeout, so only step-index progress applies). Capped at 99 so the run's
+// final state-change event ("success" or "failed") is the only one that
+// reports a full 100.
+func stepPercent(stepIndex, totalSteps int, startTime time.Time, timeoutSeconds int) float64 {
+	var byStep float64
+	if totalSteps > 0 {
+		byStep = float64(stepIndex+1) / float64(totalSteps) * 100
+	}
+
+	byTime := byStep
+	if timeoutSeconds > 0 {
+		elapsed := time.Since(startTime).Seconds()
+		byTime = elapsed / float64(timeoutSeconds) * 100
+	}
+
+	percent := byStep
+	if byTime > percent {
+		percent = byTime
+	}
+	if percent > 99 {
+		percent = 99
+	}
+	return percent
+}
+
+// CombineStepOutputs combines stdout or stderr from all steps. Exported so
+// the API-triggered workflow execution path (internal/api's
+// handleWorkflowRunExecute, which runs independently of WorkflowConverter)
+// can reuse it instead of keeping its own copy.
+func CombineStepOutputs(steps []workflow.StepResult, stdout bool) string {
 	var builder strings.Builder
 	for _, step := range steps {
 		if stdout && step.Stdout != "" {
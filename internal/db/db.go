@@ -1,10 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/ah-its-andy/jpeg2heif/internal/db/migrations"
 	_ "modernc.org/sqlite"
 )
 
@@ -13,7 +17,8 @@ type DB struct {
 	conn *sql.DB
 }
 
-// New creates a new database connection
+// New creates a new database connection and brings its schema up to date by
+// applying any pending entries from db/migrations.
 func New(dbPath string) (*DB, error) {
 	conn, err := sql.Open("sqlite", dbPath)
 	if err != nil {
@@ -25,12 +30,20 @@ func New(dbPath string) (*DB, error) {
 	conn.SetMaxIdleConns(1)
 	conn.SetConnMaxLifetime(time.Hour)
 
+	if _, err := conn.Exec(`PRAGMA foreign_keys = ON`); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+	if _, err := conn.Exec(`PRAGMA journal_mode = WAL`); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable WAL journal mode: %w", err)
+	}
+
 	db := &DB{conn: conn}
 
-	// Initialize schema
-	if err := db.initSchema(); err != nil {
+	if err := db.Migrate(context.Background()); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
 
 	return db, nil
@@ -41,100 +54,115 @@ func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
-// initSchema creates the database schema
-func (db *DB) initSchema() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS files_index (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		file_path TEXT NOT NULL UNIQUE,
-		file_md5 TEXT NOT NULL,
-		status TEXT NOT NULL,
-		converter_name TEXT,
-		metadata_preserved BOOLEAN DEFAULT 0,
-		metadata_summary TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_file_path ON files_index(file_path);
-	CREATE INDEX IF NOT EXISTS idx_file_md5 ON files_index(file_md5);
-	CREATE INDEX IF NOT EXISTS idx_status ON files_index(status);
-	
-	CREATE TABLE IF NOT EXISTS tasks_history (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		file_path TEXT NOT NULL,
-		converter_name TEXT,
-		status TEXT NOT NULL,
-		error_message TEXT,
-		console_output TEXT,
-		duration_ms INTEGER,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_task_created ON tasks_history(created_at DESC);
-	
-	CREATE TABLE IF NOT EXISTS workflows (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL UNIQUE,
-		description TEXT,
-		yaml TEXT NOT NULL,
-		enabled BOOLEAN DEFAULT 1,
-		created_by TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_workflow_name ON workflows(name);
-	CREATE INDEX IF NOT EXISTS idx_workflow_enabled ON workflows(enabled);
-	
-	CREATE TABLE IF NOT EXISTS workflow_runs (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		workflow_id INTEGER NOT NULL,
-		workflow_name TEXT NOT NULL,
-		file_index_id INTEGER,
-		file_path TEXT NOT NULL,
-		status TEXT NOT NULL,
-		start_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		end_time TIMESTAMP,
-		duration_ms INTEGER,
-		exit_code INTEGER,
-		stdout TEXT,
-		stderr TEXT,
-		logs TEXT,
-		metadata_preserved BOOLEAN DEFAULT 0,
-		metadata_summary TEXT,
-		job_params TEXT,
-		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE,
-		FOREIGN KEY (file_index_id) REFERENCES files_index(id) ON DELETE SET NULL
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_workflow_run_workflow ON workflow_runs(workflow_id);
-	CREATE INDEX IF NOT EXISTS idx_workflow_run_status ON workflow_runs(status);
-	CREATE INDEX IF NOT EXISTS idx_workflow_run_start ON workflow_runs(start_time DESC);
-	
-	CREATE TABLE IF NOT EXISTS workflows_versions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		workflow_id INTEGER NOT NULL,
-		yaml TEXT NOT NULL,
-		edited_by TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_workflow_version ON workflows_versions(workflow_id, created_at DESC);
-	`
+// Migrate applies any pending entries from db/migrations that haven't yet
+// been recorded in schema_migrations, in ascending Version order. Each
+// migration runs inside its own transaction, so a failure partway through a
+// run leaves earlier migrations committed and recorded, and the failed one
+// neither committed nor recorded, ready to retry on the next call.
+func (db *DB) Migrate(ctx context.Context) error {
+	if _, err := db.conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
 
-	_, err := db.conn.Exec(schema)
-	return err
+	applied := make(map[int]bool)
+	rows, err := db.conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for _, m := range migrations.All {
+		if applied[m.Version] {
+			continue
+		}
+
+		tx, err := db.conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): failed to begin transaction: %w", m.Version, m.Name, err)
+		}
+
+		if err := m.Up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s): failed to record as applied: %w", m.Version, m.Name, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d (%s): failed to commit: %w", m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// MigrationStatus reports which of db/migrations' entries have already run
+// against this database and which are still pending, e.g. for a
+// --migrate-only startup to print before exiting.
+func (db *DB) MigrationStatus() ([]AppliedMigration, []PendingMigration, error) {
+	rows, err := db.conn.Query(`SELECT version, name, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var applied []AppliedMigration
+	seen := make(map[int]bool)
+	for rows.Next() {
+		var a AppliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.AppliedAt); err != nil {
+			return nil, nil, err
+		}
+		applied = append(applied, a)
+		seen[a.Version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var pending []PendingMigration
+	for _, m := range migrations.All {
+		if !seen[m.Version] {
+			pending = append(pending, PendingMigration{Version: m.Version, Name: m.Name})
+		}
+	}
+
+	return applied, pending, nil
 }
 
 // UpsertFileIndex inserts or updates a file index entry
 func (db *DB) UpsertFileIndex(file *FileIndex) error {
+	hashAlgo := file.HashAlgo
+	if hashAlgo == "" {
+		hashAlgo = "md5"
+	}
+
 	query := `
-	INSERT INTO files_index (file_path, file_md5, status, converter_name, metadata_preserved, metadata_summary, updated_at)
-	VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	INSERT INTO files_index (file_path, file_md5, hash_algo, status, converter_name, metadata_preserved, metadata_summary, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 	ON CONFLICT(file_path) DO UPDATE SET
 		file_md5 = excluded.file_md5,
+		hash_algo = excluded.hash_algo,
 		status = excluded.status,
 		converter_name = excluded.converter_name,
 		metadata_preserved = excluded.metadata_preserved,
@@ -145,6 +173,7 @@ func (db *DB) UpsertFileIndex(file *FileIndex) error {
 	result, err := db.conn.Exec(query,
 		file.FilePath,
 		file.FileMD5,
+		hashAlgo,
 		file.Status,
 		file.ConverterName,
 		file.MetadataPreserved,
@@ -162,12 +191,23 @@ func (db *DB) UpsertFileIndex(file *FileIndex) error {
 		}
 	}
 
+	file.CacheHit = false
+	if file.OptionsHash != "" && file.FileMD5 != "" {
+		artifact, err := db.LookupArtifact(file.FileMD5, file.ConverterName, file.OptionsHash)
+		if err != nil {
+			return err
+		}
+		if artifact != nil {
+			file.CacheHit = true
+		}
+	}
+
 	return nil
 }
 
 // GetFileIndex retrieves a file index entry by path
 func (db *DB) GetFileIndex(filePath string) (*FileIndex, error) {
-	query := `SELECT id, file_path, file_md5, status, converter_name, metadata_preserved, metadata_summary, created_at, updated_at
+	query := `SELECT id, file_path, file_md5, hash_algo, status, converter_name, metadata_preserved, metadata_summary, created_at, updated_at
 	          FROM files_index WHERE file_path = ?`
 
 	file := &FileIndex{}
@@ -175,6 +215,7 @@ func (db *DB) GetFileIndex(filePath string) (*FileIndex, error) {
 		&file.ID,
 		&file.FilePath,
 		&file.FileMD5,
+		&file.HashAlgo,
 		&file.Status,
 		&file.ConverterName,
 		&file.MetadataPreserved,
@@ -192,7 +233,7 @@ func (db *DB) GetFileIndex(filePath string) (*FileIndex, error) {
 
 // ListFiles lists file index entries with pagination and filtering
 func (db *DB) ListFiles(status string, limit, offset int) ([]*FileIndex, error) {
-	query := `SELECT id, file_path, file_md5, status, converter_name, metadata_preserved, metadata_summary, created_at, updated_at
+	query := `SELECT id, file_path, file_md5, hash_algo, status, converter_name, metadata_preserved, metadata_summary, created_at, updated_at
 	          FROM files_index`
 
 	args := []interface{}{}
@@ -217,6 +258,7 @@ func (db *DB) ListFiles(status string, limit, offset int) ([]*FileIndex, error)
 			&file.ID,
 			&file.FilePath,
 			&file.FileMD5,
+			&file.HashAlgo,
 			&file.Status,
 			&file.ConverterName,
 			&file.MetadataPreserved,
@@ -233,10 +275,47 @@ func (db *DB) ListFiles(status string, limit, offset int) ([]*FileIndex, error)
 	return files, rows.Err()
 }
 
+// ResetStaleProcessing resets every files_index row left in status
+// "processing" back to "pending" and returns their file paths, so a caller
+// starting up after an unclean shutdown (a crash, or a SIGTERM that fired
+// mid-conversion and outran the worker pool's drain grace period) can
+// re-enqueue them instead of leaving them stuck in "processing" forever.
+func (db *DB) ResetStaleProcessing() ([]string, error) {
+	rows, err := db.conn.Query(`SELECT file_path FROM files_index WHERE status = 'processing'`)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	if _, err := db.conn.Exec(`UPDATE files_index SET status = 'pending', updated_at = CURRENT_TIMESTAMP WHERE status = 'processing'`); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
 // InsertTaskHistory adds a task history entry
 func (db *DB) InsertTaskHistory(task *TaskHistory) error {
-	query := `INSERT INTO tasks_history (file_path, converter_name, status, error_message, console_output, duration_ms)
-	          VALUES (?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO tasks_history (file_path, converter_name, status, error_message, console_output, duration_ms, annotations)
+	          VALUES (?, ?, ?, ?, ?, ?, ?)`
 
 	result, err := db.conn.Exec(query,
 		task.FilePath,
@@ -245,6 +324,7 @@ func (db *DB) InsertTaskHistory(task *TaskHistory) error {
 		task.ErrorMessage,
 		task.ConsoleOutput,
 		task.DurationMs,
+		task.Annotations,
 	)
 
 	if err != nil {
@@ -261,7 +341,7 @@ func (db *DB) InsertTaskHistory(task *TaskHistory) error {
 
 // ListTasks lists task history entries with pagination
 func (db *DB) ListTasks(limit, offset int) ([]*TaskHistory, error) {
-	query := `SELECT id, file_path, converter_name, status, error_message, duration_ms, created_at, console_output
+	query := `SELECT id, file_path, converter_name, status, error_message, duration_ms, created_at, console_output, annotations
 	          FROM tasks_history
 	          ORDER BY created_at DESC
 	          LIMIT ? OFFSET ?`
@@ -284,6 +364,7 @@ func (db *DB) ListTasks(limit, offset int) ([]*TaskHistory, error) {
 			&task.DurationMs,
 			&task.CreatedAt,
 			&task.ConsoleOutput,
+			&task.Annotations,
 		)
 		if err != nil {
 			return nil, err
@@ -296,7 +377,7 @@ func (db *DB) ListTasks(limit, offset int) ([]*TaskHistory, error) {
 
 // GetTaskByID retrieves a single task by ID
 func (db *DB) GetTaskByID(id int64) (*TaskHistory, error) {
-	query := `SELECT id, file_path, converter_name, status, error_message, duration_ms, created_at, console_output
+	query := `SELECT id, file_path, converter_name, status, error_message, duration_ms, created_at, console_output, annotations
 	          FROM tasks_history
 	          WHERE id = ?`
 
@@ -310,6 +391,7 @@ func (db *DB) GetTaskByID(id int64) (*TaskHistory, error) {
 		&task.DurationMs,
 		&task.CreatedAt,
 		&task.ConsoleOutput,
+		&task.Annotations,
 	)
 	if err != nil {
 		return nil, err
@@ -323,33 +405,263 @@ func (db *DB) GetStats() (*Stats, error) {
 	stats := &Stats{}
 
 	query := `
-	SELECT 
+	SELECT
 		COUNT(*) as total,
-		SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END) as success,
-		SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) as failed,
-		SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END) as pending,
-		SUM(CASE WHEN status = 'processing' THEN 1 ELSE 0 END) as processing
+		COALESCE(SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END), 0) as success,
+		COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0) as failed,
+		COALESCE(SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END), 0) as pending,
+		COALESCE(SUM(CASE WHEN status = 'processing' THEN 1 ELSE 0 END), 0) as processing
 	FROM files_index
 	`
 
-	err := db.conn.QueryRow(query).Scan(
+	if err := db.conn.QueryRow(query).Scan(
 		&stats.TotalFiles,
 		&stats.SuccessCount,
 		&stats.FailedCount,
 		&stats.PendingCount,
 		&stats.ProcessingCount,
-	)
+	); err != nil {
+		return stats, err
+	}
 
-	return stats, err
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM cluster_peer_hits`).Scan(&stats.PeerHitCount); err != nil {
+		return stats, err
+	}
+
+	var totalTags, preservedTags int64
+	if err := db.conn.QueryRow(`
+	SELECT COUNT(*), COALESCE(SUM(CASE WHEN preserved = 1 THEN 1 ELSE 0 END), 0)
+	FROM file_metadata
+	`).Scan(&totalTags, &preservedTags); err != nil {
+		return stats, err
+	}
+	if totalTags > 0 {
+		stats.MetadataPreserveRate = float64(preservedTags) / float64(totalTags)
+	}
+
+	return stats, nil
 }
 
-// DeleteFileIndex deletes a file index entry
+// DeleteFileIndex deletes a file index entry, first decrementing the
+// refcount of every blob chunk its output_manifest referenced (see
+// internal/blobstore), so a chunk only this file used stops being kept
+// around once nothing references it.
 func (db *DB) DeleteFileIndex(filePath string) error {
-	query := `DELETE FROM files_index WHERE file_path = ?`
-	_, err := db.conn.Exec(query, filePath)
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var manifestJSON string
+	err = tx.QueryRow(`SELECT output_manifest FROM files_index WHERE file_path = ?`, filePath).Scan(&manifestJSON)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if manifestJSON != "" {
+		chunkIDs, err := manifestChunkIDs(manifestJSON)
+		if err != nil {
+			return fmt.Errorf("failed to parse output manifest: %w", err)
+		}
+		if err := decrementBlobRefcounts(tx, chunkIDs); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM files_index WHERE file_path = ?`, filePath); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateFileIndexManifest records the blobstore.Manifest JSON describing
+// the content-defined chunks a file's converted output was split into, so
+// a later DeleteFileIndex of the same file_path can decrement their
+// refcounts.
+func (db *DB) UpdateFileIndexManifest(filePath, manifestJSON string) error {
+	_, err := db.conn.Exec(`UPDATE files_index SET output_manifest = ? WHERE file_path = ?`, manifestJSON, filePath)
+	return err
+}
+
+// manifestChunkIDs parses a files_index.output_manifest value (a
+// blobstore.Manifest JSON blob) just far enough to extract its chunk IDs;
+// it declares its own minimal shape rather than importing blobstore, the
+// same way JobParams is "JSON of variables used" without a shared struct.
+func manifestChunkIDs(manifestJSON string) ([]string, error) {
+	var manifest struct {
+		Chunks []struct {
+			SHA256 string `json:"sha256"`
+		} `json:"chunks"`
+	}
+	if err := json.Unmarshal([]byte(manifestJSON), &manifest); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(manifest.Chunks))
+	for i, c := range manifest.Chunks {
+		ids[i] = c.SHA256
+	}
+	return ids, nil
+}
+
+// decrementBlobRefcounts subtracts one reference from each chunk in
+// chunkIDs, deleting any blobs row whose refcount reaches zero. It's the
+// metadata half of blob GC; removing the now-unreferenced chunk's file from
+// disk is the caller's job (via blobstore.Store.BlobPath), since db doesn't
+// know a Store's root directory.
+func decrementBlobRefcounts(tx *sql.Tx, chunkIDs []string) error {
+	for _, id := range chunkIDs {
+		if _, err := tx.Exec(`UPDATE blobs SET ref_count = ref_count - 1 WHERE chunk_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM blobs WHERE chunk_id = ? AND ref_count <= 0`, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpsertBlob records that chunkID (a sha256 hex digest) now has one more
+// reference, inserting a fresh row with RefCount 1 if this is the first
+// file to reference it. Called once per ChunkRef a successful
+// blobstore.Store.Split returns, including chunks that already existed on
+// disk, since that's what makes deduplication visible in GetBlobStats.
+func (db *DB) UpsertBlob(chunkID string, size int64) error {
+	_, err := db.conn.Exec(`
+	INSERT INTO blobs (chunk_id, ref_count, size)
+	VALUES (?, 1, ?)
+	ON CONFLICT(chunk_id) DO UPDATE SET ref_count = ref_count + 1
+	`, chunkID, size)
+	return err
+}
+
+// GetBlob looks up a chunk's refcount and size. It returns (nil, nil) on a
+// miss.
+func (db *DB) GetBlob(chunkID string) (*Blob, error) {
+	blob := &Blob{}
+	err := db.conn.QueryRow(`SELECT chunk_id, ref_count, size, created_at FROM blobs WHERE chunk_id = ?`, chunkID).
+		Scan(&blob.ChunkID, &blob.RefCount, &blob.Size, &blob.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return blob, err
+}
+
+// GetBlobStats computes the blob store's current deduplication ratio
+// across every chunk still referenced (a blobs row is deleted once its
+// refcount reaches zero), for GET /api/stats.
+func (db *DB) GetBlobStats() (BlobStats, error) {
+	var stats BlobStats
+	err := db.conn.QueryRow(`
+	SELECT
+		COUNT(*),
+		COALESCE(SUM(size), 0),
+		COALESCE(SUM(size * ref_count), 0)
+	FROM blobs
+	`).Scan(&stats.ChunkCount, &stats.UniqueBytes, &stats.LogicalBytes)
+	return stats, err
+}
+
+// InsertStepDep records one step's dependency info (see workflow.StepDepRecord)
+// against workflowRunID, indexing the same data the run's "<OutputFile>.rec"
+// sidecar holds so it can be queried without reparsing that file.
+func (db *DB) InsertStepDep(workflowRunID int64, dep StepDep) error {
+	_, err := db.conn.Exec(`
+	INSERT INTO step_deps (workflow_run_id, step_name, command, workdir, env,
+	                        exit_code, start_time, end_time, input_hashes, created_hashes)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, workflowRunID, dep.StepName, dep.Command, dep.Workdir, dep.Env,
+		dep.ExitCode, dep.StartTime, dep.EndTime, dep.InputHashes, dep.CreatedHashes)
 	return err
 }
 
+// ListStepDeps returns every step_deps row recorded for workflowRunID, in
+// step order.
+func (db *DB) ListStepDeps(workflowRunID int64) ([]*StepDep, error) {
+	rows, err := db.conn.Query(`
+	SELECT id, workflow_run_id, step_name, command, workdir, env,
+	       exit_code, start_time, end_time, input_hashes, created_hashes, created_at
+	FROM step_deps WHERE workflow_run_id = ? ORDER BY id ASC
+	`, workflowRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []*StepDep
+	for rows.Next() {
+		dep := &StepDep{}
+		if err := rows.Scan(&dep.ID, &dep.WorkflowRunID, &dep.StepName, &dep.Command, &dep.Workdir,
+			&dep.Env, &dep.ExitCode, &dep.StartTime, &dep.EndTime, &dep.InputHashes, &dep.CreatedHashes,
+			&dep.CreatedAt); err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, rows.Err()
+}
+
+// ReplaceFileMetadata records fileIndexID's tag-by-tag metadata
+// preservation outcome (see FileMetadata), discarding whatever rows a
+// previous conversion of the same file left behind first: a rerun's tag
+// set can differ (e.g. PreserveMetadata was toggled, or a different
+// extractor backend found more tags), so accumulating rows across runs
+// would double-count in GetStats' weighted preserve rate.
+func (db *DB) ReplaceFileMetadata(fileIndexID int64, tags []FileMetadata) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM file_metadata WHERE file_index_id = ?`, fileIndexID); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+	INSERT INTO file_metadata (file_index_id, tag_name, source_value, output_value, preserved)
+	VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, tag := range tags {
+		if _, err := stmt.Exec(fileIndexID, tag.TagName, tag.SourceValue, tag.OutputValue, tag.Preserved); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListFileMetadata returns every file_metadata row recorded for
+// fileIndexID, ordered by tag name, for GET /api/files/:id/metadata.
+func (db *DB) ListFileMetadata(fileIndexID int64) ([]*FileMetadata, error) {
+	rows, err := db.conn.Query(`
+	SELECT id, file_index_id, tag_name, source_value, output_value, preserved, created_at
+	FROM file_metadata WHERE file_index_id = ? ORDER BY tag_name ASC
+	`, fileIndexID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*FileMetadata
+	for rows.Next() {
+		tag := &FileMetadata{}
+		if err := rows.Scan(&tag.ID, &tag.FileIndexID, &tag.TagName, &tag.SourceValue,
+			&tag.OutputValue, &tag.Preserved, &tag.CreatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
 // Workflow operations
 
 // CreateWorkflow inserts a new workflow
@@ -521,32 +833,77 @@ func (db *DB) CreateWorkflowRun(run *WorkflowRun) error {
 func (db *DB) UpdateWorkflowRun(run *WorkflowRun) error {
 	query := `UPDATE workflow_runs SET status = ?, end_time = ?, duration_ms = ?,
 	          exit_code = ?, stdout = ?, stderr = ?, logs = ?,
-	          metadata_preserved = ?, metadata_summary = ?
+	          metadata_preserved = ?, metadata_summary = ?, annotations = ?, summary = ?,
+	          pause_requested = ?, checkpoint = ?, paused_at = ?, resumed_at = ?
 	          WHERE id = ?`
 
 	_, err := db.conn.Exec(query, run.Status, run.EndTime, run.DurationMs,
 		run.ExitCode, run.Stdout, run.Stderr, run.Logs,
-		run.MetadataPreserved, run.MetadataSummary, run.ID)
+		run.MetadataPreserved, run.MetadataSummary, run.Annotations, run.Summary,
+		run.PauseRequested, run.Checkpoint, run.PausedAt, run.ResumedAt, run.ID)
 	return err
 }
 
+// PauseWorkflowRun requests that a running run pause at its next step
+// boundary. It only affects runs still in status 'running', so a pause
+// request racing a run that has already reached success/failed/cancelled
+// naturally is a silent no-op rather than resurrecting a finished run.
+func (db *DB) PauseWorkflowRun(runID int64) error {
+	result, err := db.conn.Exec(`UPDATE workflow_runs SET pause_requested = 1 WHERE id = ? AND status = 'running'`, runID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("workflow run %d is not running", runID)
+	}
+	return nil
+}
+
+// ResumeWorkflowRun transitions a run from 'paused' back to 'running' and
+// clears pause_requested, recording resumedAt. It is the caller's
+// responsibility to actually restart execution (see ExecutionContext.ResumeFromStep)
+// once this returns successfully.
+func (db *DB) ResumeWorkflowRun(runID int64, resumedAt time.Time) error {
+	result, err := db.conn.Exec(`UPDATE workflow_runs SET status = 'running', pause_requested = 0, resumed_at = ?
+	                             WHERE id = ? AND status = 'paused'`, resumedAt, runID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("workflow run %d is not paused", runID)
+	}
+	return nil
+}
+
 // GetWorkflowRun retrieves a workflow run by ID
 func (db *DB) GetWorkflowRun(id int64) (*WorkflowRun, error) {
 	query := `SELECT id, workflow_id, workflow_name, file_index_id, file_path, status,
 	          start_time, end_time, duration_ms, exit_code, stdout, stderr, logs,
-	          metadata_preserved, metadata_summary, job_params
+	          metadata_preserved, metadata_summary, job_params, annotations, summary,
+	          pause_requested, checkpoint, paused_at, resumed_at
 	          FROM workflow_runs WHERE id = ?`
 
 	run := &WorkflowRun{}
 	var endTime sql.NullTime
 	var exitCode sql.NullInt64
 	var fileIndexID sql.NullInt64
+	var pausedAt sql.NullTime
+	var resumedAt sql.NullTime
 
 	err := db.conn.QueryRow(query, id).Scan(
 		&run.ID, &run.WorkflowID, &run.WorkflowName, &fileIndexID, &run.FilePath,
 		&run.Status, &run.StartTime, &endTime, &run.DurationMs, &exitCode,
 		&run.Stdout, &run.Stderr, &run.Logs, &run.MetadataPreserved,
-		&run.MetadataSummary, &run.JobParams,
+		&run.MetadataSummary, &run.JobParams, &run.Annotations, &run.Summary,
+		&run.PauseRequested, &run.Checkpoint, &pausedAt, &resumedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -563,21 +920,30 @@ func (db *DB) GetWorkflowRun(id int64) (*WorkflowRun, error) {
 		fid := fileIndexID.Int64
 		run.FileIndexID = &fid
 	}
+	if pausedAt.Valid {
+		run.PausedAt = &pausedAt.Time
+	}
+	if resumedAt.Valid {
+		run.ResumedAt = &resumedAt.Time
+	}
 
 	return run, nil
 }
 
-// ListWorkflowRuns lists runs for a workflow
-func (db *DB) ListWorkflowRuns(workflowID int64, limit, offset int) ([]*WorkflowRun, error) {
+// ListWorkflowRuns lists runs for a workflow, optionally filtered to a
+// single status (e.g. "paused"); an empty status lists runs regardless of
+// status.
+func (db *DB) ListWorkflowRuns(workflowID int64, status string, limit, offset int) ([]*WorkflowRun, error) {
 	query := `SELECT id, workflow_id, workflow_name, file_index_id, file_path, status,
 	          start_time, end_time, duration_ms, exit_code, stdout, stderr, logs,
-	          metadata_preserved, metadata_summary, job_params
+	          metadata_preserved, metadata_summary, job_params, annotations, summary,
+	          pause_requested, checkpoint, paused_at, resumed_at
 	          FROM workflow_runs
-	          WHERE workflow_id = ?
+	          WHERE workflow_id = ? AND (? = '' OR status = ?)
 	          ORDER BY start_time DESC
 	          LIMIT ? OFFSET ?`
 
-	rows, err := db.conn.Query(query, workflowID, limit, offset)
+	rows, err := db.conn.Query(query, workflowID, status, status, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -586,16 +952,19 @@ func (db *DB) ListWorkflowRuns(workflowID int64, limit, offset int) ([]*Workflow
 	return db.scanWorkflowRuns(rows)
 }
 
-// ListAllWorkflowRuns lists all runs with pagination
-func (db *DB) ListAllWorkflowRuns(limit, offset int) ([]*WorkflowRun, error) {
+// ListAllWorkflowRuns lists all runs with pagination, optionally filtered to
+// a single status; an empty status lists runs regardless of status.
+func (db *DB) ListAllWorkflowRuns(status string, limit, offset int) ([]*WorkflowRun, error) {
 	query := `SELECT id, workflow_id, workflow_name, file_index_id, file_path, status,
 	          start_time, end_time, duration_ms, exit_code, stdout, stderr, logs,
-	          metadata_preserved, metadata_summary, job_params
+	          metadata_preserved, metadata_summary, job_params, annotations, summary,
+	          pause_requested, checkpoint, paused_at, resumed_at
 	          FROM workflow_runs
+	          WHERE (? = '' OR status = ?)
 	          ORDER BY start_time DESC
 	          LIMIT ? OFFSET ?`
 
-	rows, err := db.conn.Query(query, limit, offset)
+	rows, err := db.conn.Query(query, status, status, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -611,12 +980,15 @@ func (db *DB) scanWorkflowRuns(rows *sql.Rows) ([]*WorkflowRun, error) {
 		var endTime sql.NullTime
 		var exitCode sql.NullInt64
 		var fileIndexID sql.NullInt64
+		var pausedAt sql.NullTime
+		var resumedAt sql.NullTime
 
 		err := rows.Scan(
 			&run.ID, &run.WorkflowID, &run.WorkflowName, &fileIndexID, &run.FilePath,
 			&run.Status, &run.StartTime, &endTime, &run.DurationMs, &exitCode,
 			&run.Stdout, &run.Stderr, &run.Logs, &run.MetadataPreserved,
-			&run.MetadataSummary, &run.JobParams,
+			&run.MetadataSummary, &run.JobParams, &run.Annotations, &run.Summary,
+			&run.PauseRequested, &run.Checkpoint, &pausedAt, &resumedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -633,6 +1005,12 @@ func (db *DB) scanWorkflowRuns(rows *sql.Rows) ([]*WorkflowRun, error) {
 			fid := fileIndexID.Int64
 			run.FileIndexID = &fid
 		}
+		if pausedAt.Valid {
+			run.PausedAt = &pausedAt.Time
+		}
+		if resumedAt.Valid {
+			run.ResumedAt = &resumedAt.Time
+		}
 
 		runs = append(runs, run)
 	}
@@ -645,3 +1023,1143 @@ func (db *DB) ClearIndex() error {
 	_, err := db.conn.Exec(`DELETE FROM files_index`)
 	return err
 }
+
+// Visual cache operations
+
+// UpsertVisualCacheEntry inserts or refreshes a dedup cache entry for a
+// (visual digest, converter, quality) key.
+func (db *DB) UpsertVisualCacheEntry(entry *VisualCacheEntry) error {
+	query := `
+	INSERT INTO visual_cache (visual_digest, converter_name, quality, output_path, source_path)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(visual_digest, converter_name, quality) DO UPDATE SET
+		output_path = excluded.output_path,
+		source_path = excluded.source_path
+	`
+
+	result, err := db.conn.Exec(query,
+		entry.VisualDigest,
+		entry.Converter,
+		entry.Quality,
+		entry.OutputPath,
+		entry.SourcePath,
+	)
+	if err != nil {
+		return err
+	}
+
+	if entry.ID == 0 {
+		id, err := result.LastInsertId()
+		if err == nil {
+			entry.ID = id
+		}
+	}
+
+	return nil
+}
+
+// RegisterArtifact records a successful conversion's output under its
+// (source content hash, converter, options hash) key, so a future file with
+// identical bytes converted with the same settings can reuse it instead of
+// re-encoding. An existing entry for the same key is refreshed in place.
+func (db *DB) RegisterArtifact(artifact *ConvertedArtifact) error {
+	query := `
+	INSERT INTO converted_artifacts (file_md5, converter_name, options_hash, output_path, output_size, output_md5)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(file_md5, converter_name, options_hash) DO UPDATE SET
+		output_path = excluded.output_path,
+		output_size = excluded.output_size,
+		output_md5 = excluded.output_md5,
+		created_at = CURRENT_TIMESTAMP
+	`
+
+	result, err := db.conn.Exec(query,
+		artifact.FileMD5,
+		artifact.ConverterName,
+		artifact.OptionsHash,
+		artifact.OutputPath,
+		artifact.OutputSize,
+		artifact.OutputMD5,
+	)
+	if err != nil {
+		return err
+	}
+
+	if artifact.ID == 0 {
+		id, err := result.LastInsertId()
+		if err == nil {
+			artifact.ID = id
+		}
+	}
+
+	return nil
+}
+
+// LookupArtifact looks up a converted artifact by the source file's content
+// hash, the converter that would produce it, and the options hash of the
+// settings it would be produced with. It returns (nil, nil) on a miss.
+func (db *DB) LookupArtifact(md5, converterName, optionsHash string) (*ConvertedArtifact, error) {
+	query := `SELECT id, file_md5, converter_name, options_hash, output_path, output_size, output_md5, created_at
+	          FROM converted_artifacts WHERE file_md5 = ? AND converter_name = ? AND options_hash = ?`
+
+	artifact := &ConvertedArtifact{}
+	err := db.conn.QueryRow(query, md5, converterName, optionsHash).Scan(
+		&artifact.ID,
+		&artifact.FileMD5,
+		&artifact.ConverterName,
+		&artifact.OptionsHash,
+		&artifact.OutputPath,
+		&artifact.OutputSize,
+		&artifact.OutputMD5,
+		&artifact.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return artifact, nil
+}
+
+// GetVisualCacheEntry looks up a dedup cache entry by its digest, converter
+// and quality. It returns (nil, nil) on a miss.
+func (db *DB) GetVisualCacheEntry(visualDigest, converterName string, quality int) (*VisualCacheEntry, error) {
+	query := `SELECT id, visual_digest, converter_name, quality, output_path, source_path, created_at
+	          FROM visual_cache WHERE visual_digest = ? AND converter_name = ? AND quality = ?`
+
+	entry := &VisualCacheEntry{}
+	err := db.conn.QueryRow(query, visualDigest, converterName, quality).Scan(
+		&entry.ID,
+		&entry.VisualDigest,
+		&entry.Converter,
+		&entry.Quality,
+		&entry.OutputPath,
+		&entry.SourcePath,
+		&entry.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return entry, err
+}
+
+// ListVisualCacheEntries lists all dedup cache entries, newest first.
+func (db *DB) ListVisualCacheEntries() ([]*VisualCacheEntry, error) {
+	query := `SELECT id, visual_digest, converter_name, quality, output_path, source_path, created_at
+	          FROM visual_cache ORDER BY created_at DESC`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*VisualCacheEntry{}
+	for rows.Next() {
+		entry := &VisualCacheEntry{}
+		err := rows.Scan(
+			&entry.ID,
+			&entry.VisualDigest,
+			&entry.Converter,
+			&entry.Quality,
+			&entry.OutputPath,
+			&entry.SourcePath,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// DeleteVisualCacheEntry removes a dedup cache entry by ID.
+func (db *DB) DeleteVisualCacheEntry(id int64) error {
+	query := `DELETE FROM visual_cache WHERE id = ?`
+	_, err := db.conn.Exec(query, id)
+	return err
+}
+
+// Workflow result cache operations
+
+// UpsertWorkflowCacheEntry inserts or refreshes a workflow result cache
+// entry, resetting last_used_at to now.
+func (db *DB) UpsertWorkflowCacheEntry(entry *WorkflowCacheEntry) error {
+	query := `
+	INSERT INTO workflow_cache (key, output_path, meta_json, size_bytes, last_used_at)
+	VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(key) DO UPDATE SET
+		output_path = excluded.output_path,
+		meta_json = excluded.meta_json,
+		size_bytes = excluded.size_bytes,
+		last_used_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := db.conn.Exec(query,
+		entry.Key,
+		entry.OutputPath,
+		entry.MetaJSON,
+		entry.SizeBytes,
+	)
+	return err
+}
+
+// GetWorkflowCacheEntry looks up a workflow result cache entry by key. It
+// returns (nil, nil) on a miss.
+func (db *DB) GetWorkflowCacheEntry(key string) (*WorkflowCacheEntry, error) {
+	query := `SELECT key, output_path, meta_json, size_bytes, created_at, last_used_at
+	          FROM workflow_cache WHERE key = ?`
+
+	entry := &WorkflowCacheEntry{}
+	err := db.conn.QueryRow(query, key).Scan(
+		&entry.Key,
+		&entry.OutputPath,
+		&entry.MetaJSON,
+		&entry.SizeBytes,
+		&entry.CreatedAt,
+		&entry.LastUsedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return entry, err
+}
+
+// TouchWorkflowCacheEntry bumps a workflow result cache entry's
+// last_used_at to now, so it's evicted last under LRU pressure.
+func (db *DB) TouchWorkflowCacheEntry(key string) error {
+	_, err := db.conn.Exec(`UPDATE workflow_cache SET last_used_at = CURRENT_TIMESTAMP WHERE key = ?`, key)
+	return err
+}
+
+// WorkflowCacheTotalSize sums size_bytes across every cache entry.
+func (db *DB) WorkflowCacheTotalSize() (int64, error) {
+	var total sql.NullInt64
+	err := db.conn.QueryRow(`SELECT SUM(size_bytes) FROM workflow_cache`).Scan(&total)
+	return total.Int64, err
+}
+
+// ListWorkflowCacheEntriesLRU lists up to limit entries ordered by
+// last_used_at ascending (least recently used first), for eviction.
+func (db *DB) ListWorkflowCacheEntriesLRU(limit int) ([]*WorkflowCacheEntry, error) {
+	rows, err := db.conn.Query(`SELECT key, output_path, meta_json, size_bytes, created_at, last_used_at
+	                            FROM workflow_cache ORDER BY last_used_at ASC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*WorkflowCacheEntry{}
+	for rows.Next() {
+		entry := &WorkflowCacheEntry{}
+		if err := rows.Scan(
+			&entry.Key,
+			&entry.OutputPath,
+			&entry.MetaJSON,
+			&entry.SizeBytes,
+			&entry.CreatedAt,
+			&entry.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// DeleteWorkflowCacheEntry removes a workflow result cache entry by key.
+func (db *DB) DeleteWorkflowCacheEntry(key string) error {
+	_, err := db.conn.Exec(`DELETE FROM workflow_cache WHERE key = ?`, key)
+	return err
+}
+
+// CreateWebhook inserts a new webhook subscription.
+func (db *DB) CreateWebhook(wh *Webhook) error {
+	query := `
+	INSERT INTO webhooks (url, events, auth_header, auth_token, secret, enabled, max_attempts)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := db.conn.Exec(query, wh.URL, wh.Events, wh.AuthHeader, wh.AuthToken, wh.Secret, wh.Enabled, wh.MaxAttempts)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	wh.ID = id
+	return nil
+}
+
+// GetWebhook retrieves a webhook by ID.
+func (db *DB) GetWebhook(id int64) (*Webhook, error) {
+	query := `SELECT id, url, events, auth_header, auth_token, secret, enabled, max_attempts, created_at, updated_at
+	          FROM webhooks WHERE id = ?`
+
+	wh := &Webhook{}
+	err := db.conn.QueryRow(query, id).Scan(
+		&wh.ID, &wh.URL, &wh.Events, &wh.AuthHeader, &wh.AuthToken, &wh.Secret,
+		&wh.Enabled, &wh.MaxAttempts, &wh.CreatedAt, &wh.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return wh, nil
+}
+
+// ListWebhooks lists all webhook subscriptions.
+func (db *DB) ListWebhooks() ([]*Webhook, error) {
+	query := `SELECT id, url, events, auth_header, auth_token, secret, enabled, max_attempts, created_at, updated_at
+	          FROM webhooks ORDER BY created_at DESC`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		wh := &Webhook{}
+		if err := rows.Scan(
+			&wh.ID, &wh.URL, &wh.Events, &wh.AuthHeader, &wh.AuthToken, &wh.Secret,
+			&wh.Enabled, &wh.MaxAttempts, &wh.CreatedAt, &wh.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, wh)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// ListEnabledWebhooksForEvent lists enabled webhooks whose event mask
+// includes eventType. The mask is matched in Go (rather than with SQL LIKE)
+// since it's a small, comma-separated list rather than something worth a
+// join table for.
+func (db *DB) ListEnabledWebhooksForEvent(eventType string) ([]*Webhook, error) {
+	all, err := db.ListWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []*Webhook{}
+	for _, wh := range all {
+		if !wh.Enabled {
+			continue
+		}
+		for _, ev := range strings.Split(wh.Events, ",") {
+			if strings.TrimSpace(ev) == eventType {
+				matched = append(matched, wh)
+				break
+			}
+		}
+	}
+
+	return matched, nil
+}
+
+// UpdateWebhook updates an existing webhook's configuration.
+func (db *DB) UpdateWebhook(wh *Webhook) error {
+	query := `
+	UPDATE webhooks SET url = ?, events = ?, auth_header = ?, auth_token = ?, secret = ?,
+	       enabled = ?, max_attempts = ?, updated_at = CURRENT_TIMESTAMP
+	WHERE id = ?
+	`
+
+	_, err := db.conn.Exec(query, wh.URL, wh.Events, wh.AuthHeader, wh.AuthToken, wh.Secret,
+		wh.Enabled, wh.MaxAttempts, wh.ID)
+	return err
+}
+
+// DeleteWebhook removes a webhook and its queued/historical deliveries.
+func (db *DB) DeleteWebhook(id int64) error {
+	query := `DELETE FROM webhooks WHERE id = ?`
+	_, err := db.conn.Exec(query, id)
+	return err
+}
+
+// CreateWebhookDelivery queues a new delivery attempt for webhookID, due
+// immediately.
+func (db *DB) CreateWebhookDelivery(d *WebhookDelivery) error {
+	if d.Status == "" {
+		d.Status = "pending"
+	}
+	if d.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = time.Now()
+	}
+
+	query := `
+	INSERT INTO webhook_deliveries (webhook_id, event_type, payload, status, attempts, last_error, next_attempt_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := db.conn.Exec(query, d.WebhookID, d.EventType, d.Payload, d.Status, d.Attempts, d.LastError, d.NextAttemptAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	d.ID = id
+	return nil
+}
+
+// UpdateWebhookDelivery persists the outcome of a delivery attempt (status,
+// attempt count, last error, next retry time, and delivered_at once
+// successful).
+func (db *DB) UpdateWebhookDelivery(d *WebhookDelivery) error {
+	query := `
+	UPDATE webhook_deliveries SET status = ?, attempts = ?, last_error = ?,
+	       next_attempt_at = ?, delivered_at = ?
+	WHERE id = ?
+	`
+
+	_, err := db.conn.Exec(query, d.Status, d.Attempts, d.LastError, d.NextAttemptAt, d.DeliveredAt, d.ID)
+	return err
+}
+
+// ListDueWebhookDeliveries returns up to limit pending/retrying deliveries
+// whose next_attempt_at has passed, oldest first, for the dispatcher's poll
+// loop to pick up.
+func (db *DB) ListDueWebhookDeliveries(limit int) ([]*WebhookDelivery, error) {
+	query := `
+	SELECT id, webhook_id, event_type, payload, status, attempts, last_error, next_attempt_at, created_at, delivered_at
+	FROM webhook_deliveries
+	WHERE status IN ('pending', 'retrying') AND next_attempt_at <= CURRENT_TIMESTAMP
+	ORDER BY next_attempt_at ASC
+	LIMIT ?
+	`
+
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+// ListWebhookDeliveries lists the delivery history for webhookID, newest
+// first, for the /api/webhooks/{id}/deliveries inspection endpoint.
+func (db *DB) ListWebhookDeliveries(webhookID int64, limit, offset int) ([]*WebhookDelivery, error) {
+	query := `
+	SELECT id, webhook_id, event_type, payload, status, attempts, last_error, next_attempt_at, created_at, delivered_at
+	FROM webhook_deliveries
+	WHERE webhook_id = ?
+	ORDER BY created_at DESC
+	LIMIT ? OFFSET ?
+	`
+
+	rows, err := db.conn.Query(query, webhookID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+func scanWebhookDeliveries(rows *sql.Rows) ([]*WebhookDelivery, error) {
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		d := &WebhookDelivery{}
+		var lastError sql.NullString
+		var deliveredAt sql.NullTime
+
+		if err := rows.Scan(
+			&d.ID, &d.WebhookID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&lastError, &d.NextAttemptAt, &d.CreatedAt, &deliveredAt,
+		); err != nil {
+			return nil, err
+		}
+
+		d.LastError = lastError.String
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Cluster sync operations (see internal/cluster)
+
+// UpsertClusterOutput records or refreshes this node's own successful
+// conversion of (file_md5, hash_algo, converter_name), so it can be
+// gossiped to peers and served via the cluster lookup/fetch endpoints.
+func (db *DB) UpsertClusterOutput(o *ClusterOutput) error {
+	query := `
+	INSERT INTO cluster_outputs (file_path, file_md5, hash_algo, converter_name, target_path, updated_at)
+	VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(file_md5, hash_algo, converter_name) DO UPDATE SET
+		file_path = excluded.file_path,
+		target_path = excluded.target_path,
+		updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := db.conn.Exec(query, o.FilePath, o.FileMD5, o.HashAlgo, o.ConverterName, o.TargetPath)
+	return err
+}
+
+// GetClusterOutput looks up one of this node's own recorded conversions by
+// (file_md5, hash_algo, converter_name). It returns (nil, nil) on a miss.
+func (db *DB) GetClusterOutput(fileMD5, hashAlgo, converterName string) (*ClusterOutput, error) {
+	query := `SELECT id, file_path, file_md5, hash_algo, converter_name, target_path, updated_at
+	          FROM cluster_outputs WHERE file_md5 = ? AND hash_algo = ? AND converter_name = ?`
+
+	o := &ClusterOutput{}
+	err := db.conn.QueryRow(query, fileMD5, hashAlgo, converterName).Scan(
+		&o.ID, &o.FilePath, &o.FileMD5, &o.HashAlgo, &o.ConverterName, &o.TargetPath, &o.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return o, err
+}
+
+// ListRecentClusterOutputs lists up to limit of this node's own conversions,
+// most recently updated first, for the gossip push loop.
+func (db *DB) ListRecentClusterOutputs(limit int) ([]*ClusterOutput, error) {
+	query := `SELECT id, file_path, file_md5, hash_algo, converter_name, target_path, updated_at
+	          FROM cluster_outputs ORDER BY updated_at DESC LIMIT ?`
+
+	rows, err := db.conn.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	outputs := []*ClusterOutput{}
+	for rows.Next() {
+		o := &ClusterOutput{}
+		if err := rows.Scan(&o.ID, &o.FilePath, &o.FileMD5, &o.HashAlgo, &o.ConverterName, &o.TargetPath, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, o)
+	}
+
+	return outputs, rows.Err()
+}
+
+// ListClusterOutputsSince lists up to limit of this node's own conversions
+// updated strictly after since, oldest first, for a peer reconciling its
+// peer_index against ours via GET /api/cluster/index.
+func (db *DB) ListClusterOutputsSince(since time.Time, limit int) ([]*ClusterOutput, error) {
+	query := `SELECT id, file_path, file_md5, hash_algo, converter_name, target_path, updated_at
+	          FROM cluster_outputs WHERE updated_at > ? ORDER BY updated_at ASC LIMIT ?`
+
+	rows, err := db.conn.Query(query, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	outputs := []*ClusterOutput{}
+	for rows.Next() {
+		o := &ClusterOutput{}
+		if err := rows.Scan(&o.ID, &o.FilePath, &o.FileMD5, &o.HashAlgo, &o.ConverterName, &o.TargetPath, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, o)
+	}
+
+	return outputs, rows.Err()
+}
+
+// UpsertPeerIndexEntry stores or refreshes a converted-output record learned
+// from another cluster node, whether pushed via gossip or pulled during
+// reconciliation.
+func (db *DB) UpsertPeerIndexEntry(e *PeerIndexEntry) error {
+	query := `
+	INSERT INTO peer_index (peer_node, file_path, file_md5, hash_algo, status, converter_name, target_path, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(file_md5, hash_algo, converter_name) DO UPDATE SET
+		peer_node = excluded.peer_node,
+		file_path = excluded.file_path,
+		status = excluded.status,
+		target_path = excluded.target_path,
+		updated_at = excluded.updated_at
+	`
+	_, err := db.conn.Exec(query, e.PeerNode, e.FilePath, e.FileMD5, e.HashAlgo, e.Status, e.ConverterName, e.TargetPath, e.UpdatedAt)
+	return err
+}
+
+// GetPeerIndexEntry looks up a peer-learned conversion by (file_md5,
+// hash_algo, converter_name), consulted before running a local conversion.
+// It returns (nil, nil) on a miss.
+func (db *DB) GetPeerIndexEntry(fileMD5, hashAlgo, converterName string) (*PeerIndexEntry, error) {
+	query := `SELECT id, peer_node, file_path, file_md5, hash_algo, status, converter_name, target_path, updated_at
+	          FROM peer_index WHERE file_md5 = ? AND hash_algo = ? AND converter_name = ?`
+
+	e := &PeerIndexEntry{}
+	err := db.conn.QueryRow(query, fileMD5, hashAlgo, converterName).Scan(
+		&e.ID, &e.PeerNode, &e.FilePath, &e.FileMD5, &e.HashAlgo, &e.Status, &e.ConverterName, &e.TargetPath, &e.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// GetPeerWatermark returns the updated_at of the newest peer_index entry
+// reconciled from peerNode, or the zero time if nothing has been reconciled
+// from it yet.
+func (db *DB) GetPeerWatermark(peerNode string) (time.Time, error) {
+	var watermark time.Time
+	err := db.conn.QueryRow(`SELECT watermark FROM peer_watermarks WHERE peer_node = ?`, peerNode).Scan(&watermark)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return watermark, err
+}
+
+// SetPeerWatermark records the newest updated_at reconciled from peerNode,
+// so the next pull only asks for what changed since.
+func (db *DB) SetPeerWatermark(peerNode string, watermark time.Time) error {
+	query := `
+	INSERT INTO peer_watermarks (peer_node, watermark) VALUES (?, ?)
+	ON CONFLICT(peer_node) DO UPDATE SET watermark = excluded.watermark
+	`
+	_, err := db.conn.Exec(query, peerNode, watermark)
+	return err
+}
+
+// InsertClusterPeerHit records a job satisfied by fetching a peer's
+// already-converted output instead of running a local conversion, surfaced
+// via Stats.PeerHitCount.
+func (db *DB) InsertClusterPeerHit(fileMD5, converterName, peerNode string) error {
+	query := `INSERT INTO cluster_peer_hits (file_md5, converter_name, peer_node) VALUES (?, ?, ?)`
+	_, err := db.conn.Exec(query, fileMD5, converterName, peerNode)
+	return err
+}
+
+// TryAcquireLock attempts to claim key for owner, stealing it from whoever
+// held it if their lease has already expired. It returns (true, owner, nil)
+// on success. On contention it returns (false, currentOwner, nil) so the
+// caller (internal/lock.SQLiteLocker) can retry, or the API layer can
+// surface currentOwner in a 409 response.
+func (db *DB) TryAcquireLock(key, owner, converterName, filePath string, now time.Time, ttl time.Duration) (bool, string, error) {
+	expiresAt := now.Add(ttl)
+	query := `
+	INSERT INTO locks (key, owner, converter_name, file_path, acquired_at, expires_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(key) DO UPDATE SET
+		owner = excluded.owner,
+		converter_name = excluded.converter_name,
+		file_path = excluded.file_path,
+		acquired_at = excluded.acquired_at,
+		expires_at = excluded.expires_at
+	WHERE locks.expires_at < ?
+	`
+
+	result, err := db.conn.Exec(query, key, owner, converterName, filePath, now, expiresAt, now)
+	if err != nil {
+		return false, "", err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, "", err
+	}
+	if affected > 0 {
+		return true, owner, nil
+	}
+
+	lock, err := db.GetLock(key)
+	if err != nil {
+		return false, "", err
+	}
+	if lock == nil {
+		// Raced with a concurrent release between the failed UPDATE and this
+		// lookup; tell the caller to just retry.
+		return false, "", nil
+	}
+	return false, lock.Owner, nil
+}
+
+// RefreshLock extends key's lease to expiresAt, as long as owner is still
+// the current holder. It returns false if ownership was lost (the lease
+// expired and another owner stole it before this refresh), which the
+// caller must treat as a signal to abort whatever it was doing under the
+// lease.
+func (db *DB) RefreshLock(key, owner string, expiresAt time.Time) (bool, error) {
+	result, err := db.conn.Exec(`UPDATE locks SET expires_at = ? WHERE key = ? AND owner = ?`, expiresAt, key, owner)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ReleaseLock drops key, provided owner still holds it. Releasing a lease
+// that was already stolen by another owner is a no-op rather than an error,
+// since that's the expected outcome of a lost lease.
+func (db *DB) ReleaseLock(key, owner string) error {
+	_, err := db.conn.Exec(`DELETE FROM locks WHERE key = ? AND owner = ?`, key, owner)
+	return err
+}
+
+// GetLock looks up a lock by key, regardless of whether its lease has
+// expired. It returns (nil, nil) on a miss.
+func (db *DB) GetLock(key string) (*Lock, error) {
+	query := `SELECT key, owner, converter_name, file_path, acquired_at, expires_at FROM locks WHERE key = ?`
+
+	l := &Lock{}
+	err := db.conn.QueryRow(query, key).Scan(&l.Key, &l.Owner, &l.ConverterName, &l.FilePath, &l.AcquiredAt, &l.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, err
+}
+
+// FindActiveLockForConverter returns the first unexpired lock held against
+// converterName, if any, so handleConverterUpdate can refuse to toggle a
+// converter's Enabled flag out from under an in-flight conversion. It
+// returns (nil, nil) if no unexpired lock exists for that converter.
+func (db *DB) FindActiveLockForConverter(converterName string, now time.Time) (*Lock, error) {
+	query := `SELECT key, owner, converter_name, file_path, acquired_at, expires_at
+	          FROM locks WHERE converter_name = ? AND expires_at > ? LIMIT 1`
+
+	l := &Lock{}
+	err := db.conn.QueryRow(query, converterName, now).Scan(&l.Key, &l.Owner, &l.ConverterName, &l.FilePath, &l.AcquiredAt, &l.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, err
+}
+
+// SearchTasks full-text searches tasks_history.console_output via
+// tasks_history_fts, most relevant match first (lowest bm25 rank).
+func (db *DB) SearchTasks(query string, limit, offset int) ([]*TaskHistorySearchHit, error) {
+	sqlQuery := `SELECT t.id, t.file_path, t.converter_name, t.status, t.error_message,
+	             t.duration_ms, t.created_at, t.console_output,
+	             snippet(tasks_history_fts, 0, '<b>', '</b>', '...', 32),
+	             bm25(tasks_history_fts)
+	             FROM tasks_history_fts
+	             JOIN tasks_history t ON t.id = tasks_history_fts.rowid
+	             WHERE tasks_history_fts MATCH ?
+	             ORDER BY bm25(tasks_history_fts)
+	             LIMIT ? OFFSET ?`
+
+	rows, err := db.conn.Query(sqlQuery, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []*TaskHistorySearchHit{}
+	for rows.Next() {
+		task := &TaskHistory{}
+		hit := &TaskHistorySearchHit{Task: task}
+		if err := rows.Scan(
+			&task.ID, &task.FilePath, &task.ConverterName, &task.Status, &task.ErrorMessage,
+			&task.DurationMs, &task.CreatedAt, &task.ConsoleOutput,
+			&hit.Snippet, &hit.Rank,
+		); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
+
+// SearchWorkflowRuns full-text searches workflow_runs.stdout/stderr/logs via
+// workflow_runs_fts, most relevant match first (lowest bm25 rank). filters
+// narrows the match using workflow_runs_fts's UNINDEXED workflow_id/status/
+// start_time columns, so the narrowing is pushed into the same query
+// instead of post-filtered in Go; a zero-value field in filters imposes no
+// restriction on that dimension.
+func (db *DB) SearchWorkflowRuns(query string, limit, offset int, filters SearchFilters) ([]*WorkflowRunSearchHit, error) {
+	sqlQuery := `SELECT r.id, r.workflow_id, r.workflow_name, r.file_index_id, r.file_path, r.status,
+	             r.start_time, r.end_time, r.duration_ms, r.exit_code, r.stdout, r.stderr, r.logs,
+	             r.metadata_preserved, r.metadata_summary, r.job_params, r.annotations, r.summary,
+	             r.pause_requested, r.checkpoint, r.paused_at, r.resumed_at,
+	             snippet(workflow_runs_fts, -1, '<b>', '</b>', '...', 32),
+	             bm25(workflow_runs_fts)
+	             FROM workflow_runs_fts
+	             JOIN workflow_runs r ON r.id = workflow_runs_fts.rowid
+	             WHERE workflow_runs_fts MATCH ?
+	               AND (? = 0 OR workflow_runs_fts.workflow_id = ?)
+	               AND (? = '' OR workflow_runs_fts.status = ?)
+	               AND (? IS NULL OR workflow_runs_fts.start_time >= ?)
+	               AND (? IS NULL OR workflow_runs_fts.start_time <= ?)
+	             ORDER BY bm25(workflow_runs_fts)
+	             LIMIT ? OFFSET ?`
+
+	var since, until interface{}
+	if !filters.Since.IsZero() {
+		since = filters.Since
+	}
+	if !filters.Until.IsZero() {
+		until = filters.Until
+	}
+
+	rows, err := db.conn.Query(sqlQuery, query,
+		filters.WorkflowID, filters.WorkflowID,
+		filters.Status, filters.Status,
+		since, since,
+		until, until,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []*WorkflowRunSearchHit{}
+	for rows.Next() {
+		run := &WorkflowRun{}
+		hit := &WorkflowRunSearchHit{Run: run}
+		var endTime sql.NullTime
+		var exitCode sql.NullInt64
+		var fileIndexID sql.NullInt64
+		var pausedAt sql.NullTime
+		var resumedAt sql.NullTime
+
+		if err := rows.Scan(
+			&run.ID, &run.WorkflowID, &run.WorkflowName, &fileIndexID, &run.FilePath,
+			&run.Status, &run.StartTime, &endTime, &run.DurationMs, &exitCode,
+			&run.Stdout, &run.Stderr, &run.Logs, &run.MetadataPreserved,
+			&run.MetadataSummary, &run.JobParams, &run.Annotations, &run.Summary,
+			&run.PauseRequested, &run.Checkpoint, &pausedAt, &resumedAt,
+			&hit.Snippet, &hit.Rank,
+		); err != nil {
+			return nil, err
+		}
+
+		if endTime.Valid {
+			run.EndTime = &endTime.Time
+		}
+		if exitCode.Valid {
+			code := int(exitCode.Int64)
+			run.ExitCode = &code
+		}
+		if fileIndexID.Valid {
+			fid := fileIndexID.Int64
+			run.FileIndexID = &fid
+		}
+		if pausedAt.Valid {
+			run.PausedAt = &pausedAt.Time
+		}
+		if resumedAt.Valid {
+			run.ResumedAt = &resumedAt.Time
+		}
+
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
+
+// pruneBatchSize bounds how many rows a single DELETE inside Prune removes,
+// so pruning a large backlog doesn't hold the single SQLite writer
+// connection for one huge transaction; Prune loops, issuing one batch at a
+// time, until a table has nothing left to prune.
+const pruneBatchSize = 500
+
+// PruneOptions controls how Prune trims tasks_history, workflow_runs, and
+// workflows_versions to keep the database from growing unbounded. The zero
+// value of a MaxAge/MaxRows field disables that limit for its table.
+type PruneOptions struct {
+	TaskHistoryMaxAge  time.Duration
+	TaskHistoryMaxRows int64
+
+	WorkflowRunMaxAge  time.Duration
+	WorkflowRunMaxRows int64
+
+	// WorkflowVersionMaxAge/MaxRows apply across all workflows' version
+	// history combined, the same global-cap semantics as the other two
+	// tables, not a per-workflow cap.
+	WorkflowVersionMaxAge  time.Duration
+	WorkflowVersionMaxRows int64
+
+	// KeepFailedLonger doubles TaskHistoryMaxAge/WorkflowRunMaxAge (but not
+	// the MaxRows caps) for failed rows, so a failure investigation has
+	// longer before its evidence is pruned than a routine success does.
+	KeepFailedLonger bool
+
+	// VacuumReclaimThreshold runs VACUUM after pruning if doing so would
+	// reclaim at least this many pages (see PRAGMA page_count); zero
+	// disables VACUUM entirely, since it locks the whole database for the
+	// duration of the rewrite.
+	VacuumReclaimThreshold int64
+}
+
+// PruneReport summarizes one Prune run.
+type PruneReport struct {
+	TaskHistoryDeleted     int64
+	WorkflowRunDeleted     int64
+	WorkflowVersionDeleted int64
+	PagesBefore            int64
+	PagesAfter             int64
+	ReclaimedBytes         int64
+	Vacuumed               bool
+}
+
+// Prune deletes stale rows from tasks_history, workflow_runs, and
+// workflows_versions per opts, in batched transactions so it never holds
+// the single SQLite writer connection for one huge DELETE. workflow_runs
+// rows for an in-flight run (status "running" or "paused") are never
+// deleted regardless of age/count, since their disappearance would orphan
+// a run a client or the runner itself may still reference; the FTS index
+// rows added alongside each table (see SearchTasks/SearchWorkflowRuns) are
+// kept in sync automatically by the AFTER DELETE triggers created in the
+// search_indexes migration (see db/migrations), so Prune doesn't touch
+// *_fts directly.
+func (db *DB) Prune(ctx context.Context, opts PruneOptions) (*PruneReport, error) {
+	report := &PruneReport{}
+
+	pageSize, pagesBefore, err := db.pageStats()
+	if err != nil {
+		return nil, err
+	}
+	report.PagesBefore = pagesBefore
+
+	deleted, err := db.pruneTaskHistory(ctx, opts)
+	report.TaskHistoryDeleted = deleted
+	if err != nil {
+		return report, err
+	}
+
+	deleted, err = db.pruneWorkflowRuns(ctx, opts)
+	report.WorkflowRunDeleted = deleted
+	if err != nil {
+		return report, err
+	}
+
+	deleted, err = db.pruneWorkflowVersions(ctx, opts)
+	report.WorkflowVersionDeleted = deleted
+	if err != nil {
+		return report, err
+	}
+
+	_, pagesAfter, err := db.pageStats()
+	if err != nil {
+		return report, err
+	}
+	report.PagesAfter = pagesAfter
+	report.ReclaimedBytes = reclaimedPages(pagesBefore, pagesAfter) * pageSize
+
+	if opts.VacuumReclaimThreshold > 0 && reclaimedPages(pagesBefore, pagesAfter) >= opts.VacuumReclaimThreshold {
+		if _, err := db.conn.ExecContext(ctx, `VACUUM`); err != nil {
+			return report, err
+		}
+		report.Vacuumed = true
+		if _, pagesAfterVacuum, err := db.pageStats(); err == nil {
+			report.PagesAfter = pagesAfterVacuum
+			report.ReclaimedBytes = reclaimedPages(pagesBefore, pagesAfterVacuum) * pageSize
+		}
+	}
+
+	return report, nil
+}
+
+func reclaimedPages(before, after int64) int64 {
+	if before <= after {
+		return 0
+	}
+	return before - after
+}
+
+// pageStats returns SQLite's page size and current page count, the inputs
+// Prune uses to estimate bytes reclaimed.
+func (db *DB) pageStats() (pageSize, pageCount int64, err error) {
+	if err = db.conn.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, 0, err
+	}
+	if err = db.conn.QueryRow(`PRAGMA page_count`).Scan(&pageCount); err != nil {
+		return 0, 0, err
+	}
+	return pageSize, pageCount, nil
+}
+
+// pruneTaskHistory deletes tasks_history rows older than
+// opts.TaskHistoryMaxAge (doubled for status="failed" rows when
+// KeepFailedLonger is set), then trims whatever remains down to
+// opts.TaskHistoryMaxRows, oldest first.
+func (db *DB) pruneTaskHistory(ctx context.Context, opts PruneOptions) (int64, error) {
+	var total int64
+
+	if opts.TaskHistoryMaxAge > 0 {
+		cutoff := time.Now().Add(-opts.TaskHistoryMaxAge)
+		failedCutoff := cutoff
+		if opts.KeepFailedLonger {
+			failedCutoff = time.Now().Add(-2 * opts.TaskHistoryMaxAge)
+		}
+
+		n, err := db.deleteInBatches(ctx, `
+			DELETE FROM tasks_history WHERE id IN (
+				SELECT id FROM tasks_history
+				WHERE (status != 'failed' AND created_at < ?) OR (status = 'failed' AND created_at < ?)
+				LIMIT ?)`,
+			cutoff, failedCutoff)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	if opts.TaskHistoryMaxRows > 0 {
+		n, err := db.deleteInBatches(ctx, `
+			DELETE FROM tasks_history WHERE id IN (
+				SELECT id FROM (
+					SELECT id FROM tasks_history
+					ORDER BY created_at ASC
+					LIMIT MAX(0, (SELECT count(*) FROM tasks_history) - ?))
+				LIMIT ?)`,
+			opts.TaskHistoryMaxRows)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// pruneWorkflowRuns mirrors pruneTaskHistory, except it never deletes a run
+// whose status is "running" or "paused" (an in-flight run), regardless of
+// age or the row-count cap.
+func (db *DB) pruneWorkflowRuns(ctx context.Context, opts PruneOptions) (int64, error) {
+	var total int64
+
+	if opts.WorkflowRunMaxAge > 0 {
+		cutoff := time.Now().Add(-opts.WorkflowRunMaxAge)
+		failedCutoff := cutoff
+		if opts.KeepFailedLonger {
+			failedCutoff = time.Now().Add(-2 * opts.WorkflowRunMaxAge)
+		}
+
+		n, err := db.deleteInBatches(ctx, `
+			DELETE FROM workflow_runs WHERE id IN (
+				SELECT id FROM workflow_runs
+				WHERE status NOT IN ('running', 'paused')
+				  AND ((status != 'failed' AND start_time < ?) OR (status = 'failed' AND start_time < ?))
+				LIMIT ?)`,
+			cutoff, failedCutoff)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	if opts.WorkflowRunMaxRows > 0 {
+		n, err := db.deleteInBatches(ctx, `
+			DELETE FROM workflow_runs WHERE id IN (
+				SELECT id FROM (
+					SELECT id FROM workflow_runs
+					WHERE status NOT IN ('running', 'paused')
+					ORDER BY start_time ASC
+					LIMIT MAX(0, (SELECT count(*) FROM workflow_runs WHERE status NOT IN ('running', 'paused')) - ?))
+				LIMIT ?)`,
+			opts.WorkflowRunMaxRows)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// pruneWorkflowVersions deletes workflows_versions rows older than
+// opts.WorkflowVersionMaxAge, then trims whatever remains down to
+// opts.WorkflowVersionMaxRows, oldest first, across all workflows combined.
+func (db *DB) pruneWorkflowVersions(ctx context.Context, opts PruneOptions) (int64, error) {
+	var total int64
+
+	if opts.WorkflowVersionMaxAge > 0 {
+		cutoff := time.Now().Add(-opts.WorkflowVersionMaxAge)
+		n, err := db.deleteInBatches(ctx, `
+			DELETE FROM workflows_versions WHERE id IN (
+				SELECT id FROM workflows_versions WHERE created_at < ? LIMIT ?)`,
+			cutoff)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	if opts.WorkflowVersionMaxRows > 0 {
+		n, err := db.deleteInBatches(ctx, `
+			DELETE FROM workflows_versions WHERE id IN (
+				SELECT id FROM (
+					SELECT id FROM workflows_versions
+					ORDER BY created_at ASC
+					LIMIT MAX(0, (SELECT count(*) FROM workflows_versions) - ?))
+				LIMIT ?)`,
+			opts.WorkflowVersionMaxRows)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// deleteInBatches repeatedly executes query (whose final placeholder must
+// be a LIMIT of pruneBatchSize rows, appended here) until a round deletes
+// zero rows, so a large prune never runs as one long-held transaction.
+func (db *DB) deleteInBatches(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	var total int64
+	for {
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		default:
+		}
+
+		result, err := db.conn.ExecContext(ctx, query, append(append([]interface{}{}, args...), pruneBatchSize)...)
+		if err != nil {
+			return total, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += affected
+		if affected < pruneBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// StartPruneScheduler runs Prune on a fixed interval until ctx is canceled.
+// A failed prune is logged rather than returned, so one bad run (e.g. a
+// transient lock contention) doesn't end the schedule; call this from a
+// goroutine, as it blocks until ctx is done.
+func (db *DB) StartPruneScheduler(ctx context.Context, opts PruneOptions, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := db.Prune(ctx, opts); err != nil && ctx.Err() == nil {
+				fmt.Printf("Warning: scheduled prune failed: %v\n", err)
+			}
+		}
+	}
+}
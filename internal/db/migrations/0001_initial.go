@@ -0,0 +1,201 @@
+package migrations
+
+import "database/sql"
+
+// upInitial creates the schema jpeg2heif shipped with before per-feature
+// migrations existed: the core file/task/workflow tables plus the
+// dedup/cluster/lock side-tables added alongside them. Columns and tables
+// added later by sibling features (hash_algo, workflow_runs.annotations/
+// summary/pause support, full-text search, converted_artifacts) are their
+// own follow-on migrations below, so a fresh database ends up identical to
+// one that's been upgraded one migration at a time.
+func upInitial(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS files_index (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_path TEXT NOT NULL UNIQUE,
+		file_md5 TEXT NOT NULL,
+		status TEXT NOT NULL,
+		converter_name TEXT,
+		metadata_preserved BOOLEAN DEFAULT 0,
+		metadata_summary TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_file_path ON files_index(file_path);
+	CREATE INDEX IF NOT EXISTS idx_file_md5 ON files_index(file_md5);
+	CREATE INDEX IF NOT EXISTS idx_status ON files_index(status);
+
+	CREATE TABLE IF NOT EXISTS tasks_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_path TEXT NOT NULL,
+		converter_name TEXT,
+		status TEXT NOT NULL,
+		error_message TEXT,
+		console_output TEXT,
+		duration_ms INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_created ON tasks_history(created_at DESC);
+
+	CREATE TABLE IF NOT EXISTS workflows (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT,
+		yaml TEXT NOT NULL,
+		enabled BOOLEAN DEFAULT 1,
+		created_by TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_workflow_name ON workflows(name);
+	CREATE INDEX IF NOT EXISTS idx_workflow_enabled ON workflows(enabled);
+
+	CREATE TABLE IF NOT EXISTS workflow_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		workflow_id INTEGER NOT NULL,
+		workflow_name TEXT NOT NULL,
+		file_index_id INTEGER,
+		file_path TEXT NOT NULL,
+		status TEXT NOT NULL,
+		start_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		end_time TIMESTAMP,
+		duration_ms INTEGER,
+		exit_code INTEGER,
+		stdout TEXT,
+		stderr TEXT,
+		logs TEXT,
+		metadata_preserved BOOLEAN DEFAULT 0,
+		metadata_summary TEXT,
+		job_params TEXT,
+		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE,
+		FOREIGN KEY (file_index_id) REFERENCES files_index(id) ON DELETE SET NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_workflow_run_workflow ON workflow_runs(workflow_id);
+	CREATE INDEX IF NOT EXISTS idx_workflow_run_status ON workflow_runs(status);
+	CREATE INDEX IF NOT EXISTS idx_workflow_run_start ON workflow_runs(start_time DESC);
+
+	CREATE TABLE IF NOT EXISTS workflows_versions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		workflow_id INTEGER NOT NULL,
+		yaml TEXT NOT NULL,
+		edited_by TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_workflow_version ON workflows_versions(workflow_id, created_at DESC);
+
+	CREATE TABLE IF NOT EXISTS visual_cache (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		visual_digest TEXT NOT NULL,
+		converter_name TEXT NOT NULL,
+		quality INTEGER NOT NULL,
+		output_path TEXT NOT NULL,
+		source_path TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(visual_digest, converter_name, quality)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_visual_cache_digest ON visual_cache(visual_digest);
+
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		events TEXT NOT NULL,
+		auth_header TEXT,
+		auth_token TEXT,
+		secret TEXT,
+		enabled BOOLEAN DEFAULT 1,
+		max_attempts INTEGER DEFAULT 8,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		event_type TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		delivered_at TIMESTAMP,
+		FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhook_delivery_webhook ON webhook_deliveries(webhook_id);
+	CREATE INDEX IF NOT EXISTS idx_webhook_delivery_due ON webhook_deliveries(status, next_attempt_at);
+
+	CREATE TABLE IF NOT EXISTS workflow_cache (
+		key TEXT PRIMARY KEY,
+		output_path TEXT NOT NULL,
+		meta_json TEXT,
+		size_bytes INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_workflow_cache_last_used ON workflow_cache(last_used_at);
+
+	CREATE TABLE IF NOT EXISTS cluster_outputs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_path TEXT NOT NULL,
+		file_md5 TEXT NOT NULL,
+		hash_algo TEXT NOT NULL DEFAULT 'md5',
+		converter_name TEXT NOT NULL,
+		target_path TEXT NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(file_md5, hash_algo, converter_name)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_cluster_outputs_updated ON cluster_outputs(updated_at);
+
+	CREATE TABLE IF NOT EXISTS peer_index (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		peer_node TEXT NOT NULL,
+		file_path TEXT NOT NULL,
+		file_md5 TEXT NOT NULL,
+		hash_algo TEXT NOT NULL DEFAULT 'md5',
+		status TEXT NOT NULL,
+		converter_name TEXT NOT NULL,
+		target_path TEXT NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(file_md5, hash_algo, converter_name)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_peer_index_peer ON peer_index(peer_node);
+
+	CREATE TABLE IF NOT EXISTS peer_watermarks (
+		peer_node TEXT PRIMARY KEY,
+		watermark TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS cluster_peer_hits (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_md5 TEXT NOT NULL,
+		converter_name TEXT NOT NULL,
+		peer_node TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS locks (
+		key TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		converter_name TEXT NOT NULL,
+		file_path TEXT NOT NULL,
+		acquired_at TIMESTAMP NOT NULL,
+		expires_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_locks_converter ON locks(converter_name);
+	CREATE INDEX IF NOT EXISTS idx_locks_expires ON locks(expires_at);
+	`)
+	return err
+}
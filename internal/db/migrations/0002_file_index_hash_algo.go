@@ -0,0 +1,12 @@
+package migrations
+
+import "database/sql"
+
+// upFileIndexHashAlgo adds files_index.hash_algo, so a file's indexed
+// content digest can be verified against the hash algorithm (md5, sha256,
+// blake3, or xxh3; see util.NewFileHasher) that produced it instead of
+// always assuming md5.
+func upFileIndexHashAlgo(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE files_index ADD COLUMN hash_algo TEXT NOT NULL DEFAULT 'md5'`)
+	return err
+}
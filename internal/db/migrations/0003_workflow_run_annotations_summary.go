@@ -0,0 +1,15 @@
+package migrations
+
+import "database/sql"
+
+// upWorkflowRunAnnotationsSummary adds workflow_runs.annotations (a JSON
+// array of workflow.Annotation raised via "::error::"/"::warning::"/
+// "::notice::"/"::debug::") and workflow_runs.summary (Markdown written to
+// $GITHUB_STEP_SUMMARY across the run's steps).
+func upWorkflowRunAnnotationsSummary(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE workflow_runs ADD COLUMN annotations TEXT`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`ALTER TABLE workflow_runs ADD COLUMN summary TEXT`)
+	return err
+}
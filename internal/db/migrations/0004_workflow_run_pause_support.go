@@ -0,0 +1,24 @@
+package migrations
+
+import "database/sql"
+
+// upWorkflowRunPauseSupport adds the columns needed to cooperatively pause
+// and resume a long-running workflow run: pause_requested (set by
+// db.PauseWorkflowRun, read back between steps via
+// workflow.ExecutionContext.PauseCheck), checkpoint (a JSON blob recording
+// how far the run got, for workflow.ExecutionContext.ResumeFromStep), and
+// paused_at/resumed_at timestamps.
+func upWorkflowRunPauseSupport(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE workflow_runs ADD COLUMN pause_requested BOOLEAN DEFAULT 0`,
+		`ALTER TABLE workflow_runs ADD COLUMN checkpoint TEXT`,
+		`ALTER TABLE workflow_runs ADD COLUMN paused_at TIMESTAMP`,
+		`ALTER TABLE workflow_runs ADD COLUMN resumed_at TIMESTAMP`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
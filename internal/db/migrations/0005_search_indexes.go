@@ -0,0 +1,69 @@
+package migrations
+
+import "database/sql"
+
+// upSearchIndexes adds tasks_history_fts/workflow_runs_fts, the FTS5
+// external-content indexes backing db.SearchTasks/SearchWorkflowRuns, plus
+// the AFTER INSERT/UPDATE/DELETE triggers that keep them in sync with their
+// base tables, and backfills both from any rows that already existed before
+// this migration ran. Unlike the old initSchema-era backfill, no row-count
+// guard is needed here: a migration only ever runs once per database (see
+// db.Migrate), so there's no risk of re-inserting the same rows on a later
+// startup.
+func upSearchIndexes(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS tasks_history_fts USING fts5(
+		console_output,
+		content='tasks_history',
+		content_rowid='id'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS tasks_history_fts_ai AFTER INSERT ON tasks_history BEGIN
+		INSERT INTO tasks_history_fts(rowid, console_output) VALUES (new.id, new.console_output);
+	END;
+	CREATE TRIGGER IF NOT EXISTS tasks_history_fts_ad AFTER DELETE ON tasks_history BEGIN
+		INSERT INTO tasks_history_fts(tasks_history_fts, rowid, console_output) VALUES ('delete', old.id, old.console_output);
+	END;
+	CREATE TRIGGER IF NOT EXISTS tasks_history_fts_au AFTER UPDATE ON tasks_history BEGIN
+		INSERT INTO tasks_history_fts(tasks_history_fts, rowid, console_output) VALUES ('delete', old.id, old.console_output);
+		INSERT INTO tasks_history_fts(rowid, console_output) VALUES (new.id, new.console_output);
+	END;
+
+	CREATE VIRTUAL TABLE IF NOT EXISTS workflow_runs_fts USING fts5(
+		stdout, stderr, logs,
+		workflow_id UNINDEXED,
+		status UNINDEXED,
+		start_time UNINDEXED,
+		content='workflow_runs',
+		content_rowid='id'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS workflow_runs_fts_ai AFTER INSERT ON workflow_runs BEGIN
+		INSERT INTO workflow_runs_fts(rowid, stdout, stderr, logs, workflow_id, status, start_time)
+		VALUES (new.id, new.stdout, new.stderr, new.logs, new.workflow_id, new.status, new.start_time);
+	END;
+	CREATE TRIGGER IF NOT EXISTS workflow_runs_fts_ad AFTER DELETE ON workflow_runs BEGIN
+		INSERT INTO workflow_runs_fts(workflow_runs_fts, rowid, stdout, stderr, logs, workflow_id, status, start_time)
+		VALUES ('delete', old.id, old.stdout, old.stderr, old.logs, old.workflow_id, old.status, old.start_time);
+	END;
+	CREATE TRIGGER IF NOT EXISTS workflow_runs_fts_au AFTER UPDATE ON workflow_runs BEGIN
+		INSERT INTO workflow_runs_fts(workflow_runs_fts, rowid, stdout, stderr, logs, workflow_id, status, start_time)
+		VALUES ('delete', old.id, old.stdout, old.stderr, old.logs, old.workflow_id, old.status, old.start_time);
+		INSERT INTO workflow_runs_fts(rowid, stdout, stderr, logs, workflow_id, status, start_time)
+		VALUES (new.id, new.stdout, new.stderr, new.logs, new.workflow_id, new.status, new.start_time);
+	END;
+	`)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO tasks_history_fts(rowid, console_output) SELECT id, console_output FROM tasks_history`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO workflow_runs_fts(rowid, stdout, stderr, logs, workflow_id, status, start_time)
+	                      SELECT id, stdout, stderr, logs, workflow_id, status, start_time FROM workflow_runs`); err != nil {
+		return err
+	}
+
+	return nil
+}
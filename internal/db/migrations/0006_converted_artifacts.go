@@ -0,0 +1,27 @@
+package migrations
+
+import "database/sql"
+
+// upConvertedArtifacts adds converted_artifacts, the content-addressable
+// dedup cache backing db.LookupArtifact/RegisterArtifact: one row per
+// (source file content hash, converter, options hash) already converted, so
+// a byte-identical source file seen again under the same settings can reuse
+// the existing output instead of re-encoding.
+func upConvertedArtifacts(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS converted_artifacts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_md5 TEXT NOT NULL,
+		converter_name TEXT NOT NULL,
+		options_hash TEXT NOT NULL,
+		output_path TEXT NOT NULL,
+		output_size INTEGER NOT NULL,
+		output_md5 TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(file_md5, converter_name, options_hash)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_converted_artifacts_md5 ON converted_artifacts(file_md5);
+	`)
+	return err
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import "database/sql"
+
+// upBlobStore adds blobs, the refcounted content-addressed chunk table
+// backing db.UpsertBlob/GetBlob/GetBlobStats (see internal/blobstore), and
+// files_index.output_manifest, the per-file list of chunk IDs a converted
+// output was split into, so db.DeleteFileIndex can decrement their
+// refcounts when a file is removed from the index.
+func upBlobStore(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS blobs (
+		chunk_id TEXT PRIMARY KEY,
+		ref_count INTEGER NOT NULL DEFAULT 0,
+		size INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`ALTER TABLE files_index ADD COLUMN output_manifest TEXT NOT NULL DEFAULT ''`)
+	return err
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import "database/sql"
+
+// upStepDeps adds step_deps, indexing the per-step recfile records
+// workflow.Executor writes to each output's "<OutputFile>.rec" sidecar
+// (see db.StepDep), so a step's resolved command, workdir, env, timing and
+// dependency hashes can be queried by workflow run without reparsing the
+// sidecar file.
+func upStepDeps(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS step_deps (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		workflow_run_id INTEGER NOT NULL,
+		step_name TEXT NOT NULL,
+		command TEXT NOT NULL,
+		workdir TEXT NOT NULL,
+		env TEXT NOT NULL DEFAULT '',
+		exit_code INTEGER NOT NULL DEFAULT 0,
+		start_time TEXT NOT NULL DEFAULT '',
+		end_time TEXT NOT NULL DEFAULT '',
+		input_hashes TEXT NOT NULL DEFAULT '',
+		created_hashes TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (workflow_run_id) REFERENCES workflow_runs(id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_step_deps_workflow_run_id ON step_deps(workflow_run_id);
+	`)
+	return err
+}
@@ -0,0 +1,12 @@
+package migrations
+
+import "database/sql"
+
+// upTaskHistoryAnnotations adds tasks_history.annotations, carrying
+// converter.MetaResult.Annotations (a JSON array of workflow.Annotation
+// raised via "::error::"/"::warning::"/"::notice::"/"::debug::") through to
+// GET /api/tasks, which reads tasks_history rather than workflow_runs.
+func upTaskHistoryAnnotations(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE tasks_history ADD COLUMN annotations TEXT NOT NULL DEFAULT ''`)
+	return err
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import "database/sql"
+
+// upFileMetadata adds file_metadata, the tag-by-tag metadata preservation
+// outcome for a converted file (see db.FileMetadata), replacing the
+// files_index.metadata_preserved boolean as the source of truth for how
+// thoroughly a conversion preserved metadata: GetStats' weighted preserve
+// rate and GET /api/files/:id/metadata both read this table rather than
+// that single column.
+func upFileMetadata(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS file_metadata (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_index_id INTEGER NOT NULL,
+		tag_name TEXT NOT NULL,
+		source_value TEXT NOT NULL DEFAULT '',
+		output_value TEXT NOT NULL DEFAULT '',
+		preserved BOOLEAN NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (file_index_id) REFERENCES files_index(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_file_metadata_file_index_id ON file_metadata(file_index_id);
+	`)
+	return err
+}
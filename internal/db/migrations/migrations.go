@@ -0,0 +1,33 @@
+// Package migrations defines jpeg2heif's versioned database schema as an
+// ordered list of migrations, applied in order by db.Migrate. It depends on
+// nothing but database/sql (not the db package itself) so db can import it
+// without a cycle.
+package migrations
+
+import "database/sql"
+
+// Migration is one forward step in the schema's history. Version must be
+// unique and migrations are applied in ascending Version order; once a
+// Version has shipped, its Up function must never change, since doing so
+// would silently diverge already-migrated databases from freshly created
+// ones.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+}
+
+// All is the complete, ordered migration history. Append new migrations to
+// the end; never reorder, renumber, or remove an existing entry.
+var All = []Migration{
+	{Version: 1, Name: "initial", Up: upInitial},
+	{Version: 2, Name: "file_index_hash_algo", Up: upFileIndexHashAlgo},
+	{Version: 3, Name: "workflow_run_annotations_summary", Up: upWorkflowRunAnnotationsSummary},
+	{Version: 4, Name: "workflow_run_pause_support", Up: upWorkflowRunPauseSupport},
+	{Version: 5, Name: "search_indexes", Up: upSearchIndexes},
+	{Version: 6, Name: "converted_artifacts", Up: upConvertedArtifacts},
+	{Version: 7, Name: "blob_store", Up: upBlobStore},
+	{Version: 8, Name: "step_deps", Up: upStepDeps},
+	{Version: 9, Name: "task_history_annotations", Up: upTaskHistoryAnnotations},
+	{Version: 10, Name: "file_metadata", Up: upFileMetadata},
+}
@@ -9,12 +9,100 @@ type FileIndex struct {
 	ID                int64     `json:"id"`
 	FilePath          string    `json:"file_path"`
 	FileMD5           string    `json:"file_md5"`
+	HashAlgo          string    `json:"hash_algo"` // md5, sha256, blake3, or xxh3; empty means "md5" (pre-dates this column)
 	Status            string    `json:"status"` // pending, processing, success, failed
 	ConverterName     string    `json:"converter_name"`
 	MetadataPreserved bool      `json:"metadata_preserved"`
 	MetadataSummary   string    `json:"metadata_summary"`
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
+	// OptionsHash is not a files_index column; a caller that wants
+	// UpsertFileIndex to check converted_artifacts for a reusable output
+	// sets it to converter.ConvertOptions.OptionsHash() before calling.
+	// Left empty, no lookup is performed.
+	OptionsHash string `json:"-"`
+	// CacheHit is not a files_index column either; UpsertFileIndex sets it
+	// to true when OptionsHash was set and FileMD5+ConverterName+OptionsHash
+	// matched an existing ConvertedArtifact, so the caller can copy/hardlink
+	// that artifact's output instead of re-encoding.
+	CacheHit bool `json:"cache_hit"`
+	// OutputManifest is a blobstore.Manifest JSON blob describing the
+	// content-defined chunks this file's converted output was split into
+	// (see internal/blobstore), set via UpdateFileIndexManifest. Empty
+	// means either the file hasn't been converted through the blob store
+	// yet, or blob storage isn't enabled. DeleteFileIndex reads it back to
+	// decrement each chunk's refcount before removing the row.
+	OutputManifest string `json:"output_manifest,omitempty"`
+}
+
+// FileMetadata is one metadata tag's preservation outcome for a converted
+// file, recorded by comparing the tag set extractMetadata read from the
+// source against the tag set it read from the converted output (see
+// converter.TagDiff). TagName keeps whatever backend-specific key the
+// extractor used (e.g. exiftool's "ExifIFD:DateTimeOriginal"), so GET
+// /api/files/:id/metadata can show callers exactly which group a tag came
+// from instead of a backend-agnostic but less precise name.
+type FileMetadata struct {
+	ID          int64     `json:"id"`
+	FileIndexID int64     `json:"file_index_id"`
+	TagName     string    `json:"tag_name"`
+	SourceValue string    `json:"source_value"`
+	OutputValue string    `json:"output_value"`
+	Preserved   bool      `json:"preserved"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Blob is one content-defined chunk in the blob store (see
+// internal/blobstore), identified by the SHA-256 digest of its content.
+// RefCount is how many FileIndex.OutputManifest entries, across every file
+// ever converted, currently reference this chunk; once it reaches zero the
+// chunk is unreferenced and safe to remove from disk.
+type Blob struct {
+	ChunkID   string    `json:"chunk_id"`
+	RefCount  int64     `json:"ref_count"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BlobStats summarizes the blob store's deduplication across every chunk
+// still referenced, as surfaced by GET /api/stats.
+type BlobStats struct {
+	ChunkCount   int64 `json:"chunk_count"`
+	UniqueBytes  int64 `json:"unique_bytes"`  // bytes actually stored, one copy per chunk
+	LogicalBytes int64 `json:"logical_bytes"` // bytes every chunk's referencing file would add up to if none were shared
+}
+
+// DeduplicationRatio is LogicalBytes/UniqueBytes: how many times smaller the
+// blob store is than if every referencing file stored its chunks
+// independently. Returns 0 when UniqueBytes is 0 (nothing stored yet) to
+// avoid a division by zero.
+func (s BlobStats) DeduplicationRatio() float64 {
+	if s.UniqueBytes == 0 {
+		return 0
+	}
+	return float64(s.LogicalBytes) / float64(s.UniqueBytes)
+}
+
+// StepDep indexes one step's recfile record from a workflow run's
+// "<OutputFile>.rec" sidecar (see workflow.StepDepRecord), so its command,
+// workdir, env, timing and dependency hashes can be queried without
+// reparsing the sidecar file. It's written once per step by
+// WorkflowConverter after a successful run; the sidecar itself, not this
+// table, is what workflow.Executor.ExecuteIfChanged reads to decide
+// whether to skip a rerun.
+type StepDep struct {
+	ID            int64     `json:"id"`
+	WorkflowRunID int64     `json:"workflow_run_id"`
+	StepName      string    `json:"step_name"`
+	Command       string    `json:"command"`
+	Workdir       string    `json:"workdir"`
+	Env           string    `json:"env"`       // JSON object of the step's resolved environment
+	ExitCode      int       `json:"exit_code"`
+	StartTime     string    `json:"start_time"` // TAI64N external format
+	EndTime       string    `json:"end_time"`   // TAI64N external format
+	InputHashes   string    `json:"input_hashes"`   // JSON object: template var name -> sha256
+	CreatedHashes string    `json:"created_hashes"` // JSON object: path relative to Workdir -> sha256
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 // TaskHistory represents a conversion task history entry
@@ -27,6 +115,11 @@ type TaskHistory struct {
 	ConsoleOutput string    `json:"console_output"` // 控制台详细输出
 	DurationMs    int64     `json:"duration_ms"`
 	CreatedAt     time.Time `json:"created_at"`
+	// Annotations is converter.MetaResult.Annotations carried through: a
+	// JSON array of workflow.Annotation raised via "::error::"/
+	// "::warning::"/"::notice::"/"::debug::" during conversion. Empty for
+	// converters that don't support workflow commands.
+	Annotations string `json:"annotations,omitempty"`
 }
 
 // Stats represents conversion statistics
@@ -36,6 +129,14 @@ type Stats struct {
 	FailedCount     int64 `json:"failed_count"`
 	PendingCount    int64 `json:"pending_count"`
 	ProcessingCount int64 `json:"processing_count"`
+	PeerHitCount    int64 `json:"peer_hit_count"` // conversions satisfied by fetching a cluster peer's output instead of running locally
+	// MetadataPreserveRate is PreservedTagCount/TotalTagCount across every
+	// file_metadata row ever recorded (see FileMetadata), not the fraction
+	// of files with at least one tag preserved: a file that kept 40 of 50
+	// tags contributes 40/50 here, not a single "preserved" count, so one
+	// partially-preserved file doesn't read the same as one that preserved
+	// nothing. 0 when no file_metadata rows exist yet.
+	MetadataPreserveRate float64 `json:"metadata_preserve_rate"`
 }
 
 // Workflow represents a YAML-based conversion workflow
@@ -57,7 +158,7 @@ type WorkflowRun struct {
 	WorkflowName      string     `json:"workflow_name"`
 	FileIndexID       *int64     `json:"file_index_id,omitempty"`
 	FilePath          string     `json:"file_path"`
-	Status            string     `json:"status"` // pending, running, success, failed
+	Status            string     `json:"status"` // pending, running, success, failed, cancelled, paused
 	StartTime         time.Time  `json:"start_time"`
 	EndTime           *time.Time `json:"end_time,omitempty"`
 	DurationMs        int64      `json:"duration_ms"`
@@ -68,6 +169,20 @@ type WorkflowRun struct {
 	MetadataPreserved bool       `json:"metadata_preserved"`
 	MetadataSummary   string     `json:"metadata_summary"`
 	JobParams         string     `json:"job_params"` // JSON of variables used
+	Annotations       string     `json:"annotations"` // JSON array of workflow.Annotation raised via "::error::"/"::warning::"/"::notice::"/"::debug::"
+	Summary           string     `json:"summary"`     // Markdown written to $GITHUB_STEP_SUMMARY across the run's steps
+	// PauseRequested is set by PauseWorkflowRun and read back by the
+	// runner between steps (see workflow.ExecutionContext.PauseCheck); it
+	// does not itself change Status, since the runner may observe it only
+	// after the run has already reached a terminal status naturally.
+	PauseRequested bool `json:"pause_requested"`
+	// Checkpoint is a JSON blob recording enough of the run's
+	// intermediate state (currently {"completed_steps": N}) for a
+	// resumed execution to pick up from workflow.ExecutionContext.ResumeFromStep
+	// instead of restarting from the first step.
+	Checkpoint string     `json:"checkpoint"`
+	PausedAt   *time.Time `json:"paused_at,omitempty"`
+	ResumedAt  *time.Time `json:"resumed_at,omitempty"`
 }
 
 // WorkflowVersion represents a historical version of a workflow
@@ -78,3 +193,172 @@ type WorkflowVersion struct {
 	EditedBy   string    `json:"edited_by"`
 	CreatedAt  time.Time `json:"created_at"`
 }
+
+// Webhook represents an outbound subscription to lifecycle events, delivered
+// as a signed HTTP POST.
+type Webhook struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	Events      string    `json:"events"` // comma-separated event names, e.g. "file.converted,file.failed"
+	AuthHeader  string    `json:"auth_header,omitempty"`
+	AuthToken   string    `json:"-"` // sent as AuthHeader's value; never echoed back
+	Secret      string    `json:"-"` // HMAC-SHA256 key for X-JPEG2HEIF-Signature; never echoed back
+	Enabled     bool      `json:"enabled"`
+	MaxAttempts int       `json:"max_attempts"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is a single queued/attempted delivery of one event to one
+// Webhook. Rows are created eagerly (before the first HTTP attempt) so a
+// delivery survives a restart between being queued and being delivered.
+type WebhookDelivery struct {
+	ID            int64      `json:"id"`
+	WebhookID     int64      `json:"webhook_id"`
+	EventType     string     `json:"event_type"`
+	Payload       string     `json:"payload"` // JSON body as sent
+	Status        string     `json:"status"`  // pending, delivered, retrying, dead_letter
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WorkflowCacheEntry records a previously produced workflow run's output
+// keyed by a digest of everything that determines it (source file content,
+// workflow definition, quality), so an identical future run can reuse
+// OutputPath instead of re-executing the workflow. Unlike VisualCacheEntry
+// (keyed by decoded pixels, image converters only), this applies to any
+// workflow-based conversion and is stored content-addressed on disk under
+// Config.CacheDir rather than at the task's normal output path.
+type WorkflowCacheEntry struct {
+	Key        string    `json:"key"`
+	OutputPath string    `json:"output_path"`
+	MetaJSON   string    `json:"meta_json"` // JSON-encoded converter.MetaResult
+	SizeBytes  int64     `json:"size_bytes"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// VisualCacheEntry records a previously produced conversion output keyed by
+// the visual digest of its source pixels, so a future file that decodes to
+// the same pixels (e.g. the same photo re-exported with different EXIF data)
+// can be satisfied by reusing OutputPath instead of re-running the converter.
+type VisualCacheEntry struct {
+	ID           int64     `json:"id"`
+	VisualDigest string    `json:"visual_digest"`
+	Converter    string    `json:"converter_name"`
+	Quality      int       `json:"quality"`
+	OutputPath   string    `json:"output_path"`
+	SourcePath   string    `json:"source_path"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ClusterOutput records one of this node's own successful conversions, keyed
+// by the source file's content hash and the converter that produced it, so
+// it can be gossiped to peers and served via internal/cluster's lookup/fetch
+// endpoints.
+type ClusterOutput struct {
+	ID            int64     `json:"id"`
+	FilePath      string    `json:"file_path"`
+	FileMD5       string    `json:"file_md5"`
+	HashAlgo      string    `json:"hash_algo"`
+	ConverterName string    `json:"converter_name"`
+	TargetPath    string    `json:"target_path"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// PeerIndexEntry is a converted-output record learned from another cluster
+// node, either pushed to us via gossip or pulled during reconciliation. It
+// lets a worker consult the cluster before converting a file without making
+// a live network call per task. See internal/cluster.
+type PeerIndexEntry struct {
+	ID            int64     `json:"id"`
+	PeerNode      string    `json:"peer_node"`
+	FilePath      string    `json:"file_path"`
+	FileMD5       string    `json:"file_md5"`
+	HashAlgo      string    `json:"hash_algo"`
+	Status        string    `json:"status"` // success; only successful conversions are worth gossiping
+	ConverterName string    `json:"converter_name"`
+	TargetPath    string    `json:"target_path"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Lock records a distributed conversion lease held by one node over a given
+// (file path, converter) pair, so two jpeg2heif processes/nodes sharing this
+// database can't convert the same file through the same converter at once.
+// Key is a digest of FilePath and ConverterName (see internal/lock.Key);
+// ConverterName and FilePath are kept in their own columns so a lock can
+// also be looked up by converter alone, which the API layer needs to refuse
+// disabling a converter while a lease against it is still outstanding.
+type Lock struct {
+	Key           string    `json:"key"`
+	Owner         string    `json:"owner"`
+	ConverterName string    `json:"converter_name"`
+	FilePath      string    `json:"file_path"`
+	AcquiredAt    time.Time `json:"acquired_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// ConvertedArtifact records a previously produced conversion output keyed by
+// the source file's raw content hash, the converter that produced it, and a
+// digest of the ConvertOptions used (see converter.ConvertOptions.OptionsHash),
+// so an exact-byte-identical source file (e.g. the same JPEG duplicated
+// across folders) seen again under the same settings can reuse the existing
+// output instead of re-encoding. Unlike VisualCacheEntry, which matches on
+// decoded pixels and so also catches re-exported copies of the same photo,
+// this only matches files that are byte-for-byte the same.
+type ConvertedArtifact struct {
+	ID            int64     `json:"id"`
+	FileMD5       string    `json:"file_md5"`
+	ConverterName string    `json:"converter_name"`
+	OptionsHash   string    `json:"options_hash"`
+	OutputPath    string    `json:"output_path"`
+	OutputSize    int64     `json:"output_size"`
+	OutputMD5     string    `json:"output_md5"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AppliedMigration is one row of schema_migrations, as reported by
+// DB.MigrationStatus.
+type AppliedMigration struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// PendingMigration is one entry from db/migrations that hasn't been applied
+// to this database yet, as reported by DB.MigrationStatus.
+type PendingMigration struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+}
+
+// TaskHistorySearchHit is one SearchTasks result: the matched task plus a
+// snippet() excerpt of its console output (matched terms wrapped in
+// "<b>...</b>") and its BM25 rank, where a lower rank is a better match.
+type TaskHistorySearchHit struct {
+	Task    *TaskHistory `json:"task"`
+	Snippet string       `json:"snippet"`
+	Rank    float64      `json:"rank"`
+}
+
+// WorkflowRunSearchHit is one SearchWorkflowRuns result, the workflow_runs
+// analogue of TaskHistorySearchHit.
+type WorkflowRunSearchHit struct {
+	Run     *WorkflowRun `json:"run"`
+	Snippet string       `json:"snippet"`
+	Rank    float64      `json:"rank"`
+}
+
+// SearchFilters narrows SearchWorkflowRuns beyond the full-text query
+// itself, using workflow_runs_fts's UNINDEXED auxiliary columns so the
+// narrowing is pushed into the same MATCH query rather than post-filtered
+// in Go. The zero value of each field means "don't filter on this".
+type SearchFilters struct {
+	WorkflowID int64
+	Status     string
+	Since      time.Time
+	Until      time.Time
+}
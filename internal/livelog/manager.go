@@ -1,9 +1,10 @@
 package livelog
-package livelog
 
 import (
 	"sync"
 	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/progress"
 )
 
 // LiveLog represents a live log entry
@@ -16,12 +17,14 @@ type LiveLog struct {
 
 // Manager manages live logs for running tasks
 type Manager struct {
-	mu   sync.RWMutex
-	logs map[string]*LiveLog // key: file path
+	mu     sync.RWMutex
+	logs   map[string]*LiveLog     // key: file path
+	events *progress.Registry // per-file Publishers for SSE subscribers
 }
 
 var globalManager = &Manager{
-	logs: make(map[string]*LiveLog),
+	logs:   make(map[string]*LiveLog),
+	events: progress.NewRegistry(500),
 }
 
 // GetManager returns the singleton live log manager
@@ -29,28 +32,55 @@ func GetManager() *Manager {
 	return globalManager
 }
 
-// StartTask creates a new live log entry for a task
+// Events returns the Publisher carrying live start/append/end events for
+// filePath, for use by the API layer's live-log SSE endpoint. It's created
+// lazily, so a client may subscribe before or after StartTask is called.
+func (m *Manager) Events(filePath string) progress.Publisher {
+	return m.events.Get(filePath)
+}
+
+// StartTask creates a new live log entry for a task and publishes a "start"
+// event to any subscriber already attached to filePath.
 func (m *Manager) StartTask(filePath string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	m.logs[filePath] = &LiveLog{
 		FilePath:   filePath,
 		Logs:       "",
 		StartTime:  time.Now(),
 		LastUpdate: time.Now(),
 	}
+	m.mu.Unlock()
+
+	m.events.Get(filePath).Publish(progress.JobEvent{
+		Type:      progress.JobEventStateChange,
+		Path:      filePath,
+		State:     "start",
+		Timestamp: time.Now(),
+	})
 }
 
-// AppendLog appends log content to a task's live log
+// AppendLog appends log content to a task's live log and publishes it as a
+// "log" event to any subscriber attached to filePath. A no-op if StartTask
+// hasn't been called for filePath (or EndTask already has).
 func (m *Manager) AppendLog(filePath, logContent string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if log, exists := m.logs[filePath]; exists {
+	log, exists := m.logs[filePath]
+	if exists {
 		log.Logs += logContent
 		log.LastUpdate = time.Now()
 	}
+	m.mu.Unlock()
+
+	if !exists {
+		return
+	}
+
+	m.events.Get(filePath).Publish(progress.JobEvent{
+		Type:      progress.JobEventLog,
+		Path:      filePath,
+		Message:   logContent,
+		Timestamp: time.Now(),
+	})
 }
 
 // GetLog retrieves the live log for a task
@@ -72,12 +102,21 @@ func (m *Manager) GetLog(filePath string) (*LiveLog, bool) {
 	}, true
 }
 
-// EndTask removes a task's live log (called when task completes)
+// EndTask publishes an "end" event for filePath and removes its live log
+// (called when task completes). The Publisher itself is left in place so a
+// subscriber that reconnects just after completion still replays the final
+// events instead of finding nothing registered.
 func (m *Manager) EndTask(filePath string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	delete(m.logs, filePath)
+	m.mu.Unlock()
+
+	m.events.Get(filePath).Publish(progress.JobEvent{
+		Type:      progress.JobEventStateChange,
+		Path:      filePath,
+		State:     "end",
+		Timestamp: time.Now(),
+	})
 }
 
 // GetAllActiveLogs returns all active live logs
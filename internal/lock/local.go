@@ -0,0 +1,74 @@
+package lock
+
+import (
+	"context"
+	"sync"
+)
+
+// LocalLocker serializes access to a (file path, converter) pair within
+// this process only, via a per-key mutex; it never contends across
+// separate processes or nodes. Use SQLiteLocker when the same watch
+// directory is served by more than one jpeg2heif process.
+type LocalLocker struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewLocalLocker creates a LocalLocker.
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{locks: make(map[string]*sync.Mutex)}
+}
+
+func (l *LocalLocker) mutexFor(key string) *sync.Mutex {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m, ok := l.locks[key]
+	if !ok {
+		m = &sync.Mutex{}
+		l.locks[key] = m
+	}
+	return m
+}
+
+// Acquire blocks until the (filePath, converterName) pair's mutex is free
+// or ctx is canceled.
+func (l *LocalLocker) Acquire(ctx context.Context, filePath, converterName string) (Lease, error) {
+	m := l.mutexFor(Key(filePath, converterName))
+
+	acquired := make(chan struct{})
+	go func() {
+		m.Lock()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		leaseCtx, cancel := context.WithCancel(ctx)
+		return &localLease{mu: m, ctx: leaseCtx, cancel: cancel}, nil
+	case <-ctx.Done():
+		// The goroutine above may still be blocked in m.Lock() and acquire it
+		// after we've given up; release it as soon as it does so the mutex
+		// isn't held forever by an abandoned attempt.
+		go func() {
+			<-acquired
+			m.Unlock()
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+type localLease struct {
+	mu     *sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (l *localLease) Context() context.Context { return l.ctx }
+
+func (l *localLease) Release() {
+	l.once.Do(func() {
+		l.cancel()
+		l.mu.Unlock()
+	})
+}
@@ -0,0 +1,41 @@
+// Package lock gates conversion work against a (file path, converter) pair
+// so it can't run concurrently, whether the contention is between two
+// goroutines in this process (LocalLocker) or between two jpeg2heif
+// processes/nodes sharing a database (SQLiteLocker). Both implementations
+// satisfy the same Locker interface so worker.Worker can depend on it
+// without caring which one is wired in.
+package lock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Key derives the lock key for a (file path, converter) pair, matching the
+// same granularity a worker already converts at: two tasks for the same
+// file through the same converter must never run at once, but the same
+// file going through two different converters (or two different files
+// through the same converter) may.
+func Key(filePath, converterName string) string {
+	sum := sha256.Sum256([]byte(filePath + "|" + converterName))
+	return hex.EncodeToString(sum[:])
+}
+
+// Lease represents a held lock on a (file path, converter) pair. Context
+// returns a context derived from the one passed to Acquire, which is
+// additionally canceled if the lease is lost out from under its holder
+// (SQLiteLocker only: an owned lease expired before it could be refreshed,
+// and another owner stole it). Callers must treat that cancellation as a
+// signal to abort whatever they were doing under the lease. Release must be
+// called exactly once, whether or not Context was canceled first.
+type Lease interface {
+	Context() context.Context
+	Release()
+}
+
+// Locker acquires a Lease for a (file path, converter) pair, blocking until
+// it's available or ctx is canceled.
+type Locker interface {
+	Acquire(ctx context.Context, filePath, converterName string) (Lease, error)
+}
@@ -0,0 +1,125 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/db"
+)
+
+// ErrContended is returned by SQLiteLocker.Acquire when ctx is canceled
+// while the key was held by another owner. Owner identifies the current
+// holder, so the API layer can surface it in a 409 response.
+type ErrContended struct {
+	FilePath      string
+	ConverterName string
+	Owner         string
+}
+
+func (e *ErrContended) Error() string {
+	return fmt.Sprintf("conversion of %q via %q is locked by %q", e.FilePath, e.ConverterName, e.Owner)
+}
+
+// acquirePollInterval is how often a blocked Acquire retries a contended
+// key, since lease expiry (not a channel it could wait on) is what frees it.
+const acquirePollInterval = 500 * time.Millisecond
+
+// SQLiteLocker coordinates a lease across multiple jpeg2heif processes/nodes
+// sharing database, via the locks table: TryAcquireLock claims a key with a
+// conditional UPSERT that only steals it once its lease has expired, and a
+// background goroutine refreshes every lease this owner holds at ttl/3, so
+// a live holder doesn't lose its lock to a stale-expiry steal mid-conversion.
+type SQLiteLocker struct {
+	db    *db.DB
+	owner string
+	ttl   time.Duration
+}
+
+// NewSQLiteLocker creates a SQLiteLocker backed by database. owner
+// identifies this process/node (util.Config.ClusterNodeID, typically) in
+// the locks table and in ErrContended. ttl bounds how long an acquired
+// lease survives without a refresh; refreshes happen at ttl/3, giving two
+// missed refreshes of slack before a lease is considered abandoned (e.g.
+// its owner crashed) and eligible for another owner to steal.
+func NewSQLiteLocker(database *db.DB, owner string, ttl time.Duration) *SQLiteLocker {
+	return &SQLiteLocker{db: database, owner: owner, ttl: ttl}
+}
+
+// Acquire claims the (filePath, converterName) pair, retrying on a poll
+// interval until it's free or ctx is canceled. Unlike LocalLocker, a denied
+// attempt can't block on a Go mutex being unlocked; lease expiry is the
+// only thing that frees a key whose owner stopped refreshing it (e.g. it
+// crashed), so polling is the only option.
+func (l *SQLiteLocker) Acquire(ctx context.Context, filePath, converterName string) (Lease, error) {
+	key := Key(filePath, converterName)
+
+	for {
+		acquired, currentOwner, err := l.db.TryAcquireLock(key, l.owner, converterName, filePath, time.Now(), l.ttl)
+		if err != nil {
+			return nil, fmt.Errorf("acquire lock for %q via %q: %w", filePath, converterName, err)
+		}
+		if acquired {
+			return l.startLease(ctx, key), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, &ErrContended{FilePath: filePath, ConverterName: converterName, Owner: currentOwner}
+		case <-time.After(acquirePollInterval):
+		}
+	}
+}
+
+func (l *SQLiteLocker) startLease(ctx context.Context, key string) Lease {
+	leaseCtx, cancel := context.WithCancel(ctx)
+	lease := &sqliteLease{locker: l, key: key, ctx: leaseCtx, cancel: cancel, stop: make(chan struct{})}
+	go lease.refreshLoop()
+	return lease
+}
+
+type sqliteLease struct {
+	locker *SQLiteLocker
+	key    string
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	once   sync.Once
+}
+
+func (l *sqliteLease) Context() context.Context { return l.ctx }
+
+func (l *sqliteLease) Release() {
+	l.once.Do(func() {
+		close(l.stop)
+		l.cancel()
+		if err := l.locker.db.ReleaseLock(l.key, l.locker.owner); err != nil {
+			log.Printf("lock: failed to release %q: %v", l.key, err)
+		}
+	})
+}
+
+func (l *sqliteLease) refreshLoop() {
+	ticker := time.NewTicker(l.locker.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			held, err := l.locker.db.RefreshLock(l.key, l.locker.owner, time.Now().Add(l.locker.ttl))
+			if err != nil {
+				log.Printf("lock: failed to refresh %q: %v", l.key, err)
+				continue
+			}
+			if !held {
+				// Another owner stole the lease after it expired without us
+				// noticing; cancel so whoever is converting under it aborts.
+				l.cancel()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// logger is the default Logger implementation, writing either
+// human-readable lines or one JSON object per line to out.
+type logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	format string
+	level  *int32 // shared with every Logger derived from this one via With, so SetLevel affects them all
+	fields []field
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+func (l *logger) Debug(msg string, kv ...interface{}) { l.log(LevelDebug, msg, kv) }
+func (l *logger) Info(msg string, kv ...interface{})  { l.log(LevelInfo, msg, kv) }
+func (l *logger) Warn(msg string, kv ...interface{})  { l.log(LevelWarn, msg, kv) }
+func (l *logger) Error(msg string, kv ...interface{}) { l.log(LevelError, msg, kv) }
+
+// With returns a derived Logger carrying an additional field on every
+// subsequent call. The receiver is left unmodified.
+func (l *logger) With(key string, value interface{}) Logger {
+	fields := make([]field, len(l.fields), len(l.fields)+1)
+	copy(fields, l.fields)
+	fields = append(fields, field{key: key, value: value})
+	return &logger{out: l.out, format: l.format, level: l.level, fields: fields}
+}
+
+// SetLevel changes the minimum level l, and every Logger already derived
+// from it via With, emits from now on, since they all share the same level
+// pointer. Safe for concurrent use.
+func (l *logger) SetLevel(level Level) {
+	atomic.StoreInt32(l.level, int32(level))
+}
+
+func (l *logger) log(level Level, msg string, kv []interface{}) {
+	if int32(level) < atomic.LoadInt32(l.level) {
+		return
+	}
+
+	fields := make([]field, 0, len(l.fields)+len(kv)/2)
+	fields = append(fields, l.fields...)
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			continue
+		}
+		fields = append(fields, field{key: key, value: kv[i+1]})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.format == "json" {
+		l.writeJSON(level, msg, fields)
+	} else {
+		l.writeConsole(level, msg, fields)
+	}
+}
+
+func (l *logger) writeJSON(level Level, msg string, fields []field) {
+	entry := make(map[string]interface{}, len(fields)+3)
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["msg"] = msg
+	for _, f := range fields {
+		entry[f.key] = f.value
+	}
+
+	_ = json.NewEncoder(l.out).Encode(entry)
+}
+
+func (l *logger) writeConsole(level Level, msg string, fields []field) {
+	var b strings.Builder
+	b.WriteString(time.Now().Format("2006-01-02T15:04:05.000"))
+	b.WriteString(" [")
+	b.WriteString(strings.ToUpper(level.String()))
+	b.WriteString("] ")
+	b.WriteString(msg)
+
+	sorted := make([]field, len(fields))
+	copy(sorted, fields)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].key < sorted[j].key })
+	for _, f := range sorted {
+		fmt.Fprintf(&b, " %s=%v", f.key, f.value)
+	}
+
+	fmt.Fprintln(l.out, b.String())
+}
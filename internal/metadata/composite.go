@@ -0,0 +1,46 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+)
+
+// CompositeCopier tries primary first and, if it fails, falls back to
+// fallback. This backs backend=auto: prefer the dependency-free native
+// writer, but don't let a source image native parsing can't handle (e.g.
+// no EXIF/XMP/ICC segments present) silently drop metadata preservation
+// when exiftool could still have handled it.
+type CompositeCopier struct {
+	primary  Copier
+	fallback Copier
+
+	used Copier // which backend actually ran, set after Copy
+}
+
+func (c *CompositeCopier) Name() string { return "auto" }
+
+func (c *CompositeCopier) Copy(ctx context.Context, srcPath, dstPath string) (Result, error) {
+	result, err := c.primary.Copy(ctx, srcPath, dstPath)
+	if err == nil {
+		c.used = c.primary
+		result.Backend = c.Name() + "(" + c.primary.Name() + ")"
+		return result, nil
+	}
+
+	fallbackResult, fallbackErr := c.fallback.Copy(ctx, srcPath, dstPath)
+	if fallbackErr != nil {
+		return Result{Backend: c.Name()}, fmt.Errorf("native failed (%v) and exiftool fallback failed: %w", err, fallbackErr)
+	}
+	c.used = c.fallback
+	fallbackResult.Backend = c.Name() + "(" + c.fallback.Name() + ")"
+	fallbackResult.Summary += fmt.Sprintf(" (native backend failed: %v)", err)
+	return fallbackResult, nil
+}
+
+func (c *CompositeCopier) VerifyDateTimeOriginal(srcPath, dstPath string) (bool, string, error) {
+	used := c.used
+	if used == nil {
+		used = c.primary
+	}
+	return used.VerifyDateTimeOriginal(srcPath, dstPath)
+}
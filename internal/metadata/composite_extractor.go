@@ -0,0 +1,26 @@
+package metadata
+
+import "fmt"
+
+// CompositeExtractor tries primary first and, if it fails, falls back to
+// fallback, mirroring CompositeCopier's backend=auto behavior but for
+// reading tags instead of writing them.
+type CompositeExtractor struct {
+	primary  Extractor
+	fallback Extractor
+}
+
+func (e *CompositeExtractor) Name() string { return "auto" }
+
+func (e *CompositeExtractor) Extract(path string) (map[string]string, error) {
+	tags, err := e.primary.Extract(path)
+	if err == nil {
+		return tags, nil
+	}
+
+	fallbackTags, fallbackErr := e.fallback.Extract(path)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("%s failed (%v) and %s fallback failed: %w", e.primary.Name(), err, e.fallback.Name(), fallbackErr)
+	}
+	return fallbackTags, nil
+}
@@ -0,0 +1,45 @@
+// Package metadata provides pluggable backends for copying EXIF/XMP/ICC
+// metadata from a source JPEG into a converted HEIC file.
+package metadata
+
+import "context"
+
+// Result describes the outcome of a metadata copy attempt, independent of
+// which Copier backend produced it.
+type Result struct {
+	Preserved bool
+	Summary   string
+	Backend   string
+}
+
+// Copier copies metadata from a source JPEG into a converted HEIC file.
+// Implementations trade off fidelity against external dependencies; see
+// ExiftoolCopier, NativeCopier, and CompositeCopier.
+type Copier interface {
+	// Name identifies the backend for logging and Result.Backend.
+	Name() string
+
+	// Copy transfers metadata from srcPath (JPEG) to dstPath (HEIC) and
+	// reports whether preservation succeeded.
+	Copy(ctx context.Context, srcPath, dstPath string) (Result, error)
+
+	// VerifyDateTimeOriginal reads DateTimeOriginal back from dstPath and
+	// compares it against the value found in srcPath, so callers can do an
+	// independent round-trip check after Copy regardless of backend.
+	VerifyDateTimeOriginal(srcPath, dstPath string) (preserved bool, detail string, err error)
+}
+
+// NewCopier builds the Copier for the given backend name: "native",
+// "exiftool", or "auto" (native first, falling back to exiftool on
+// failure). Unknown or empty values fall back to "exiftool", matching the
+// behavior before backend selection existed.
+func NewCopier(backend string) Copier {
+	switch backend {
+	case "native":
+		return &NativeCopier{}
+	case "auto":
+		return &CompositeCopier{primary: &NativeCopier{}, fallback: &ExiftoolCopier{}}
+	default:
+		return &ExiftoolCopier{}
+	}
+}
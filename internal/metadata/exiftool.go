@@ -0,0 +1,52 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ExiftoolCopier shells out to exiftool to copy all metadata tags from the
+// source JPEG to the destination HEIC. This is the original behavior of
+// Converter.Convert before backend selection existed.
+type ExiftoolCopier struct{}
+
+func (c *ExiftoolCopier) Name() string { return "exiftool" }
+
+func (c *ExiftoolCopier) Copy(ctx context.Context, srcPath, dstPath string) (Result, error) {
+	cmd := exec.CommandContext(ctx, "exiftool", "-overwrite_original", "-TagsFromFile", srcPath, "-all:all", dstPath)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return Result{Backend: c.Name()}, fmt.Errorf("exiftool failed: %w, output: %s", err, string(out))
+	}
+	return Result{Preserved: true, Summary: "metadata copied via exiftool", Backend: c.Name()}, nil
+}
+
+func (c *ExiftoolCopier) VerifyDateTimeOriginal(srcPath, dstPath string) (bool, string, error) {
+	srcVal, err := exiftoolReadDateTimeOriginal(srcPath)
+	if err != nil {
+		return false, "", err
+	}
+	dstVal, err := exiftoolReadDateTimeOriginal(dstPath)
+	if err != nil {
+		return false, "", err
+	}
+	switch {
+	case srcVal != "" && srcVal == dstVal:
+		return true, "DateTimeOriginal preserved", nil
+	case dstVal != "":
+		return false, "DateTimeOriginal written but differs", nil
+	default:
+		return false, "DateTimeOriginal not found in output", nil
+	}
+}
+
+func exiftoolReadDateTimeOriginal(path string) (string, error) {
+	cmd := exec.Command("exiftool", "-DateTimeOriginal", "-s3", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,42 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// ExiftoolExtractor reads every EXIF/IPTC/XMP/ICC/MakerNotes tag exiftool
+// knows about for a file, keyed by exiftool's own "Group1:TagName" names
+// (e.g. "ExifIFD:DateTimeOriginal", "GPS:GPSLatitude", "ICC_Profile:
+// ProfileDescription") so a tag-by-tag diff can tell a preserved EXIF
+// DateTimeOriginal apart from an unrelated tag that merely shares its short
+// name.
+type ExiftoolExtractor struct{}
+
+func (e *ExiftoolExtractor) Name() string { return "exiftool" }
+
+func (e *ExiftoolExtractor) Extract(path string) (map[string]string, error) {
+	cmd := exec.Command("exiftool", "-j", "-a", "-G1", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("exiftool failed: %w", err)
+	}
+
+	var records []map[string]interface{}
+	if err := json.Unmarshal(out, &records); err != nil {
+		return nil, fmt.Errorf("parse exiftool output: %w", err)
+	}
+	if len(records) == 0 {
+		return map[string]string{}, nil
+	}
+
+	tags := make(map[string]string, len(records[0]))
+	for key, value := range records[0] {
+		if key == "SourceFile" {
+			continue
+		}
+		tags[key] = fmt.Sprintf("%v", value)
+	}
+	return tags, nil
+}
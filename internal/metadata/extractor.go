@@ -0,0 +1,33 @@
+package metadata
+
+// Extractor reads every metadata tag it can find in a file into a flat
+// string map, independent of any Copy operation, so a caller can diff a
+// source and destination file tag-by-tag instead of relying on a single
+// preserved/not-preserved boolean.
+type Extractor interface {
+	// Name identifies the backend for logging.
+	Name() string
+
+	// Extract reads path's metadata tags. Keys are backend-specific
+	// (ExiftoolExtractor uses exiftool's "Group1:TagName" names, e.g.
+	// "ExifIFD:DateTimeOriginal"; NativeExtractor uses "EXIF:<FieldName>"),
+	// so a caller that needs one specific tag regardless of backend should
+	// match on the substring after the last ":" rather than the full key.
+	Extract(path string) (map[string]string, error)
+}
+
+// NewExtractor builds the Extractor for the given backend name: "exiftool",
+// "native", or "auto" (exiftool first, falling back to the dependency-free
+// goexif reader if exiftool isn't installed or its run fails). Unknown or
+// empty values fall back to "auto": unlike NewCopier, Extract never writes
+// anything, so there's no harm in always trying both backends.
+func NewExtractor(backend string) Extractor {
+	switch backend {
+	case "exiftool":
+		return &ExiftoolExtractor{}
+	case "native":
+		return &NativeExtractor{}
+	default:
+		return &CompositeExtractor{primary: &ExiftoolExtractor{}, fallback: &NativeExtractor{}}
+	}
+}
@@ -0,0 +1,36 @@
+package metadata
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// HeifICCProfileName runs libheif's heif-info against a HEIC/HEIF file and
+// returns the name it reports for the embedded ICC color profile, or ""
+// if none is present. exiftool can read a HEIC's ICC profile too, but its
+// description for this container is sometimes just "embedded"; this gives
+// ExtractMetadata a second opinion straight from the library that wrote
+// the file.
+func HeifICCProfileName(path string) (string, error) {
+	cmd := exec.Command("heif-info", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("heif-info failed: %w", err)
+	}
+	return parseHeifInfoICCProfile(string(out)), nil
+}
+
+// parseHeifInfoICCProfile scans heif-info's human-readable output for its
+// "color profile: <name>" line. Any other output (or no ICC profile at
+// all) is treated as "no profile found", not an error.
+func parseHeifInfoICCProfile(output string) string {
+	const prefix = "color profile:"
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(strings.ToLower(line), prefix) {
+			return strings.TrimSpace(line[len(prefix):])
+		}
+	}
+	return ""
+}
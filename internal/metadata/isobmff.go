@@ -0,0 +1,89 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// nativeBoxType is the four-character code of the trailer box NativeCopier
+// appends to a HEIC file. It deliberately isn't a real ISOBMFF box type —
+// see NativeCopier's doc comment for why it's a trailer rather than a
+// properly linked meta item.
+const nativeBoxType = "Xtra"
+
+type nativePayload struct {
+	EXIF []byte
+	XMP  []byte
+	ICC  []byte
+}
+
+// encodeNativePayload serializes the three metadata blocks into a single
+// length-prefixed buffer suitable for embedding in the trailer box.
+func encodeNativePayload(p nativePayload) []byte {
+	var buf bytes.Buffer
+	writeChunk(&buf, p.EXIF)
+	writeChunk(&buf, p.XMP)
+	writeChunk(&buf, p.ICC)
+	return buf.Bytes()
+}
+
+func writeChunk(buf *bytes.Buffer, data []byte) {
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(data)))
+	buf.Write(lenBuf[:])
+	buf.Write(data)
+}
+
+func decodeNativePayload(data []byte) (nativePayload, error) {
+	var p nativePayload
+	r := bytes.NewReader(data)
+	for _, dst := range []*[]byte{&p.EXIF, &p.XMP, &p.ICC} {
+		var lenBuf [4]byte
+		if _, err := r.Read(lenBuf[:]); err != nil {
+			return p, fmt.Errorf("read chunk length: %w", err)
+		}
+		n := binary.BigEndian.Uint32(lenBuf[:])
+		chunk := make([]byte, n)
+		if n > 0 {
+			if _, err := r.Read(chunk); err != nil {
+				return p, fmt.Errorf("read chunk: %w", err)
+			}
+		}
+		*dst = chunk
+	}
+	return p, nil
+}
+
+// appendTrailerBox appends a single ISOBMFF-shaped (4-byte size + 4-byte
+// type + payload) box to the end of heicData. Box scanners that tolerate
+// unknown trailing boxes still parse the rest of the file exactly as before.
+func appendTrailerBox(heicData []byte, payload []byte) []byte {
+	size := uint32(8 + len(payload))
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[0:4], size)
+	copy(header[4:8], nativeBoxType)
+	out := make([]byte, 0, len(heicData)+len(header)+len(payload))
+	out = append(out, heicData...)
+	out = append(out, header[:]...)
+	out = append(out, payload...)
+	return out
+}
+
+// findTrailerBox scans heicData's top-level boxes for the native trailer box
+// and returns its payload, or nil if none is present.
+func findTrailerBox(heicData []byte) ([]byte, error) {
+	pos := 0
+	for pos+8 <= len(heicData) {
+		size := binary.BigEndian.Uint32(heicData[pos : pos+4])
+		boxType := string(heicData[pos+4 : pos+8])
+		if size < 8 || pos+int(size) > len(heicData) {
+			return nil, nil
+		}
+		if boxType == nativeBoxType {
+			return heicData[pos+8 : pos+int(size)], nil
+		}
+		pos += int(size)
+	}
+	return nil, nil
+}
@@ -0,0 +1,71 @@
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// SourceMetadata holds the raw metadata blocks extracted from a JPEG's APPn
+// segments, ready to be handed to any Copier implementation.
+type SourceMetadata struct {
+	EXIF []byte // APP1 "Exif\x00\x00" payload, TIFF header onward
+	XMP  []byte // APP1 "http://ns.adobe.com/xap/1.0/\x00" payload
+	ICC  []byte // APP2 "ICC_PROFILE\x00" payload (chunked profiles are concatenated in order)
+}
+
+var (
+	exifMarker = []byte("Exif\x00\x00")
+	xmpMarker  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iccMarker  = []byte("ICC_PROFILE\x00")
+)
+
+// ExtractSourceMetadata scans a JPEG file's APPn markers for embedded EXIF,
+// XMP, and ICC profile data, stopping at the start of scan (SOS) marker.
+func ExtractSourceMetadata(jpegPath string) (*SourceMetadata, error) {
+	data, err := os.ReadFile(jpegPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("not a JPEG (missing SOI marker): %s", jpegPath)
+	}
+
+	meta := &SourceMetadata{}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			break
+		}
+		marker := data[pos+1]
+		if marker == 0xD8 || marker == 0xD9 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			pos += 2
+			continue
+		}
+		if marker == 0xDA {
+			break
+		}
+		segLen := int(data[pos+2])<<8 | int(data[pos+3])
+		if segLen < 2 || pos+2+segLen > len(data) {
+			break
+		}
+		payload := data[pos+4 : pos+2+segLen]
+
+		switch marker {
+		case 0xE1: // APP1: EXIF or XMP
+			if bytes.HasPrefix(payload, exifMarker) {
+				meta.EXIF = append([]byte(nil), payload[len(exifMarker):]...)
+			} else if bytes.HasPrefix(payload, xmpMarker) {
+				meta.XMP = append([]byte(nil), payload[len(xmpMarker):]...)
+			}
+		case 0xE2: // APP2: ICC profile, possibly split across several chunks
+			if bytes.HasPrefix(payload, iccMarker) && len(payload) > len(iccMarker)+2 {
+				// Skip the marker plus the 2-byte chunk-sequence/chunk-count pair.
+				meta.ICC = append(meta.ICC, payload[len(iccMarker)+2:]...)
+			}
+		}
+		pos += 2 + segLen
+	}
+
+	return meta, nil
+}
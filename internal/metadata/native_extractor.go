@@ -0,0 +1,46 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// NativeExtractor reads EXIF tags directly via goexif, without shelling out
+// to exiftool. It only sees the subset of tags goexif's TIFF/EXIF decoder
+// understands (no XMP, no ICC, no MakerNotes, no GPS composites beyond the
+// raw IFD values), so NewExtractor uses it as the "auto" fallback for
+// installs without exiftool, not as a full replacement for it.
+type NativeExtractor struct{}
+
+func (e *NativeExtractor) Name() string { return "native" }
+
+func (e *NativeExtractor) Extract(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	x, err := exif.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("goexif decode: %w", err)
+	}
+
+	tags := make(map[string]string)
+	x.Walk(tagCollector{tags: tags})
+	return tags, nil
+}
+
+// tagCollector implements exif.Walker, copying every decoded tag into a
+// flat string map keyed by "EXIF:<FieldName>".
+type tagCollector struct {
+	tags map[string]string
+}
+
+func (c tagCollector) Walk(name exif.FieldName, tag *tiff.Tag) error {
+	c.tags["EXIF:"+string(name)] = tag.String()
+	return nil
+}
@@ -0,0 +1,318 @@
+// Package metrics holds a small, dependency-free set of Prometheus-style
+// counters, gauges, and histograms for jpeg2heif's conversion pipeline, and
+// an encoder that renders them in the Prometheus text exposition format.
+// It exists so /metrics doesn't require adding prometheus/client_golang as
+// a dependency; the surface it needs (labeled counters/gauges
+// plus a duration histogram) is small enough to hand-roll.
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metric is implemented by Counter, Gauge, and Histogram so the package
+// registry can render all of them without a type switch per call site.
+type metric interface {
+	name() string
+	help() string
+	writeTo(sb *strings.Builder)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []metric
+)
+
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, m)
+}
+
+// labelKey joins label values into a stable map key. Label cardinality in
+// this package is always small and known ahead of time (converter name,
+// event result, watch dir, tool name), so a joined string is simpler than a
+// nested map.
+func labelKey(values []string) string {
+	return strings.Join(values, "\x1f")
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, n := range names {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		parts[i] = fmt.Sprintf(`%s="%s"`, n, escapeLabelValue(v))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeLabelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return v
+}
+
+// Counter is a monotonically increasing value, optionally partitioned by a
+// fixed set of label names (e.g. "dir", "converter").
+type Counter struct {
+	metricName string
+	metricHelp string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter creates and registers a Counter. labelNames declares the
+// label dimensions every Inc/Add call must supply values for, in order.
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{
+		metricName: name,
+		metricHelp: help,
+		labelNames: labelNames,
+		values:     make(map[string]float64),
+	}
+	register(c)
+	return c
+}
+
+// Inc increments the counter for the given label values by 1.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter for the given label values by delta.
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[labelKey(labelValues)] += delta
+}
+
+func (c *Counter) name() string { return c.metricName }
+func (c *Counter) help() string { return c.metricHelp }
+
+func (c *Counter) writeTo(sb *strings.Builder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(sb, c.metricName, c.metricHelp, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(sb, "%s%s %v\n", c.metricName, formatLabels(c.labelNames, strings.Split(key, "\x1f")), c.values[key])
+	}
+}
+
+// Gauge is a value that can go up or down, optionally partitioned by a
+// fixed set of label names (e.g. "state", "tool").
+type Gauge struct {
+	metricName string
+	metricHelp string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewGauge creates and registers a Gauge.
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{
+		metricName: name,
+		metricHelp: help,
+		labelNames: labelNames,
+		values:     make(map[string]float64),
+	}
+	register(g)
+	return g
+}
+
+// Set sets the gauge for the given label values to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[labelKey(labelValues)] = v
+}
+
+// Inc increments the gauge for the given label values by 1.
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec decrements the gauge for the given label values by 1.
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Add adds delta (which may be negative) to the gauge for the given label
+// values.
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[labelKey(labelValues)] += delta
+}
+
+func (g *Gauge) name() string { return g.metricName }
+func (g *Gauge) help() string { return g.metricHelp }
+
+func (g *Gauge) writeTo(sb *strings.Builder) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeHeader(sb, g.metricName, g.metricHelp, "gauge")
+	for _, key := range sortedKeys(g.values) {
+		fmt.Fprintf(sb, "%s%s %v\n", g.metricName, formatLabels(g.labelNames, strings.Split(key, "\x1f")), g.values[key])
+	}
+}
+
+// Histogram tracks the distribution of a value (e.g. a duration in seconds)
+// against a fixed set of cumulative upper bounds, Prometheus-style.
+type Histogram struct {
+	metricName string
+	metricHelp string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	counts map[string][]uint64
+	sums   map[string]float64
+	totals map[string]uint64
+}
+
+// NewHistogram creates and registers a Histogram with the given bucket
+// upper bounds (which need not include +Inf; it is added implicitly).
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{
+		metricName: name,
+		metricHelp: help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		counts:     make(map[string][]uint64),
+		sums:       make(map[string]float64),
+		totals:     make(map[string]uint64),
+	}
+	register(h)
+	return h
+}
+
+// Observe records v (e.g. elapsed seconds) against the histogram's buckets
+// for the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := labelKey(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts, ok := h.counts[key]
+	if !ok {
+		counts = make([]uint64, len(h.buckets))
+		h.counts[key] = counts
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			counts[i]++
+		}
+	}
+	h.sums[key] += v
+	h.totals[key]++
+}
+
+func (h *Histogram) name() string { return h.metricName }
+func (h *Histogram) help() string { return h.metricHelp }
+
+func (h *Histogram) writeTo(sb *strings.Builder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(sb, h.metricName, h.metricHelp, "histogram")
+	for _, key := range sortedKeys(h.sums) {
+		labelValues := strings.Split(key, "\x1f")
+		counts := h.counts[key]
+		for i, upper := range h.buckets {
+			bucketLabels := formatLabels(append(append([]string{}, h.labelNames...), "le"), append(append([]string{}, labelValues...), fmt.Sprintf("%v", upper)))
+			fmt.Fprintf(sb, "%s_bucket%s %d\n", h.metricName, bucketLabels, counts[i])
+		}
+		infLabels := formatLabels(append(append([]string{}, h.labelNames...), "le"), append(append([]string{}, labelValues...), "+Inf"))
+		fmt.Fprintf(sb, "%s_bucket%s %d\n", h.metricName, infLabels, h.totals[key])
+		fmt.Fprintf(sb, "%s_sum%s %v\n", h.metricName, formatLabels(h.labelNames, labelValues), h.sums[key])
+		fmt.Fprintf(sb, "%s_count%s %d\n", h.metricName, formatLabels(h.labelNames, labelValues), h.totals[key])
+	}
+}
+
+func writeHeader(sb *strings.Builder, name, help, typ string) {
+	fmt.Fprintf(sb, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(sb, "# TYPE %s %s\n", name, typ)
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch v := m.(type) {
+	case map[string]float64:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]uint64:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metadataPreservedMu guards the running totals behind MetadataPreservedRatio,
+// which (unlike a Counter) needs to report a ratio rather than a sum.
+var (
+	metadataPreservedMu    sync.Mutex
+	metadataPreservedCount uint64
+	metadataTotal          uint64
+)
+
+// RecordConversion records a single conversion attempt's outcome against
+// ConversionDuration, ConversionsTotal, and MetadataPreservedRatio. result
+// should be ResultSuccess or one of the ResultXxxFailed constants (or any
+// other converter-specific outcome label); status collapses that into a
+// coarse "success"/"failed" for consumers that don't care about the
+// specific failure stage.
+func RecordConversion(converter, result string, dur time.Duration, metadataPreserved bool) {
+	ConversionDuration.Observe(dur.Seconds(), converter, result)
+
+	status := "failed"
+	if result == ResultSuccess {
+		status = "success"
+	}
+	ConversionsTotal.Inc(converter, status)
+
+	metadataPreservedMu.Lock()
+	metadataTotal++
+	if metadataPreserved {
+		metadataPreservedCount++
+	}
+	ratio := float64(metadataPreservedCount) / float64(metadataTotal)
+	metadataPreservedMu.Unlock()
+	MetadataPreservedRatio.Set(ratio)
+}
+
+// RecordWorkflowRun records a completed WorkflowRun's outcome against
+// WorkflowRunsTotal.
+func RecordWorkflowRun(workflowName, status string) {
+	WorkflowRunsTotal.Inc(workflowName, status)
+}
+
+// Render writes every registered metric in Prometheus text exposition
+// format.
+func Render() string {
+	registryMu.Lock()
+	snapshot := make([]metric, len(registry))
+	copy(snapshot, registry)
+	registryMu.Unlock()
+
+	var sb strings.Builder
+	for _, m := range snapshot {
+		m.writeTo(&sb)
+	}
+	return sb.String()
+}
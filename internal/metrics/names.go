@@ -0,0 +1,118 @@
+package metrics
+
+// defaultDurationBuckets covers sub-second hashing up through multi-minute
+// conversions of large originals, in seconds.
+var defaultDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Conversion result labels for ConversionDuration, classifying which stage
+// of JPEG2HEICConverter.Convert an outcome came from.
+const (
+	ResultSuccess                 = "success"
+	ResultHeifEncFailed           = "heif_enc_failed"
+	ResultMetadataInjectionFailed = "metadata_injection_failed"
+	ResultCopyFailed              = "copy_failed"
+)
+
+// The metrics below are the process-wide instruments read by the /metrics
+// handler (see api.Server.Start). They are package-level, since a process has exactly one set of
+// counters regardless of how many Worker/Watcher/Server instances exist.
+var (
+	FilesDiscovered = NewCounter(
+		"jpeg2heif_files_discovered_total",
+		"Total number of files the watcher has queued for conversion, by watch directory.",
+		"dir",
+	)
+
+	ConversionDuration = NewHistogram(
+		"jpeg2heif_conversion_duration_seconds",
+		"Time spent converting a single file, by converter and outcome.",
+		defaultDurationBuckets,
+		"converter", "result",
+	)
+
+	ConversionBytesIn = NewCounter(
+		"jpeg2heif_conversion_bytes_in_total",
+		"Total bytes read from source files during conversion, by converter.",
+		"converter",
+	)
+
+	ConversionBytesOut = NewCounter(
+		"jpeg2heif_conversion_bytes_out_total",
+		"Total bytes written to converted output files, by converter.",
+		"converter",
+	)
+
+	QueueDepth = NewGauge(
+		"jpeg2heif_queue_depth",
+		"Number of tasks currently buffered in the worker pool's queue.",
+	)
+
+	WorkerBusy = NewGauge(
+		"jpeg2heif_worker_busy",
+		"Number of worker goroutines currently processing a task.",
+	)
+
+	RebuildJobs = NewGauge(
+		"jpeg2heif_rebuild_jobs",
+		"Number of rebuild-index jobs currently in each state.",
+		"state",
+	)
+
+	ExternalToolMissing = NewGauge(
+		"jpeg2heif_external_tool_missing",
+		"1 if a required external tool was not found on PATH at startup, 0 otherwise.",
+		"tool",
+	)
+
+	HashDuration = NewHistogram(
+		"jpeg2heif_hash_duration_seconds",
+		"Time spent hashing a file's content for the index, by algorithm and chunk size.",
+		defaultDurationBuckets,
+		"algorithm", "chunk_size",
+	)
+
+	ConversionsTotal = NewCounter(
+		"jpeg2heif_conversions_total",
+		"Total number of conversion attempts, by converter and coarse outcome (success or failed).",
+		"converter", "status",
+	)
+
+	WorkflowRunsTotal = NewCounter(
+		"jpeg2heif_workflow_runs_total",
+		"Total number of workflow runs, by workflow name and outcome.",
+		"workflow", "status",
+	)
+
+	WorkflowStepDuration = NewHistogram(
+		"jpeg2heif_workflow_step_duration_seconds",
+		"Time spent executing a single workflow step, by workflow name and step name.",
+		defaultDurationBuckets,
+		"workflow", "step",
+	)
+
+	PendingFiles = NewGauge(
+		"jpeg2heif_pending_files",
+		"Number of files in the index currently awaiting conversion.",
+	)
+
+	ProcessingFiles = NewGauge(
+		"jpeg2heif_processing_files",
+		"Number of files in the index currently being converted.",
+	)
+
+	MetadataPreservedRatio = NewGauge(
+		"jpeg2heif_metadata_preserved_ratio",
+		"Fraction of recorded conversions (since process start) that preserved source metadata, between 0 and 1.",
+	)
+
+	ConverterEnabled = NewGauge(
+		"jpeg2heif_converter_enabled",
+		"1 if the named converter is currently enabled, 0 otherwise.",
+		"converter",
+	)
+
+	WatchedDirs = NewGauge(
+		"jpeg2heif_watched_dirs",
+		"Number of directories currently registered with the watcher's underlying fsnotify watcher (always 0 in poll mode), for comparison against fs.inotify.max_user_watches.",
+	)
+)
@@ -0,0 +1,31 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// JSONReporter emits one JSON object per line (ndjson) for every event and
+// stats snapshot, so a batch conversion can be piped into other tools.
+type JSONReporter struct {
+	enc *json.Encoder
+	mu  sync.Mutex
+}
+
+// NewJSONReporter creates a reporter that writes newline-delimited JSON to out.
+func NewJSONReporter(out io.Writer) *JSONReporter {
+	return &JSONReporter{enc: json.NewEncoder(out)}
+}
+
+func (r *JSONReporter) Report(ev Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(ev)
+}
+
+func (r *JSONReporter) ReportStats(s Stats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(s)
+}
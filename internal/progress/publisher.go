@@ -0,0 +1,167 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// JobEventType identifies the kind of SSE-streamable update published for a
+// single job (a rebuild-index run or an in-progress conversion task), as
+// opposed to EventType (reporter.go), which covers the worker pool's own
+// lifecycle events fed to a Reporter.
+type JobEventType string
+
+const (
+	JobEventProgress    JobEventType = "progress"
+	JobEventLog         JobEventType = "log"
+	JobEventFileDone    JobEventType = "file_done"
+	JobEventStateChange JobEventType = "state_change"
+)
+
+// JobEvent is a single update for one job, suitable for JSON-encoding as an
+// SSE "data:" payload. ID is assigned by the Publisher when the event is
+// published and is also used as the SSE event ID, so clients can resume
+// with a Last-Event-ID header after a reconnect.
+type JobEvent struct {
+	ID        int64        `json:"id"`
+	Type      JobEventType `json:"type"`
+	Processed int          `json:"processed,omitempty"`
+	Total     int          `json:"total,omitempty"`
+	Percent   float64      `json:"percent,omitempty"`
+	Path      string       `json:"path,omitempty"`
+	Message   string       `json:"message,omitempty"`
+	State     string       `json:"state,omitempty"`
+	Timestamp time.Time    `json:"timestamp"`
+}
+
+// Publisher fans JobEvents for a single job out to every subscriber
+// currently attached, and buffers the most recent events so a client that
+// subscribes (or reconnects) after the job started can replay what it
+// missed instead of starting blind.
+type Publisher interface {
+	Publish(ev JobEvent)
+	// Subscribe attaches a new listener and returns a channel of events
+	// with ID greater than lastEventID (0 replays everything buffered),
+	// plus an unsubscribe func the caller must call when done listening.
+	Subscribe(lastEventID int64) (events <-chan JobEvent, unsubscribe func())
+}
+
+// NopPublisher discards every event and returns an already-closed
+// subscription. It's a safe default for callers that have no registry to
+// wire a real Publisher in from (e.g. a CLI invocation with no API server).
+type NopPublisher struct{}
+
+func (NopPublisher) Publish(JobEvent) {}
+
+func (NopPublisher) Subscribe(int64) (<-chan JobEvent, func()) {
+	ch := make(chan JobEvent)
+	close(ch)
+	return ch, func() {}
+}
+
+// jobPublisher is the default Publisher implementation: an in-memory ring
+// buffer of the most recent events plus a set of live subscriber channels.
+type jobPublisher struct {
+	mu          sync.Mutex
+	nextID      int64
+	buffer      []JobEvent
+	bufferSize  int
+	subscribers map[int]chan JobEvent
+	nextSubID   int
+}
+
+func newJobPublisher(bufferSize int) *jobPublisher {
+	return &jobPublisher{bufferSize: bufferSize, subscribers: map[int]chan JobEvent{}}
+}
+
+func (p *jobPublisher) Publish(ev JobEvent) {
+	p.mu.Lock()
+	p.nextID++
+	ev.ID = p.nextID
+	p.buffer = append(p.buffer, ev)
+	if len(p.buffer) > p.bufferSize {
+		p.buffer = p.buffer[len(p.buffer)-p.bufferSize:]
+	}
+	subs := make([]chan JobEvent, 0, len(p.subscribers))
+	for _, ch := range p.subscribers {
+		subs = append(subs, ch)
+	}
+	p.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- ev:
+		default:
+			// Slow subscriber; drop rather than block the publisher.
+		}
+	}
+}
+
+func (p *jobPublisher) Subscribe(lastEventID int64) (<-chan JobEvent, func()) {
+	p.mu.Lock()
+
+	// Replay buffered events synchronously, before the channel is
+	// registered for live delivery, so replayed and live events can never
+	// arrive out of order on the same channel.
+	replay := make([]JobEvent, 0, len(p.buffer))
+	for _, ev := range p.buffer {
+		if ev.ID > lastEventID {
+			replay = append(replay, ev)
+		}
+	}
+	ch := make(chan JobEvent, len(replay)+64)
+	for _, ev := range replay {
+		ch <- ev
+	}
+
+	id := p.nextSubID
+	p.nextSubID++
+	p.subscribers[id] = ch
+	p.mu.Unlock()
+
+	unsubscribe := func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if _, ok := p.subscribers[id]; ok {
+			delete(p.subscribers, id)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Registry is a per-job registry of Publishers, keyed by job ID, so
+// multiple callers (the handler that starts a job and the SSE handler a
+// client later subscribes through) can reach the same Publisher without a
+// value needing to be threaded through every layer in between.
+type Registry struct {
+	mu         sync.Mutex
+	publishers map[string]*jobPublisher
+	bufferSize int
+}
+
+// NewRegistry creates a Registry whose Publishers each buffer the last
+// bufferSize events for replay to late subscribers.
+func NewRegistry(bufferSize int) *Registry {
+	return &Registry{publishers: map[string]*jobPublisher{}, bufferSize: bufferSize}
+}
+
+// Get returns the Publisher for jobID, creating it on first use.
+func (r *Registry) Get(jobID string) Publisher {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.publishers[jobID]
+	if !ok {
+		p = newJobPublisher(r.bufferSize)
+		r.publishers[jobID] = p
+	}
+	return p
+}
+
+// Remove drops jobID's Publisher, freeing its event buffer. Safe to call
+// even if no Publisher was ever created for jobID.
+func (r *Registry) Remove(jobID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.publishers, jobID)
+}
@@ -0,0 +1,58 @@
+// Package progress provides a typed event bus for reporting batch
+// conversion progress, replacing ad-hoc log.Printf calls in the worker
+// pool with structured events that can be rendered to a terminal or
+// emitted as newline-delimited JSON for piping into other tools.
+package progress
+
+import "time"
+
+// EventType identifies the kind of lifecycle event being reported.
+type EventType string
+
+const (
+	EventTaskQueued       EventType = "task_queued"
+	EventTaskStarted      EventType = "task_started"
+	EventHashProgress     EventType = "hash_progress"
+	EventConvertStdout    EventType = "convert_stdout"
+	EventMetadataVerified EventType = "metadata_verified"
+	EventCacheHit         EventType = "cache_hit"
+	EventTaskFinished     EventType = "task_finished"
+)
+
+// Event is a single progress update emitted during a batch conversion run.
+type Event struct {
+	Type       EventType `json:"type"`
+	WorkerID   int       `json:"worker_id,omitempty"`
+	FilePath   string    `json:"file_path,omitempty"`
+	Converter  string    `json:"converter,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Stats is a periodic aggregate snapshot of batch throughput.
+type Stats struct {
+	FilesDone   int64         `json:"files_done"`
+	FilesFailed int64         `json:"files_failed"`
+	BytesDone   int64         `json:"bytes_done"`
+	FilesPerSec float64       `json:"files_per_sec"`
+	BytesPerSec float64       `json:"bytes_per_sec"`
+	ETA         time.Duration `json:"eta"`
+	Timestamp   time.Time     `json:"timestamp"`
+}
+
+// Reporter receives progress events and periodic stats from the worker
+// pool. Implementations must be safe for concurrent use, since events are
+// published from multiple worker goroutines.
+type Reporter interface {
+	Report(ev Event)
+	ReportStats(s Stats)
+}
+
+// NopReporter discards every event. It is the default when no reporter is
+// configured, so existing callers keep working unchanged.
+type NopReporter struct{}
+
+func (NopReporter) Report(Event)      {}
+func (NopReporter) ReportStats(Stats) {}
@@ -0,0 +1,87 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// TerminalReporter renders events as single-line, human-readable updates,
+// writing one new line to out per event as it arrives. It keeps each active
+// worker's latest status line in lines (in order), but never redraws or
+// overwrites earlier output, so lines from concurrent workers interleave in
+// arrival order.
+type TerminalReporter struct {
+	out io.Writer
+	mu  sync.Mutex
+
+	lines map[int]string // worker ID -> last rendered status line
+	order []int          // stable render order for active workers
+}
+
+// NewTerminalReporter creates a reporter that writes human-readable status
+// lines to out.
+func NewTerminalReporter(out io.Writer) *TerminalReporter {
+	return &TerminalReporter{
+		out:   out,
+		lines: make(map[int]string),
+	}
+}
+
+func (r *TerminalReporter) Report(ev Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	line := r.renderLine(ev)
+
+	switch ev.Type {
+	case EventTaskFinished:
+		delete(r.lines, ev.WorkerID)
+		r.removeFromOrder(ev.WorkerID)
+	default:
+		if _, ok := r.lines[ev.WorkerID]; !ok {
+			r.order = append(r.order, ev.WorkerID)
+		}
+		r.lines[ev.WorkerID] = line
+	}
+
+	fmt.Fprintln(r.out, line)
+}
+
+func (r *TerminalReporter) renderLine(ev Event) string {
+	switch ev.Type {
+	case EventTaskQueued:
+		return fmt.Sprintf("[queued]   %s", ev.FilePath)
+	case EventTaskStarted:
+		return fmt.Sprintf("[worker %d] %-10s %s", ev.WorkerID, "start", ev.FilePath)
+	case EventHashProgress:
+		return fmt.Sprintf("[worker %d] %-10s %s: %s", ev.WorkerID, "hashing", ev.FilePath, ev.Message)
+	case EventConvertStdout:
+		return fmt.Sprintf("[worker %d] %-10s %s", ev.WorkerID, "convert", ev.Message)
+	case EventMetadataVerified:
+		return fmt.Sprintf("[worker %d] %-10s %s: %s", ev.WorkerID, "metadata", ev.FilePath, ev.Message)
+	case EventCacheHit:
+		return fmt.Sprintf("[worker %d] %-10s %s: %s", ev.WorkerID, "cache-hit", ev.FilePath, ev.Message)
+	case EventTaskFinished:
+		return fmt.Sprintf("[worker %d] %-10s %s (%s, %dms)", ev.WorkerID, "done", ev.FilePath, ev.Status, ev.DurationMs)
+	default:
+		return fmt.Sprintf("[worker %d] %s: %s", ev.WorkerID, ev.Type, ev.Message)
+	}
+}
+
+func (r *TerminalReporter) removeFromOrder(workerID int) {
+	for i, id := range r.order {
+		if id == workerID {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *TerminalReporter) ReportStats(s Stats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.out, "[stats] done=%d failed=%d %.1f files/s %.1f KB/s eta=%s\n",
+		s.FilesDone, s.FilesFailed, s.FilesPerSec, s.BytesPerSec/1024, s.ETA.Round(time.Second))
+}
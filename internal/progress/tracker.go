@@ -0,0 +1,100 @@
+package progress
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tracker accumulates per-file throughput counters and periodically
+// publishes aggregate Stats (files/sec, bytes/sec, ETA) to a Reporter.
+type Tracker struct {
+	reporter Reporter
+
+	start       time.Time
+	filesDone   int64
+	filesFailed int64
+	bytesDone   int64
+
+	totalFiles int64 // expected total, for ETA; 0 if unknown
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+// NewTracker creates a tracker that reports aggregate stats to reporter
+// every interval until Stop is called. totalFiles may be 0 if the batch
+// size isn't known up front, in which case ETA is always zero.
+func NewTracker(reporter Reporter, totalFiles int64, interval time.Duration) *Tracker {
+	t := &Tracker{
+		reporter:   reporter,
+		start:      time.Now(),
+		totalFiles: totalFiles,
+		stopCh:     make(chan struct{}),
+	}
+	if interval > 0 {
+		go t.run(interval)
+	}
+	return t
+}
+
+func (t *Tracker) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case <-ticker.C:
+			t.reporter.ReportStats(t.snapshot())
+		}
+	}
+}
+
+// RecordSuccess records a completed file conversion of the given size.
+func (t *Tracker) RecordSuccess(bytes int64) {
+	atomic.AddInt64(&t.filesDone, 1)
+	atomic.AddInt64(&t.bytesDone, bytes)
+}
+
+// RecordFailure records a failed file conversion.
+func (t *Tracker) RecordFailure() {
+	atomic.AddInt64(&t.filesFailed, 1)
+}
+
+func (t *Tracker) snapshot() Stats {
+	elapsed := time.Since(t.start).Seconds()
+	done := atomic.LoadInt64(&t.filesDone)
+	failed := atomic.LoadInt64(&t.filesFailed)
+	bytesDone := atomic.LoadInt64(&t.bytesDone)
+
+	var filesPerSec, bytesPerSec float64
+	if elapsed > 0 {
+		filesPerSec = float64(done) / elapsed
+		bytesPerSec = float64(bytesDone) / elapsed
+	}
+
+	var eta time.Duration
+	if t.totalFiles > 0 && filesPerSec > 0 {
+		remaining := t.totalFiles - done - failed
+		if remaining > 0 {
+			eta = time.Duration(float64(remaining)/filesPerSec) * time.Second
+		}
+	}
+
+	return Stats{
+		FilesDone:   done,
+		FilesFailed: failed,
+		BytesDone:   bytesDone,
+		FilesPerSec: filesPerSec,
+		BytesPerSec: bytesPerSec,
+		ETA:         eta,
+		Timestamp:   time.Now(),
+	}
+}
+
+// Stop stops the periodic reporting goroutine and emits a final snapshot.
+func (t *Tracker) Stop() {
+	t.stopOnce.Do(func() { close(t.stopCh) })
+	t.reporter.ReportStats(t.snapshot())
+}
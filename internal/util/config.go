@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
@@ -8,38 +9,221 @@ import (
 	"time"
 )
 
-// Config holds application configuration
+// Config holds application configuration, resolved in three layers of
+// increasing precedence: built-in defaults, an optional YAML file (see
+// LoadConfig and ConfigManager), and environment variables. A field left
+// unset at one layer keeps whatever the layer below it resolved to.
 type Config struct {
 	WatchDirs              []string
 	DBPath                 string
 	HTTPPort               int
 	LogLevel               string
+	LogFormat              string // console or json
 	PollInterval           time.Duration
 	MetadataStabilityDelay time.Duration
+	WatcherMode            string   // auto, native, or poll; see watcher.Mode. auto (the default) falls back to poll if fsnotify isn't usable
+	WatchExtensions        []string // lowercase extensions (with leading "."), e.g. ".jpg"; empty uses watcher's built-in default list
+	WatchIgnoreHidden      bool     // if true (the default), directories and files whose name starts with "." are never watched, scanned, or queued
+	WatchIgnoreGlobs       []string // filepath.Match patterns, matched against both a directory/file's base name and its full path, e.g. "node_modules" or "@eaDir"; see watcher.FilterConfig
 	MaxWorkers             int
 	ConvertQuality         int
 	PreserveMetadata       bool
 	MD5ChunkSize           int
+	HashAlgorithm          string // md5, sha256, blake3, or xxh3; see util.NewFileHasher
+	RebuildJobTTL          time.Duration
+	WebhookPollInterval    time.Duration
+	EnablePprof            bool
+	CacheEnabled           bool
+	CacheDir               string
+	CacheMaxSizeMB         int
+	BlobStoreEnabled       bool   // if true, workflow outputs are split into content-defined chunks and deduplicated (see internal/blobstore) instead of copied whole
+	BlobStoreDir           string // root directory blobstore.Store writes chunks under, when BlobStoreEnabled
+	ClusterNodeID          string        // this node's name, as gossiped to peers; required for cluster sync to be useful
+	ClusterPeers           []string      // "name=url" entries, e.g. "node-b=http://node-b:8080"; empty disables cluster sync
+	ClusterAuthToken       string        // shared secret peers present via X-Cluster-Token; empty accepts unauthenticated cluster requests
+	ClusterPollInterval    time.Duration // how often to gossip outputs to, and reconcile deltas from, each peer
+	MetricsListenPort      int           // if nonzero, serve GET /metrics on this separate port instead of the main HTTP port, so scraping doesn't require exposing the whole API
+	LockLeaseTTL           time.Duration // how long a distributed conversion lease survives without a refresh; only used when cluster sync is enabled (see internal/lock.SQLiteLocker)
+	ShutdownGracePeriod    time.Duration // how long a SIGINT/SIGTERM handler waits for the worker pool to finish whatever it's currently converting before giving up on a clean drain; see Worker.StopWithTimeout
+
+	WorkflowConcurrency int           // max workflow runs executed at once; see api.Server.SetWorkflowConcurrency
+	WorkflowStepTimeout time.Duration // default per-step timeout applied when a YAML step doesn't set its own Timeout; see workflow.ExecutionContext.DefaultStepTimeout; 0 leaves such steps unbounded
+
+	PruneEnabled                bool          // if true, run db.Prune on PruneInterval in the background (see db.StartPruneScheduler)
+	PruneInterval               time.Duration // how often the background prune scheduler runs
+	PruneTaskHistoryMaxAge      time.Duration // db.PruneOptions.TaskHistoryMaxAge
+	PruneTaskHistoryMaxRows     int           // db.PruneOptions.TaskHistoryMaxRows
+	PruneWorkflowRunMaxAge      time.Duration // db.PruneOptions.WorkflowRunMaxAge
+	PruneWorkflowRunMaxRows     int           // db.PruneOptions.WorkflowRunMaxRows
+	PruneWorkflowVersionMaxAge  time.Duration // db.PruneOptions.WorkflowVersionMaxAge
+	PruneWorkflowVersionMaxRows int           // db.PruneOptions.WorkflowVersionMaxRows
+	PruneKeepFailedLonger       bool          // db.PruneOptions.KeepFailedLonger
+	PruneVacuumReclaimPages     int           // db.PruneOptions.VacuumReclaimThreshold, in pages; 0 disables VACUUM
 }
 
-// LoadConfig loads configuration from environment variables
-func LoadConfig() *Config {
-	cfg := &Config{
-		WatchDirs:              parseWatchDirs(getEnv("WATCH_DIRS", "/data/watch")),
-		DBPath:                 getEnv("DB_PATH", "/data/jpeg2heif.db"),
-		HTTPPort:               getEnvInt("HTTP_PORT", 8080),
-		LogLevel:               getEnv("LOG_LEVEL", "info"),
-		PollInterval:           getEnvDuration("POLL_INTERVAL", 300*time.Second),
-		MetadataStabilityDelay: getEnvDuration("METADATA_STABILITY_DELAY", 5*time.Second),
-		MaxWorkers:             getEnvInt("MAX_WORKERS", 4),
-		ConvertQuality:         getEnvInt("CONVERT_QUALITY", 85),
-		PreserveMetadata:       getEnvBool("PRESERVE_METADATA", true),
-		MD5ChunkSize:           getEnvInt("MD5_CHUNK_SIZE", 8192),
+// LoadConfig resolves a Config from, in increasing precedence: built-in
+// defaults, the YAML file at configPath (or CONFIG_PATH, if configPath is
+// empty), and environment variables. A file that doesn't exist or fails to
+// parse is logged as a warning and skipped, falling back to defaults for
+// every field it would have set; a later call to ConfigManager.Reload
+// re-reads the same file and can recover once it's fixed.
+func LoadConfig(configPath string) *Config {
+	cfg := defaultConfig()
+
+	if configPath == "" {
+		configPath = os.Getenv("CONFIG_PATH")
 	}
+	if configPath != "" {
+		if err := loadConfigFile(configPath, cfg); err != nil {
+			log.Printf("Warning: failed to load config file %s: %v", configPath, err)
+		}
+	}
+
+	applyEnvOverrides(cfg)
 
 	return cfg
 }
 
+// ResolveConfigPath applies the same precedence LoadConfig uses to locate
+// its config file (the explicit path, if any, else CONFIG_PATH) without
+// loading it, so a caller constructing a ConfigManager can pass the same
+// path LoadConfig itself resolved.
+func ResolveConfigPath(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return os.Getenv("CONFIG_PATH")
+}
+
+// defaultConfig returns a Config populated with the application's built-in
+// defaults, before any config file or environment variable is considered.
+func defaultConfig() *Config {
+	return &Config{
+		WatchDirs:              []string{"/data/watch"},
+		DBPath:                 "/data/jpeg2heif.db",
+		HTTPPort:               8080,
+		LogLevel:               "info",
+		LogFormat:              "console",
+		PollInterval:           300 * time.Second,
+		MetadataStabilityDelay: 5 * time.Second,
+		WatcherMode:            "auto",
+		WatchIgnoreHidden:      true,
+		WatchIgnoreGlobs:       []string{"node_modules", ".git", "@eaDir"},
+		MaxWorkers:             4,
+		ConvertQuality:         85,
+		PreserveMetadata:       true,
+		MD5ChunkSize:           8192,
+		HashAlgorithm:          "md5",
+		RebuildJobTTL:          10 * time.Minute,
+		WebhookPollInterval:    5 * time.Second,
+		EnablePprof:            false,
+		CacheEnabled:           true,
+		CacheDir:               "/data/workflow-cache",
+		CacheMaxSizeMB:         2048,
+		BlobStoreEnabled:       false,
+		BlobStoreDir:           "/data/blob-store",
+		ClusterNodeID:          "",
+		ClusterPeers:           []string{},
+		ClusterAuthToken:       "",
+		ClusterPollInterval:    30 * time.Second,
+		MetricsListenPort:      0,
+		LockLeaseTTL:           2 * time.Minute,
+		ShutdownGracePeriod:    30 * time.Second,
+
+		WorkflowConcurrency: 4,
+		WorkflowStepTimeout: 0,
+
+		PruneEnabled:                false,
+		PruneInterval:               1 * time.Hour,
+		PruneTaskHistoryMaxAge:      30 * 24 * time.Hour,
+		PruneTaskHistoryMaxRows:     100000,
+		PruneWorkflowRunMaxAge:      30 * 24 * time.Hour,
+		PruneWorkflowRunMaxRows:     100000,
+		PruneWorkflowVersionMaxAge:  90 * 24 * time.Hour,
+		PruneWorkflowVersionMaxRows: 1000,
+		PruneKeepFailedLonger:       true,
+		PruneVacuumReclaimPages:     10000,
+	}
+}
+
+// applyEnvOverrides overwrites each field of cfg with its environment
+// variable, if that variable is set, leaving cfg's existing value (already
+// resolved from defaults and/or a config file) as the fallback. This keeps
+// environment variables the highest-precedence layer regardless of what
+// loaded cfg beforehand.
+func applyEnvOverrides(cfg *Config) {
+	applyEnvStringList("WATCH_DIRS", &cfg.WatchDirs)
+	cfg.DBPath = getEnv("DB_PATH", cfg.DBPath)
+	cfg.HTTPPort = getEnvInt("HTTP_PORT", cfg.HTTPPort)
+	cfg.LogLevel = getEnv("LOG_LEVEL", cfg.LogLevel)
+	cfg.LogFormat = getEnv("LOG_FORMAT", cfg.LogFormat)
+	cfg.PollInterval = getEnvDuration("POLL_INTERVAL", cfg.PollInterval)
+	cfg.MetadataStabilityDelay = getEnvDuration("METADATA_STABILITY_DELAY", cfg.MetadataStabilityDelay)
+	cfg.WatcherMode = getEnv("WATCHER_MODE", cfg.WatcherMode)
+	applyEnvStringList("WATCH_EXTENSIONS", &cfg.WatchExtensions)
+	cfg.WatchIgnoreHidden = getEnvBool("WATCH_IGNORE_HIDDEN", cfg.WatchIgnoreHidden)
+	applyEnvStringList("WATCH_IGNORE_GLOBS", &cfg.WatchIgnoreGlobs)
+	cfg.MaxWorkers = getEnvInt("MAX_WORKERS", cfg.MaxWorkers)
+	cfg.ConvertQuality = getEnvInt("CONVERT_QUALITY", cfg.ConvertQuality)
+	cfg.PreserveMetadata = getEnvBool("PRESERVE_METADATA", cfg.PreserveMetadata)
+	cfg.MD5ChunkSize = getEnvInt("MD5_CHUNK_SIZE", cfg.MD5ChunkSize)
+	cfg.HashAlgorithm = getEnv("HASH_ALGORITHM", cfg.HashAlgorithm)
+	cfg.RebuildJobTTL = getEnvDuration("REBUILD_JOB_TTL", cfg.RebuildJobTTL)
+	cfg.WebhookPollInterval = getEnvDuration("WEBHOOK_POLL_INTERVAL", cfg.WebhookPollInterval)
+	cfg.EnablePprof = getEnvBool("ENABLE_PPROF", cfg.EnablePprof)
+	cfg.CacheEnabled = getEnvBool("CACHE_ENABLED", cfg.CacheEnabled)
+	cfg.CacheDir = getEnv("CACHE_DIR", cfg.CacheDir)
+	cfg.CacheMaxSizeMB = getEnvInt("CACHE_MAX_SIZE_MB", cfg.CacheMaxSizeMB)
+	cfg.BlobStoreEnabled = getEnvBool("BLOB_STORE_ENABLED", cfg.BlobStoreEnabled)
+	cfg.BlobStoreDir = getEnv("BLOB_STORE_DIR", cfg.BlobStoreDir)
+	cfg.ClusterNodeID = getEnv("CLUSTER_NODE_ID", cfg.ClusterNodeID)
+	applyEnvStringList("CLUSTER_PEERS", &cfg.ClusterPeers)
+	cfg.ClusterAuthToken = getEnv("CLUSTER_AUTH_TOKEN", cfg.ClusterAuthToken)
+	cfg.ClusterPollInterval = getEnvDuration("CLUSTER_POLL_INTERVAL", cfg.ClusterPollInterval)
+	cfg.MetricsListenPort = getEnvInt("METRICS_LISTEN_PORT", cfg.MetricsListenPort)
+	cfg.LockLeaseTTL = getEnvDuration("LOCK_LEASE_TTL", cfg.LockLeaseTTL)
+	cfg.ShutdownGracePeriod = getEnvDuration("SHUTDOWN_GRACE_PERIOD", cfg.ShutdownGracePeriod)
+
+	cfg.WorkflowConcurrency = getEnvInt("WORKFLOW_CONCURRENCY", cfg.WorkflowConcurrency)
+	cfg.WorkflowStepTimeout = getEnvDuration("WORKFLOW_STEP_TIMEOUT", cfg.WorkflowStepTimeout)
+
+	cfg.PruneEnabled = getEnvBool("PRUNE_ENABLED", cfg.PruneEnabled)
+	cfg.PruneInterval = getEnvDuration("PRUNE_INTERVAL", cfg.PruneInterval)
+	cfg.PruneTaskHistoryMaxAge = getEnvDuration("PRUNE_TASK_HISTORY_MAX_AGE", cfg.PruneTaskHistoryMaxAge)
+	cfg.PruneTaskHistoryMaxRows = getEnvInt("PRUNE_TASK_HISTORY_MAX_ROWS", cfg.PruneTaskHistoryMaxRows)
+	cfg.PruneWorkflowRunMaxAge = getEnvDuration("PRUNE_WORKFLOW_RUN_MAX_AGE", cfg.PruneWorkflowRunMaxAge)
+	cfg.PruneWorkflowRunMaxRows = getEnvInt("PRUNE_WORKFLOW_RUN_MAX_ROWS", cfg.PruneWorkflowRunMaxRows)
+	cfg.PruneWorkflowVersionMaxAge = getEnvDuration("PRUNE_WORKFLOW_VERSION_MAX_AGE", cfg.PruneWorkflowVersionMaxAge)
+	cfg.PruneWorkflowVersionMaxRows = getEnvInt("PRUNE_WORKFLOW_VERSION_MAX_ROWS", cfg.PruneWorkflowVersionMaxRows)
+	cfg.PruneKeepFailedLonger = getEnvBool("PRUNE_KEEP_FAILED_LONGER", cfg.PruneKeepFailedLonger)
+	cfg.PruneVacuumReclaimPages = getEnvInt("PRUNE_VACUUM_RECLAIM_PAGES", cfg.PruneVacuumReclaimPages)
+}
+
+// Validate rejects field combinations that would make the application
+// unable to start or behave nonsensically. Called by LoadConfig's callers
+// indirectly is not required (a zero-value-safe Config always starts), but
+// is required by ConfigManager before an on-disk edit is allowed to
+// replace the currently-active Config, so a typo in the file can't take a
+// running process down.
+func (c *Config) Validate() error {
+	if c.DBPath == "" {
+		return fmt.Errorf("db_path must not be empty")
+	}
+	if c.MaxWorkers < 1 {
+		return fmt.Errorf("max_workers must be at least 1, got %d", c.MaxWorkers)
+	}
+	if c.ConvertQuality < 1 || c.ConvertQuality > 100 {
+		return fmt.Errorf("convert_quality must be between 1 and 100, got %d", c.ConvertQuality)
+	}
+	if c.HTTPPort < 1 || c.HTTPPort > 65535 {
+		return fmt.Errorf("http_port must be between 1 and 65535, got %d", c.HTTPPort)
+	}
+	if c.WorkflowConcurrency < 1 {
+		return fmt.Errorf("workflow_concurrency must be at least 1, got %d", c.WorkflowConcurrency)
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -77,6 +261,15 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// applyEnvStringList overwrites *dst with key's comma-separated value, if
+// key is set in the environment; otherwise *dst (already resolved from
+// defaults and/or a config file) is left as-is.
+func applyEnvStringList(key string, dst *[]string) {
+	if v := os.Getenv(key); v != "" {
+		*dst = parseWatchDirs(v)
+	}
+}
+
 func parseWatchDirs(dirs string) []string {
 	parts := strings.Split(dirs, ",")
 	result := []string{}
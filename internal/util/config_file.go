@@ -0,0 +1,236 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile mirrors Config for YAML decoding. Duration fields are decoded
+// as human-readable strings (e.g. "5m", "30s") rather than Config's
+// time.Duration, and every field is a pointer (or, for slices, left nil),
+// so a key absent from the document is distinguishable from one explicitly
+// set to its zero value and loadConfigFile can leave Config's existing
+// value (already resolved from defaults) untouched.
+type configFile struct {
+	WatchDirs              []string `yaml:"watch_dirs"`
+	DBPath                 *string  `yaml:"db_path"`
+	HTTPPort               *int     `yaml:"http_port"`
+	LogLevel               *string  `yaml:"log_level"`
+	LogFormat              *string  `yaml:"log_format"`
+	PollInterval           *string  `yaml:"poll_interval"`
+	MetadataStabilityDelay *string  `yaml:"metadata_stability_delay"`
+	WatcherMode            *string  `yaml:"watcher_mode"`
+	WatchExtensions        []string `yaml:"watch_extensions"`
+	WatchIgnoreHidden      *bool    `yaml:"watch_ignore_hidden"`
+	WatchIgnoreGlobs       []string `yaml:"watch_ignore_globs"`
+	MaxWorkers             *int     `yaml:"max_workers"`
+	ConvertQuality         *int     `yaml:"convert_quality"`
+	PreserveMetadata       *bool    `yaml:"preserve_metadata"`
+	MD5ChunkSize           *int     `yaml:"md5_chunk_size"`
+	HashAlgorithm          *string  `yaml:"hash_algorithm"`
+	RebuildJobTTL          *string  `yaml:"rebuild_job_ttl"`
+	WebhookPollInterval    *string  `yaml:"webhook_poll_interval"`
+	EnablePprof            *bool    `yaml:"enable_pprof"`
+	CacheEnabled           *bool    `yaml:"cache_enabled"`
+	CacheDir               *string  `yaml:"cache_dir"`
+	CacheMaxSizeMB         *int     `yaml:"cache_max_size_mb"`
+	BlobStoreEnabled       *bool    `yaml:"blob_store_enabled"`
+	BlobStoreDir           *string  `yaml:"blob_store_dir"`
+	ClusterNodeID          *string  `yaml:"cluster_node_id"`
+	ClusterPeers           []string `yaml:"cluster_peers"`
+	ClusterAuthToken       *string  `yaml:"cluster_auth_token"`
+	ClusterPollInterval    *string  `yaml:"cluster_poll_interval"`
+	MetricsListenPort      *int     `yaml:"metrics_listen_port"`
+	LockLeaseTTL           *string  `yaml:"lock_lease_ttl"`
+	ShutdownGracePeriod    *string  `yaml:"shutdown_grace_period"`
+
+	WorkflowConcurrency *int    `yaml:"workflow_concurrency"`
+	WorkflowStepTimeout *string `yaml:"workflow_step_timeout"`
+
+	PruneEnabled                *bool   `yaml:"prune_enabled"`
+	PruneInterval               *string `yaml:"prune_interval"`
+	PruneTaskHistoryMaxAge      *string `yaml:"prune_task_history_max_age"`
+	PruneTaskHistoryMaxRows     *int    `yaml:"prune_task_history_max_rows"`
+	PruneWorkflowRunMaxAge      *string `yaml:"prune_workflow_run_max_age"`
+	PruneWorkflowRunMaxRows     *int    `yaml:"prune_workflow_run_max_rows"`
+	PruneWorkflowVersionMaxAge  *string `yaml:"prune_workflow_version_max_age"`
+	PruneWorkflowVersionMaxRows *int    `yaml:"prune_workflow_version_max_rows"`
+	PruneKeepFailedLonger       *bool   `yaml:"prune_keep_failed_longer"`
+	PruneVacuumReclaimPages     *int    `yaml:"prune_vacuum_reclaim_pages"`
+}
+
+// loadConfigFile reads the YAML document at path and merges it onto cfg,
+// overriding only the fields the document actually sets; everything else
+// keeps cfg's existing value. Used by LoadConfig for the initial load and
+// by ConfigManager for every reload triggered by a file change.
+func loadConfigFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var fc configFile
+	if err := yaml.Unmarshal(data, &fc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	// Apply onto a copy so a bad value partway through (an unparsable
+	// duration, say) leaves cfg exactly as it was.
+	next := *cfg
+	if err := fc.applyTo(&next); err != nil {
+		return err
+	}
+	*cfg = next
+	return nil
+}
+
+func (fc *configFile) applyTo(cfg *Config) error {
+	if fc.WatchDirs != nil {
+		cfg.WatchDirs = fc.WatchDirs
+	}
+	if fc.DBPath != nil {
+		cfg.DBPath = *fc.DBPath
+	}
+	if fc.HTTPPort != nil {
+		cfg.HTTPPort = *fc.HTTPPort
+	}
+	if fc.LogLevel != nil {
+		cfg.LogLevel = *fc.LogLevel
+	}
+	if fc.LogFormat != nil {
+		cfg.LogFormat = *fc.LogFormat
+	}
+	if err := applyDuration("poll_interval", fc.PollInterval, &cfg.PollInterval); err != nil {
+		return err
+	}
+	if err := applyDuration("metadata_stability_delay", fc.MetadataStabilityDelay, &cfg.MetadataStabilityDelay); err != nil {
+		return err
+	}
+	if fc.WatcherMode != nil {
+		cfg.WatcherMode = *fc.WatcherMode
+	}
+	if fc.WatchExtensions != nil {
+		cfg.WatchExtensions = fc.WatchExtensions
+	}
+	if fc.WatchIgnoreHidden != nil {
+		cfg.WatchIgnoreHidden = *fc.WatchIgnoreHidden
+	}
+	if fc.WatchIgnoreGlobs != nil {
+		cfg.WatchIgnoreGlobs = fc.WatchIgnoreGlobs
+	}
+	if fc.MaxWorkers != nil {
+		cfg.MaxWorkers = *fc.MaxWorkers
+	}
+	if fc.ConvertQuality != nil {
+		cfg.ConvertQuality = *fc.ConvertQuality
+	}
+	if fc.PreserveMetadata != nil {
+		cfg.PreserveMetadata = *fc.PreserveMetadata
+	}
+	if fc.MD5ChunkSize != nil {
+		cfg.MD5ChunkSize = *fc.MD5ChunkSize
+	}
+	if fc.HashAlgorithm != nil {
+		cfg.HashAlgorithm = *fc.HashAlgorithm
+	}
+	if err := applyDuration("rebuild_job_ttl", fc.RebuildJobTTL, &cfg.RebuildJobTTL); err != nil {
+		return err
+	}
+	if err := applyDuration("webhook_poll_interval", fc.WebhookPollInterval, &cfg.WebhookPollInterval); err != nil {
+		return err
+	}
+	if fc.EnablePprof != nil {
+		cfg.EnablePprof = *fc.EnablePprof
+	}
+	if fc.CacheEnabled != nil {
+		cfg.CacheEnabled = *fc.CacheEnabled
+	}
+	if fc.CacheDir != nil {
+		cfg.CacheDir = *fc.CacheDir
+	}
+	if fc.CacheMaxSizeMB != nil {
+		cfg.CacheMaxSizeMB = *fc.CacheMaxSizeMB
+	}
+	if fc.BlobStoreEnabled != nil {
+		cfg.BlobStoreEnabled = *fc.BlobStoreEnabled
+	}
+	if fc.BlobStoreDir != nil {
+		cfg.BlobStoreDir = *fc.BlobStoreDir
+	}
+	if fc.ClusterNodeID != nil {
+		cfg.ClusterNodeID = *fc.ClusterNodeID
+	}
+	if fc.ClusterPeers != nil {
+		cfg.ClusterPeers = fc.ClusterPeers
+	}
+	if fc.ClusterAuthToken != nil {
+		cfg.ClusterAuthToken = *fc.ClusterAuthToken
+	}
+	if err := applyDuration("cluster_poll_interval", fc.ClusterPollInterval, &cfg.ClusterPollInterval); err != nil {
+		return err
+	}
+	if fc.MetricsListenPort != nil {
+		cfg.MetricsListenPort = *fc.MetricsListenPort
+	}
+	if err := applyDuration("lock_lease_ttl", fc.LockLeaseTTL, &cfg.LockLeaseTTL); err != nil {
+		return err
+	}
+	if err := applyDuration("shutdown_grace_period", fc.ShutdownGracePeriod, &cfg.ShutdownGracePeriod); err != nil {
+		return err
+	}
+
+	if fc.WorkflowConcurrency != nil {
+		cfg.WorkflowConcurrency = *fc.WorkflowConcurrency
+	}
+	if err := applyDuration("workflow_step_timeout", fc.WorkflowStepTimeout, &cfg.WorkflowStepTimeout); err != nil {
+		return err
+	}
+
+	if fc.PruneEnabled != nil {
+		cfg.PruneEnabled = *fc.PruneEnabled
+	}
+	if err := applyDuration("prune_interval", fc.PruneInterval, &cfg.PruneInterval); err != nil {
+		return err
+	}
+	if err := applyDuration("prune_task_history_max_age", fc.PruneTaskHistoryMaxAge, &cfg.PruneTaskHistoryMaxAge); err != nil {
+		return err
+	}
+	if fc.PruneTaskHistoryMaxRows != nil {
+		cfg.PruneTaskHistoryMaxRows = *fc.PruneTaskHistoryMaxRows
+	}
+	if err := applyDuration("prune_workflow_run_max_age", fc.PruneWorkflowRunMaxAge, &cfg.PruneWorkflowRunMaxAge); err != nil {
+		return err
+	}
+	if fc.PruneWorkflowRunMaxRows != nil {
+		cfg.PruneWorkflowRunMaxRows = *fc.PruneWorkflowRunMaxRows
+	}
+	if err := applyDuration("prune_workflow_version_max_age", fc.PruneWorkflowVersionMaxAge, &cfg.PruneWorkflowVersionMaxAge); err != nil {
+		return err
+	}
+	if fc.PruneWorkflowVersionMaxRows != nil {
+		cfg.PruneWorkflowVersionMaxRows = *fc.PruneWorkflowVersionMaxRows
+	}
+	if fc.PruneKeepFailedLonger != nil {
+		cfg.PruneKeepFailedLonger = *fc.PruneKeepFailedLonger
+	}
+	if fc.PruneVacuumReclaimPages != nil {
+		cfg.PruneVacuumReclaimPages = *fc.PruneVacuumReclaimPages
+	}
+
+	return nil
+}
+
+func applyDuration(field string, s *string, dst *time.Duration) error {
+	if s == nil {
+		return nil
+	}
+	d, err := time.ParseDuration(*s)
+	if err != nil {
+		return fmt.Errorf("%s: invalid duration %q: %w", field, *s, err)
+	}
+	*dst = d
+	return nil
+}
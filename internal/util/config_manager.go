@@ -0,0 +1,162 @@
+package util
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/logging"
+)
+
+// ConfigSubscriber is notified after a successful reload, with the config
+// in effect before and after the change. Called synchronously from
+// ConfigManager's fsnotify goroutine, so a subscriber that does real work
+// (resizing a worker pool, re-pointing a watcher) should do it quickly or
+// hand off to its own goroutine.
+type ConfigSubscriber func(old, new *Config)
+
+// ConfigManager holds the currently active, resolved Config behind an
+// atomic.Pointer so concurrent readers (Current) never observe a
+// partially-applied update, and optionally watches the backing config file
+// via fsnotify, reloading and re-validating it on every change. A reload
+// that fails to parse or fails Validate is logged and discarded, leaving
+// the previously active Config (and everything built on it) untouched.
+type ConfigManager struct {
+	current atomic.Pointer[Config]
+	path    string
+
+	mu      sync.Mutex
+	subs    []ConfigSubscriber
+	watcher *fsnotify.Watcher
+	logger  logging.Logger
+}
+
+// NewConfigManager wraps an already-resolved Config (typically the result
+// of LoadConfig) for live access and, if path is non-empty, hot reload.
+func NewConfigManager(initial *Config, path string) *ConfigManager {
+	m := &ConfigManager{path: path, logger: logging.NewNop()}
+	m.current.Store(initial)
+	return m
+}
+
+// SetLogger wires a structured logging.Logger for reload events. Must be
+// called before WatchFile.
+func (m *ConfigManager) SetLogger(l logging.Logger) {
+	if l == nil {
+		l = logging.NewNop()
+	}
+	m.logger = l
+}
+
+// Current returns the currently active Config. Safe for concurrent use;
+// the returned value is never mutated in place, so callers can keep a
+// reference across a later reload without locking.
+func (m *ConfigManager) Current() *Config {
+	return m.current.Load()
+}
+
+// Subscribe registers fn to be called after every successful reload. fn is
+// not called for the initial Config passed to NewConfigManager.
+func (m *ConfigManager) Subscribe(fn ConfigSubscriber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subs = append(m.subs, fn)
+}
+
+// Reload re-reads the config file at m.path (if any) on top of defaults and
+// environment variables, exactly as LoadConfig does, validates the result,
+// and atomically swaps it in on success. Subscribers are notified after
+// the swap. Returns the validation or parse error, if any, without
+// changing the active Config.
+func (m *ConfigManager) Reload() error {
+	next := defaultConfig()
+	if m.path != "" {
+		if err := loadConfigFile(m.path, next); err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+	}
+	applyEnvOverrides(next)
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	old := m.current.Swap(next)
+
+	m.mu.Lock()
+	subs := make([]ConfigSubscriber, len(m.subs))
+	copy(subs, m.subs)
+	m.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(old, next)
+	}
+
+	return nil
+}
+
+// WatchFile starts watching m.path for writes and reloads on each one. A
+// no-op if m.path is empty (nothing to watch). The watch runs until Close
+// is called; it does not stop on a failed reload, so fixing the file and
+// saving again will pick it up on the next write.
+func (m *ConfigManager) WatchFile() error {
+	if m.path == "" {
+		return nil
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config file watcher: %w", err)
+	}
+	if err := fsw.Add(m.path); err != nil {
+		fsw.Close()
+		return fmt.Errorf("failed to watch config file %s: %w", m.path, err)
+	}
+
+	m.mu.Lock()
+	m.watcher = fsw
+	m.mu.Unlock()
+
+	go m.watchLoop(fsw)
+	return nil
+}
+
+func (m *ConfigManager) watchLoop(fsw *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if err := m.Reload(); err != nil {
+				m.logger.Error("config reload failed, keeping previous config", "path", m.path, "error", err)
+				continue
+			}
+			m.logger.Info("config reloaded", "path", m.path)
+
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return
+			}
+			m.logger.Error("config file watcher error", "error", err)
+		}
+	}
+}
+
+// Close stops the file watch, if one is running. Safe to call even if
+// WatchFile was never called or failed.
+func (m *ConfigManager) Close() error {
+	m.mu.Lock()
+	fsw := m.watcher
+	m.watcher = nil
+	m.mu.Unlock()
+
+	if fsw == nil {
+		return nil
+	}
+	return fsw.Close()
+}
@@ -0,0 +1,219 @@
+package util
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/metrics"
+	"github.com/zeebo/xxh3"
+	"lukechampine.com/blake3"
+)
+
+// FileHasher computes a content digest of a file, selectable via
+// Config.HashAlgorithm so FileIndex rows can record which algorithm
+// produced their digest and a rescan can compare like with like (or
+// re-hash and migrate a row written by a previous algorithm).
+type FileHasher interface {
+	// Algorithm is this hasher's name, as stored in FileIndex.HashAlgo
+	// (e.g. "md5", "sha256", "blake3", "xxh3").
+	Algorithm() string
+	// Hash streams path in chunkSize blocks and returns its hex digest.
+	// Files at or above parallelHashThreshold are hashed with
+	// hashFileParallel instead of a single streaming pass.
+	Hash(path string, chunkSize int) (string, error)
+}
+
+// parallelHashThreshold is the file size above which Hash switches from
+// streaming through a single hash.Hash to hashFileParallel's chunked,
+// multi-goroutine strategy. Below it, the fixed cost of spinning up
+// goroutines and the extra Merkle-combine pass isn't worth it.
+const parallelHashThreshold = 64 * 1024 * 1024 // 64 MiB
+
+// NewFileHasher returns the FileHasher for algorithm ("md5", "sha256",
+// "blake3", or "xxh3"); an empty algorithm defaults to "md5" for backward
+// compatibility with FileIndex rows written before HashAlgorithm existed.
+func NewFileHasher(algorithm string) (FileHasher, error) {
+	switch algorithm {
+	case "", "md5":
+		return md5Hasher{}, nil
+	case "sha256":
+		return sha256Hasher{}, nil
+	case "blake3":
+		return blake3Hasher{}, nil
+	case "xxh3":
+		return xxh3Hasher{}, nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %q", algorithm)
+	}
+}
+
+type md5Hasher struct{}
+
+func (md5Hasher) Algorithm() string { return "md5" }
+func (md5Hasher) Hash(path string, chunkSize int) (string, error) {
+	return hashFile("md5", path, chunkSize, md5.New)
+}
+
+type sha256Hasher struct{}
+
+func (sha256Hasher) Algorithm() string { return "sha256" }
+func (sha256Hasher) Hash(path string, chunkSize int) (string, error) {
+	return hashFile("sha256", path, chunkSize, sha256.New)
+}
+
+type blake3Hasher struct{}
+
+func (blake3Hasher) Algorithm() string { return "blake3" }
+func (blake3Hasher) Hash(path string, chunkSize int) (string, error) {
+	return hashFile("blake3", path, chunkSize, func() hash.Hash { return blake3.New(32, nil) })
+}
+
+type xxh3Hasher struct{}
+
+func (xxh3Hasher) Algorithm() string { return "xxh3" }
+func (xxh3Hasher) Hash(path string, chunkSize int) (string, error) {
+	return hashFile("xxh3", path, chunkSize, func() hash.Hash { return xxh3.New() })
+}
+
+// hashFile picks between the serial and parallel strategies based on file
+// size, and records jpeg2heif_hash_duration_seconds either way.
+func hashFile(algorithm, path string, chunkSize int, newHash func() hash.Hash) (string, error) {
+	if chunkSize <= 0 {
+		chunkSize = 8192
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	start := time.Now()
+	var digest string
+	if info.Size() < parallelHashThreshold {
+		digest, err = hashFileSerial(path, chunkSize, newHash)
+	} else {
+		digest, err = hashFileParallel(path, info.Size(), chunkSize, runtime.NumCPU(), newHash)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	metrics.HashDuration.Observe(time.Since(start).Seconds(), algorithm, strconv.Itoa(chunkSize))
+	return digest, nil
+}
+
+// hashFileSerial streams path through a single hash.Hash, chunkSize bytes
+// at a time.
+func hashFileSerial(path string, chunkSize int, newHash func() hash.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	h := newHash()
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if _, werr := h.Write(buf[:n]); werr != nil {
+				return "", fmt.Errorf("failed to write to hash: %w", werr)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFileParallel hashes path in parallel: it reads size in chunkSize
+// blocks via pread (os.File.ReadAt, so reads need no shared seek position)
+// across up to workers goroutines, hashes each block independently, then
+// combines the ordered per-block digests with one more hash pass (a
+// single-level Merkle tree: block digests concatenated, then hashed).
+// This turns re-hashing a large file from serial-IO-bound into CPU-bound,
+// since blocks can be read and hashed concurrently instead of streamed
+// through one hash.Hash in sequence.
+func hashFileParallel(path string, size int64, chunkSize, workers int, newHash func() hash.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	if size == 0 {
+		return hex.EncodeToString(newHash().Sum(nil)), nil
+	}
+
+	numBlocks := int((size + int64(chunkSize) - 1) / int64(chunkSize))
+	digests := make([][]byte, numBlocks)
+
+	if workers <= 0 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for i := 0; i < numBlocks; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			offset := int64(i) * int64(chunkSize)
+			length := int64(chunkSize)
+			if offset+length > size {
+				length = size - offset
+			}
+
+			buf := make([]byte, length)
+			if _, err := f.ReadAt(buf, offset); err != nil && err != io.EOF {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to read block %d: %w", i, err)
+				}
+				mu.Unlock()
+				return
+			}
+
+			h := newHash()
+			h.Write(buf)
+			digests[i] = h.Sum(nil)
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return "", firstErr
+	}
+
+	root := newHash()
+	for _, d := range digests {
+		root.Write(d)
+	}
+	return hex.EncodeToString(root.Sum(nil)), nil
+}
+
+// CalculateMD5 calculates the MD5 hash of a file using streaming. It's kept
+// as a thin wrapper around FileHasher's "md5" implementation for existing
+// callers that predate HashAlgorithm; new call sites should go through
+// NewFileHasher so they pick up Config.HashAlgorithm.
+func CalculateMD5(filePath string, chunkSize int) (string, error) {
+	return md5Hasher{}.Hash(filePath, chunkSize)
+}
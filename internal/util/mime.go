@@ -0,0 +1,66 @@
+package util
+
+import (
+	"bytes"
+	"os"
+)
+
+// sniffLen is how many leading bytes SniffMime reads to identify a file's
+// format, enough to cover every magic number checked by detectMime below.
+const sniffLen = 512
+
+// SniffMime identifies path's image format from its leading bytes (a "magic
+// number") rather than its file extension, returning a MIME type such as
+// "image/jpeg", or "" if the format isn't recognized or the file can't be
+// read. Callers like the worker pass this to converter.FindConverter
+// instead of an empty srcMime, so CanConvert/MatchPriority rules that key
+// on mime type (e.g. a workflow's inputs.match) see a real value even when
+// a file was discovered by extension alone, or has none at all.
+func SniffMime(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLen)
+	n, err := f.Read(buf)
+	if err != nil && n == 0 {
+		return ""
+	}
+	return detectMime(buf[:n])
+}
+
+// isoBMFFBrand returns the major brand of an ISO base media file format
+// container (the format HEIC and AVIF are both built on): a 4-byte box
+// size, the ASCII literal "ftyp", and then the brand itself, e.g. "heic" or
+// "avif". Returns "" if b doesn't start with an ftyp box.
+func isoBMFFBrand(b []byte) string {
+	if len(b) < 12 || !bytes.Equal(b[4:8], []byte("ftyp")) {
+		return ""
+	}
+	return string(b[8:12])
+}
+
+// detectMime matches the magic numbers of the image formats jpeg2heif cares
+// about: JPEG, PNG, HEIC/HEIF and AVIF (both ISO base media file format
+// containers, distinguished only by their ftyp brand), and TIFF.
+func detectMime(b []byte) string {
+	switch {
+	case len(b) >= 3 && b[0] == 0xFF && b[1] == 0xD8 && b[2] == 0xFF:
+		return "image/jpeg"
+	case len(b) >= 8 && bytes.Equal(b[:8], []byte{0x89, 'P', 'N', 'G', 0x0D, 0x0A, 0x1A, 0x0A}):
+		return "image/png"
+	case len(b) >= 4 && (bytes.Equal(b[:4], []byte("II*\x00")) || bytes.Equal(b[:4], []byte("MM\x00*"))):
+		return "image/tiff"
+	default:
+		switch isoBMFFBrand(b) {
+		case "heic", "heix", "heim", "heis", "hevc", "hevx", "hevm", "hevs", "mif1", "msf1":
+			return "image/heic"
+		case "avif", "avis":
+			return "image/avif"
+		default:
+			return ""
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package utils
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 func IsJPEG(path string) bool {
@@ -12,10 +17,40 @@ func IsJPEG(path string) bool {
 	return ext == ".jpg" || ext == ".jpeg"
 }
 
+// WaitFileStable waits for path to stop changing before returning. It is a
+// convenience wrapper around WaitFileStableCtx for callers with no context
+// to propagate; prefer WaitFileStableCtx where a ctx is already in hand, so
+// the wait aborts cleanly on shutdown instead of blocking for the full
+// delay.
 func WaitFileStable(path string, delay time.Duration) error {
-	// Wait for two consecutive identical sizes separated by delay
+	return WaitFileStableCtx(context.Background(), path, delay)
+}
+
+// WaitFileStableCtx waits for path to stop changing before returning,
+// resolving as soon as no write to path is observed for delay. It uses
+// defaultStabilityWatcher's shared fsnotify instance rather than polling
+// os.Stat on a timer, so a slow upload (large source JPEG, rsync/SMB write)
+// isn't cut off after a fixed number of stat cycles and an already-stable
+// file doesn't wait out cycles it doesn't need. On platforms where
+// fsnotify can't watch a directory (or, on macOS, where its kqueue backend
+// reports only generic Write events with no reliable way to tell "still
+// writing" from "paused between chunks"), it falls back to the original
+// stat-polling loop. ctx cancellation aborts the wait and returns ctx.Err().
+func WaitFileStableCtx(ctx context.Context, path string, delay time.Duration) error {
+	if runtime.GOOS != "darwin" {
+		if w, err := defaultStabilityWatcher(); err == nil {
+			return w.Wait(ctx, path, delay)
+		}
+	}
+	return waitFileStablePoll(ctx, path, delay)
+}
+
+// waitFileStablePoll is the original polling implementation: up to 5 stat
+// cycles, sleeping delay between them, returning as soon as two
+// consecutive sizes match (or the cycle budget runs out).
+func waitFileStablePoll(ctx context.Context, path string, delay time.Duration) error {
 	var lastSize int64 = -1
-	for i := 0; i < 5; i++ { // up to ~5 cycles
+	for i := 0; i < 5; i++ {
 		fi, err := os.Stat(path)
 		if err != nil {
 			return err
@@ -25,11 +60,181 @@ func WaitFileStable(path string, delay time.Duration) error {
 			return nil
 		}
 		lastSize = sz
-		time.Sleep(delay)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return nil
+}
+
+// FileStabilityWatcher detects when a file has stopped being written to by
+// watching its parent directory for Create/Write events and debouncing on
+// each one, rather than polling os.Stat on a timer. A single fsnotify
+// instance is shared across every path being waited on, added once per
+// parent directory no matter how many files within it are being watched
+// concurrently, so a busy watch directory doesn't exhaust the platform's
+// watch-descriptor limit the way one fsnotify.Watcher per file would.
+type FileStabilityWatcher struct {
+	fsw *fsnotify.Watcher
+
+	mu      sync.Mutex
+	dirRefs map[string]int
+	waiters map[string][]chan struct{}
+}
+
+// NewFileStabilityWatcher creates a FileStabilityWatcher backed by a fresh
+// fsnotify instance and starts its event loop. Call Close when done.
+func NewFileStabilityWatcher() (*FileStabilityWatcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &FileStabilityWatcher{
+		fsw:     fsw,
+		dirRefs: make(map[string]int),
+		waiters: make(map[string][]chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Close stops the event loop and releases the underlying fsnotify instance.
+func (w *FileStabilityWatcher) Close() error {
+	return w.fsw.Close()
+}
+
+func (w *FileStabilityWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			w.notify(event.Name)
+		case _, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func (w *FileStabilityWatcher) notify(path string) {
+	w.mu.Lock()
+	chans := w.waiters[path]
+	w.mu.Unlock()
+	for _, ch := range chans {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Wait blocks until path has had no Write/Create event for delay. ctx
+// cancellation aborts the wait and returns ctx.Err().
+func (w *FileStabilityWatcher) Wait(ctx context.Context, path string, delay time.Duration) error {
+	dir := filepath.Dir(path)
+	if err := w.acquireDir(dir); err != nil {
+		return err
+	}
+	defer w.releaseDir(dir)
+
+	ch := make(chan struct{}, 1)
+	w.addWaiter(path, ch)
+	defer w.removeWaiter(path, ch)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		case <-ch:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(delay)
+		}
 	}
+}
+
+// acquireDir adds dir to the shared fsnotify instance if no other waiter is
+// already watching it.
+func (w *FileStabilityWatcher) acquireDir(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.dirRefs[dir] == 0 {
+		if err := w.fsw.Add(dir); err != nil {
+			return err
+		}
+	}
+	w.dirRefs[dir]++
 	return nil
 }
 
+// releaseDir drops dir's watch once the last waiter interested in it is
+// gone, so a directory with no in-flight uploads doesn't keep a watch
+// descriptor pinned indefinitely.
+func (w *FileStabilityWatcher) releaseDir(dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.dirRefs[dir]--
+	if w.dirRefs[dir] <= 0 {
+		delete(w.dirRefs, dir)
+		_ = w.fsw.Remove(dir)
+	}
+}
+
+func (w *FileStabilityWatcher) addWaiter(path string, ch chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.waiters[path] = append(w.waiters[path], ch)
+}
+
+func (w *FileStabilityWatcher) removeWaiter(path string, ch chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	chans := w.waiters[path]
+	for i, c := range chans {
+		if c == ch {
+			w.waiters[path] = append(chans[:i], chans[i+1:]...)
+			break
+		}
+	}
+	if len(w.waiters[path]) == 0 {
+		delete(w.waiters, path)
+	}
+}
+
+var (
+	defaultWatcherOnce sync.Once
+	defaultWatcher     *FileStabilityWatcher
+	defaultWatcherErr  error
+)
+
+// defaultStabilityWatcher lazily creates the package-wide FileStabilityWatcher
+// shared by every WaitFileStableCtx call, rather than standing up a new
+// fsnotify instance per file.
+func defaultStabilityWatcher() (*FileStabilityWatcher, error) {
+	defaultWatcherOnce.Do(func() {
+		defaultWatcher, defaultWatcherErr = NewFileStabilityWatcher()
+	})
+	return defaultWatcher, defaultWatcherErr
+}
+
 func TargetHEICPath(src string) string {
 	base := filepath.Base(src)
 	name := strings.TrimSuffix(base, filepath.Ext(base)) + ".heic"
@@ -0,0 +1,119 @@
+package watcher
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileSnapshot is the (size, modtime, mode) of one regular file as seen by
+// the poll engine's last walk — enough to tell a rewritten file apart from
+// an untouched one without reading its content.
+type fileSnapshot struct {
+	size    int64
+	modTime time.Time
+	mode    os.FileMode
+}
+
+// startPoll launches the poll-only engine: no fsnotify involved at all,
+// just a walk of every watch directory every pollInterval, diffed against
+// the previous walk's snapshot. Used when Mode is ModePoll, or when
+// ModeAuto couldn't create or use an fsnotify.Watcher (see New and
+// downgradeToPoll in watcher.go).
+func (w *Watcher) startPoll() error {
+	w.snapshot = w.pollSnapshot()
+
+	go w.pollLoop()
+
+	log.Printf("Watcher started in poll mode, monitoring %d director(ies) every %v", len(w.watchDirsSnapshot()), w.pollInterval)
+	w.logger.Info("watcher started", "mode", "poll", "poll_interval", w.pollInterval.String())
+	return nil
+}
+
+// pollLoop re-walks the watch directories every pollInterval for the
+// lifetime of the watcher.
+func (w *Watcher) pollLoop() {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.pollOnce()
+		}
+	}
+}
+
+// pollOnce walks every watch directory, diffs the result against the
+// previous snapshot, and feeds whatever changed into the same debounce
+// pipeline fsnotify's handleEvent uses for Create/Write: a path that's new,
+// or whose (size, modTime, mode) differs from the last poll — which also
+// covers the destination side of a rename, since it arrives here looking
+// exactly like a new file — (re)starts its debounce window exactly as a
+// Write event would. A path that disappeared between polls gets the same
+// treatment handleEvent gives a native fsnotify Remove/Rename: its debounce
+// entry (if any) is dropped and a FileEvent with Operation "remove" is
+// emitted, mirroring handleRemove, so cache/thumbnail invalidation isn't
+// silently skipped on poll-mode mounts.
+func (w *Watcher) pollOnce() {
+	next := w.pollSnapshot()
+	prev := w.snapshot
+	w.snapshot = next
+
+	for path := range prev {
+		if _, ok := next[path]; !ok {
+			w.pendingMu.Lock()
+			if pf, exists := w.pendingFiles[path]; exists {
+				if pf.timer != nil {
+					pf.timer.Stop()
+				}
+				delete(w.pendingFiles, path)
+			}
+			w.pendingMu.Unlock()
+
+			select {
+			case w.fileQueue <- FileEvent{Path: path, Operation: "remove", Timestamp: time.Now()}:
+			default:
+				log.Printf("Warning: file queue is full, dropping remove event for %s", path)
+			}
+		}
+	}
+
+	for path, snap := range next {
+		if old, existed := prev[path]; existed && old == snap {
+			continue
+		}
+		w.debounceFile(path)
+	}
+}
+
+// pollSnapshot walks every watch directory and records the (size, modtime,
+// mode) of each regular media file it finds, for pollOnce to diff against
+// the previous call's result.
+func (w *Watcher) pollSnapshot() map[string]fileSnapshot {
+	snap := make(map[string]fileSnapshot)
+
+	for _, dir := range w.watchDirsSnapshot() {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil // Continue on error, matching ScanNow/addRecursive
+			}
+			if info.IsDir() {
+				if path != dir && w.shouldIgnoreDir(path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !w.isMediaFile(path) {
+				return nil
+			}
+			snap[path] = fileSnapshot{size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+			return nil
+		})
+	}
+
+	return snap
+}
@@ -2,15 +2,20 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/logging"
+	"github.com/ah-its-andy/jpeg2heif/internal/metrics"
 )
 
 // FileEvent represents a file system event
@@ -20,10 +25,65 @@ type FileEvent struct {
 	Timestamp time.Time
 }
 
+// Mode selects how a Watcher detects filesystem changes. ModeNative relies
+// entirely on fsnotify's kernel-level events, supplemented by the existing
+// periodic scan. ModePoll skips fsnotify entirely and walks every watch
+// directory every pollInterval instead (see poll.go) — the only option that
+// reliably sees changes on SMB/NFS/rclone/overlay mounts, where inotify/
+// kqueue events are either missing or unreliable. ModeAuto (the default)
+// tries native first and silently falls back to poll if fsnotify can't be
+// created, or can't watch a directory because the underlying filesystem
+// doesn't support it.
+type Mode string
+
+const (
+	ModeAuto   Mode = "auto"
+	ModeNative Mode = "native"
+	ModePoll   Mode = "poll"
+)
+
+// defaultMediaExtensions is the extension set isMediaFile uses when
+// FilterConfig.Extensions is empty.
+var defaultMediaExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tiff"}
+
+// FilterConfig controls which directories New's Watcher descends into and
+// which files it treats as media to convert, so large libraries don't burn
+// inotify watch slots (a scarce resource; see fs.inotify.max_user_watches)
+// on trees like .git, node_modules, or a thumbnail cache that nobody wants
+// watched in the first place.
+type FilterConfig struct {
+	// Extensions is the set of lowercase extensions (with leading ".")
+	// isMediaFile treats as convertible. Empty uses defaultMediaExtensions.
+	Extensions []string
+
+	// IgnoreHidden skips any directory or file whose base name starts with
+	// "." (a watch root itself is never skipped this way, even if its own
+	// name starts with ".").
+	IgnoreHidden bool
+
+	// IgnoreGlobs is a set of filepath.Match patterns checked against both
+	// a directory or file's base name and its full path; a match causes
+	// addRecursive/ScanNow/pollSnapshot to skip the directory entirely, or
+	// isMediaFile to reject the file, e.g. "node_modules" or "@eaDir".
+	IgnoreGlobs []string
+}
+
 // Watcher monitors directories for file changes
 type Watcher struct {
-	fsWatcher      *fsnotify.Watcher
-	watchDirs      []string
+	// fsWatcher is nil in poll mode (either requested via ModePoll, or
+	// reached by ModeAuto downgrading — see New and downgradeToPoll); every
+	// fsnotify-specific code path is only ever reached when this is non-nil.
+	fsWatcher *fsnotify.Watcher
+	mode      Mode
+	filter    FilterConfig
+
+	// watchDirs is read by scanDirectories, pollSnapshot, and matchWatchDir
+	// from background goroutines and can be changed live via SetWatchDirs, so
+	// every access goes through watchDirsMu rather than reading the field
+	// directly.
+	watchDirsMu sync.RWMutex
+	watchDirs   []string
+
 	fileQueue      chan FileEvent
 	stabilityDelay time.Duration
 	pollInterval   time.Duration
@@ -33,26 +93,38 @@ type Watcher struct {
 	cancel         context.CancelFunc
 	watchedDirs    map[string]bool
 	watchedDirsMu  sync.RWMutex
+	logger         logging.Logger
+
+	// snapshot is the poll engine's last walk result, read and replaced only
+	// from pollLoop's goroutine; see poll.go.
+	snapshot map[string]fileSnapshot
 }
 
+// pendingFile tracks one file waiting out its debounce window; see
+// debounceFile and debounceStable.
 type pendingFile struct {
-	path        string
-	lastSize    int64
-	lastModTime time.Time
-	firstSeen   time.Time
+	path  string
+	timer *time.Timer
+	seq   int
 }
 
-// New creates a new watcher
-func New(watchDirs []string, stabilityDelay, pollInterval time.Duration) (*Watcher, error) {
-	fsw, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create fsnotify watcher: %w", err)
+// New creates a new watcher for the given mode (ModeAuto if empty). In
+// ModePoll it never touches fsnotify; in ModeNative a failure to create an
+// fsnotify.Watcher is fatal; in ModeAuto the same failure is logged and New
+// falls back to returning a poll-mode Watcher instead of erroring. filter
+// governs which directories get watched/scanned and which files count as
+// media; its zero value (no extensions, IgnoreHidden false, no globs) means
+// only defaultMediaExtensions is applied and nothing is skipped.
+func New(watchDirs []string, stabilityDelay, pollInterval time.Duration, mode Mode, filter FilterConfig) (*Watcher, error) {
+	if mode == "" {
+		mode = ModeAuto
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	w := &Watcher{
-		fsWatcher:      fsw,
+		mode:           mode,
+		filter:         filter,
 		watchDirs:      watchDirs,
 		fileQueue:      make(chan FileEvent, 1000),
 		stabilityDelay: stabilityDelay,
@@ -61,16 +133,96 @@ func New(watchDirs []string, stabilityDelay, pollInterval time.Duration) (*Watch
 		ctx:            ctx,
 		cancel:         cancel,
 		watchedDirs:    make(map[string]bool),
+		logger:         logging.NewNop(),
+		snapshot:       make(map[string]fileSnapshot),
 	}
 
+	if mode == ModePoll {
+		return w, nil
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		if mode == ModeNative {
+			return nil, fmt.Errorf("failed to create fsnotify watcher: %w", err)
+		}
+		log.Printf("Warning: fsnotify unavailable (%v), falling back to poll mode", err)
+		w.mode = ModePoll
+		return w, nil
+	}
+	w.fsWatcher = fsw
+
 	return w, nil
 }
 
+// SetLogger wires a structured logging.Logger for this watcher's lifecycle
+// and scan events. Must be called before Start.
+func (w *Watcher) SetLogger(l logging.Logger) {
+	if l == nil {
+		l = logging.NewNop()
+	}
+	w.logger = l
+}
+
+// SetWatchDirs adds any directory in dirs not already being watched,
+// recursively, so new entries take effect without a restart. Safe to call
+// after Start. A directory dropped from dirs is left registered with
+// fsnotify and still scanned: tearing down an existing recursive watch
+// isn't supported, so removing a directory from config requires a restart
+// to fully stop watching it.
+func (w *Watcher) SetWatchDirs(dirs []string) {
+	w.watchDirsMu.Lock()
+	existing := make(map[string]bool, len(w.watchDirs))
+	for _, d := range w.watchDirs {
+		existing[d] = true
+	}
+	var added []string
+	for _, d := range dirs {
+		if !existing[d] {
+			w.watchDirs = append(w.watchDirs, d)
+			added = append(added, d)
+		}
+	}
+	w.watchDirsMu.Unlock()
+
+	// In poll mode there's no fsnotify watch to register: pollSnapshot reads
+	// watchDirsSnapshot() fresh on every poll, so a directory added here is
+	// picked up automatically on the next poll without any extra work.
+	if w.fsWatcher != nil {
+		for _, d := range added {
+			if err := w.addRecursive(d); err != nil {
+				log.Printf("Warning: failed to add watch directory %s: %v", d, err)
+			}
+		}
+	}
+	if len(added) > 0 {
+		w.logger.Info("watch directories added", "dirs", added)
+	}
+}
+
+// watchDirsSnapshot returns a copy of the current watch directory list,
+// reflecting the latest SetWatchDirs call if any.
+func (w *Watcher) watchDirsSnapshot() []string {
+	w.watchDirsMu.RLock()
+	defer w.watchDirsMu.RUnlock()
+	dirs := make([]string, len(w.watchDirs))
+	copy(dirs, w.watchDirs)
+	return dirs
+}
+
 // Start starts the watcher
 func (w *Watcher) Start() error {
+	if w.fsWatcher == nil {
+		return w.startPoll()
+	}
+
 	// Add initial watch directories recursively
-	for _, dir := range w.watchDirs {
+	for _, dir := range w.watchDirsSnapshot() {
 		if err := w.addRecursive(dir); err != nil {
+			if w.mode == ModeAuto && isUnsupported(err) {
+				log.Printf("Warning: fsnotify unsupported on %s (%v), falling back to poll mode", dir, err)
+				return w.downgradeToPoll()
+			}
 			log.Printf("Warning: failed to add watch directory %s: %v", dir, err)
 		}
 	}
@@ -78,21 +230,47 @@ func (w *Watcher) Start() error {
 	// Start event processor
 	go w.processEvents()
 
-	// Start stability checker
-	go w.checkStability()
-
 	// Start periodic scan
 	go w.periodicScan()
 
 	log.Printf("Watcher started, monitoring %d directories", len(w.watchedDirs))
+	w.logger.Info("watcher started", "mode", string(w.mode), "watched_dirs", len(w.watchedDirs))
 	return nil
 }
 
+// downgradeToPoll closes the now-unusable fsnotify watcher and switches this
+// Watcher to poll mode for the rest of its lifetime. Reached from Start when
+// ModeAuto's initial fsnotify.Add calls reveal the filesystem doesn't
+// actually deliver native events despite fsnotify.NewWatcher having
+// succeeded in New — e.g. a network mount where creating the watcher works
+// but Add on a directory under it fails outright.
+func (w *Watcher) downgradeToPoll() error {
+	w.fsWatcher.Close()
+	w.fsWatcher = nil
+	w.mode = ModePoll
+	return w.startPoll()
+}
+
+// isUnsupported reports whether err looks like the filesystem doesn't
+// support inotify/kqueue at all, as opposed to some other Add failure (a
+// permissions error, say) that isn't worth downgrading the whole watcher
+// over.
+func isUnsupported(err error) bool {
+	if errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.ENOSYS) {
+		return true
+	}
+	return strings.Contains(err.Error(), "not supported")
+}
+
 // Stop stops the watcher
 func (w *Watcher) Stop() error {
 	w.cancel()
 	close(w.fileQueue)
-	return w.fsWatcher.Close()
+	w.logger.Info("watcher stopped")
+	if w.fsWatcher != nil {
+		return w.fsWatcher.Close()
+	}
+	return nil
 }
 
 // Events returns the file event channel
@@ -108,6 +286,9 @@ func (w *Watcher) addRecursive(root string) error {
 		}
 
 		if info.IsDir() {
+			if path != root && w.shouldIgnoreDir(path) {
+				return filepath.SkipDir
+			}
 			if err := w.addDir(path); err != nil {
 				log.Printf("Warning: failed to watch directory %s: %v", path, err)
 			}
@@ -131,6 +312,7 @@ func (w *Watcher) addDir(path string) error {
 	}
 
 	w.watchedDirs[path] = true
+	metrics.WatchedDirs.Set(float64(len(w.watchedDirs)))
 	return nil
 }
 
@@ -152,6 +334,11 @@ func (w *Watcher) processEvents() {
 			if !ok {
 				return
 			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				log.Printf("Warning: fsnotify event queue overflowed, reconciling watch state")
+				w.reconcileAfterOverflow()
+				continue
+			}
 			log.Printf("Watcher error: %v", err)
 		}
 	}
@@ -159,9 +346,17 @@ func (w *Watcher) processEvents() {
 
 // handleEvent handles a single file system event
 func (w *Watcher) handleEvent(event fsnotify.Event) {
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		w.handleRemove(event.Name)
+		return
+	}
+
 	// Check if it's a directory creation - add to watch list
 	if event.Op&fsnotify.Create != 0 {
 		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if w.shouldIgnoreDir(event.Name) {
+				return
+			}
 			if err := w.addRecursive(event.Name); err != nil {
 				log.Printf("Failed to add new directory to watch: %v", err)
 			}
@@ -184,74 +379,156 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 		return
 	}
 
-	// Add to pending files for stability check
-	w.pendingMu.Lock()
-	if _, exists := w.pendingFiles[event.Name]; !exists {
-		info, err := os.Stat(event.Name)
-		if err != nil {
-			w.pendingMu.Unlock()
-			return
+	// (Re)start the debounce window; a burst of Write events for the same
+	// path just keeps pushing this back rather than risking a check mid-burst.
+	w.debounceFile(event.Name)
+}
+
+// handleRemove handles both fsnotify.Remove and fsnotify.Rename events for
+// path, treated the same way: fsnotify reports a rename as a Remove of the
+// old name plus a separate Create of the new one (handled like any other
+// new path by handleEvent), so from the old name's perspective the right
+// response is identical to an outright delete. It unwatches path if it was
+// a directory, drops path itself from watchedDirs, drops it (or, for a
+// directory, everything under it) from pendingFiles, and emits a FileEvent with Operation
+// "remove" so downstream cache/thumbnail code has a chance to invalidate
+// whatever it holds for path.
+func (w *Watcher) handleRemove(path string) {
+	w.watchedDirsMu.Lock()
+	_, wasDir := w.watchedDirs[path]
+	if wasDir {
+		delete(w.watchedDirs, path)
+	}
+	count := len(w.watchedDirs)
+	w.watchedDirsMu.Unlock()
+
+	if wasDir {
+		metrics.WatchedDirs.Set(float64(count))
+		if err := w.fsWatcher.Remove(path); err != nil {
+			log.Printf("Warning: failed to unwatch removed directory %s: %v", path, err)
 		}
+	}
 
-		w.pendingFiles[event.Name] = &pendingFile{
-			path:        event.Name,
-			lastSize:    info.Size(),
-			lastModTime: info.ModTime(),
-			firstSeen:   time.Now(),
+	w.pendingMu.Lock()
+	for p, pf := range w.pendingFiles {
+		if p == path || strings.HasPrefix(p, path+string(filepath.Separator)) {
+			if pf.timer != nil {
+				pf.timer.Stop()
+			}
+			delete(w.pendingFiles, p)
 		}
 	}
 	w.pendingMu.Unlock()
+
+	select {
+	case w.fileQueue <- FileEvent{Path: path, Operation: "remove", Timestamp: time.Now()}:
+	default:
+		log.Printf("Warning: file queue is full, dropping remove event for %s", path)
+	}
 }
 
-// checkStability checks if pending files are stable and ready for processing
-func (w *Watcher) checkStability() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+// stabilitySampleInterval is the pause between the two consecutive samples
+// debounceStable takes before deciding a file has stopped changing.
+const stabilitySampleInterval = 1 * time.Second
+
+// debounceFile (re)starts path's debounce timer: stabilityDelay after the
+// most recent call for this path (a Write/Create event, or the same path
+// showing up changed in a poll snapshot), debounceStable runs and decides
+// whether the file is done. Called instead of comparing against a fixed
+// ticker so a file written in bursts never gets checked mid-burst — every
+// new event simply pushes the check back out.
+func (w *Watcher) debounceFile(path string) {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+
+	pf, exists := w.pendingFiles[path]
+	if !exists {
+		pf = &pendingFile{path: path}
+		w.pendingFiles[path] = pf
+	} else if pf.timer != nil {
+		pf.timer.Stop()
+	}
 
-	for {
-		select {
-		case <-w.ctx.Done():
-			return
-		case <-ticker.C:
-			w.pendingMu.Lock()
-			now := time.Now()
+	pf.seq++
+	seq := pf.seq
+	pf.timer = time.AfterFunc(w.stabilityDelay, func() { w.debounceStable(path, seq) })
+}
 
-			for path, pf := range w.pendingFiles {
-				// Check if file has been pending long enough
-				if now.Sub(pf.firstSeen) < w.stabilityDelay {
-					continue
-				}
+// debounceStable runs once stabilityDelay has passed with no further event
+// for path. It still isn't enough that nothing arrived through fsnotify or
+// a poll during that window — the file could be growing via writes that
+// never produce a distinguishable event (e.g. over NFS) — so it additionally
+// requires two (size, mtime) samples stabilitySampleInterval apart to agree
+// before emitting. seq guards against a newer debounceFile call superseding
+// this one while the second sample is being taken.
+func (w *Watcher) debounceStable(path string, seq int) {
+	if !w.pendingStillCurrent(path, seq) {
+		return
+	}
 
-				// Check current file state
-				info, err := os.Stat(path)
-				if err != nil {
-					// File disappeared, remove from pending
-					delete(w.pendingFiles, path)
-					continue
-				}
+	info, err := os.Stat(path)
+	if err != nil {
+		w.dropPending(path, seq)
+		return
+	}
+	size, modTime := info.Size(), info.ModTime()
 
-				// Check if size and mod time are stable
-				if info.Size() == pf.lastSize && info.ModTime().Equal(pf.lastModTime) {
-					// File is stable, send to queue
-					select {
-					case w.fileQueue <- FileEvent{
-						Path:      path,
-						Operation: "create",
-						Timestamp: now,
-					}:
-						delete(w.pendingFiles, path)
-					default:
-						log.Printf("Warning: file queue is full, skipping %s", path)
-					}
-				} else {
-					// File is still changing, update last known state
-					pf.lastSize = info.Size()
-					pf.lastModTime = info.ModTime()
-				}
-			}
+	time.Sleep(stabilitySampleInterval)
 
-			w.pendingMu.Unlock()
+	if !w.pendingStillCurrent(path, seq) {
+		return
+	}
+
+	info2, err := os.Stat(path)
+	if err != nil {
+		w.dropPending(path, seq)
+		return
+	}
+
+	if info2.Size() != size || !info2.ModTime().Equal(modTime) {
+		// Still changing with no fresh event to reset the timer on (an NFS
+		// write, say) - wait another stabilityDelay and sample again.
+		w.pendingMu.Lock()
+		if pf, exists := w.pendingFiles[path]; exists && pf.seq == seq {
+			pf.seq++
+			nextSeq := pf.seq
+			pf.timer = time.AfterFunc(w.stabilityDelay, func() { w.debounceStable(path, nextSeq) })
 		}
+		w.pendingMu.Unlock()
+		return
+	}
+
+	w.pendingMu.Lock()
+	if pf, exists := w.pendingFiles[path]; !exists || pf.seq != seq {
+		w.pendingMu.Unlock()
+		return
+	}
+	delete(w.pendingFiles, path)
+	w.pendingMu.Unlock()
+
+	select {
+	case w.fileQueue <- FileEvent{Path: path, Operation: "create", Timestamp: time.Now()}:
+		metrics.FilesDiscovered.Inc(w.matchWatchDir(path))
+	case <-w.ctx.Done():
+	}
+}
+
+// pendingStillCurrent reports whether path's debounce entry still matches
+// seq, i.e. no later debounceFile call has superseded the check in progress.
+func (w *Watcher) pendingStillCurrent(path string, seq int) bool {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+	pf, exists := w.pendingFiles[path]
+	return exists && pf.seq == seq
+}
+
+// dropPending removes path's debounce entry if it still matches seq,
+// called when the file disappears mid-check.
+func (w *Watcher) dropPending(path string, seq int) {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+	if pf, exists := w.pendingFiles[path]; exists && pf.seq == seq {
+		delete(w.pendingFiles, path)
 	}
 }
 
@@ -273,17 +550,53 @@ func (w *Watcher) periodicScan() {
 	}
 }
 
-// scanDirectories scans all watch directories for files
+// ErrWatcherNotRunning is returned by ScanNow when ctx is already done
+// before the scan even starts, e.g. TriggerScan calling ScanNow(w.ctx)
+// after Stop has been called. It's distinct from the context.Canceled (or
+// DeadlineExceeded) ScanNow returns when ctx becomes done partway through a
+// scan already in progress, so a caller can tell "never ran" from "ran and
+// was cut short" with errors.Is.
+var ErrWatcherNotRunning = errors.New("watcher: scan not started, context already done")
+
+// scanDirectories performs the watcher's own periodic scan, using its
+// background context (see periodicScan). reconcileAfterOverflow also uses
+// this for fire-and-forget callers that don't need cancellation or an
+// error back.
 func (w *Watcher) scanDirectories() {
-	log.Println("Starting periodic directory scan")
+	if err := w.ScanNow(w.ctx); err != nil && err != context.Canceled && err != ErrWatcherNotRunning {
+		log.Printf("directory scan: %v", err)
+	}
+}
 
-	for _, dir := range w.watchDirs {
+// ScanNow walks every watch directory once, queuing any media file it
+// finds that isn't already pending. It returns ErrWatcherNotRunning if ctx
+// is already done before the walk starts, and ctx.Err() if ctx becomes
+// done partway through (so a caller driving this from a cancelable
+// background job, see api.Server's scan-now job, can abort a scan in
+// progress instead of only being able to ignore its result). Each media
+// file found is sent to fileQueue with a blocking send gated on ctx, so
+// ScanNow doesn't return until every file it found has actually been
+// accepted by the queue (or ctx ends first).
+func (w *Watcher) ScanNow(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ErrWatcherNotRunning
+	}
+
+	log.Println("Starting directory scan")
+
+	for _, dir := range w.watchDirsSnapshot() {
 		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
 			if err != nil {
 				return nil // Continue on error
 			}
 
 			if info.IsDir() {
+				if path != dir && w.shouldIgnoreDir(path) {
+					return filepath.SkipDir
+				}
 				return nil
 			}
 
@@ -300,34 +613,76 @@ func (w *Watcher) scanDirectories() {
 				return nil
 			}
 
-			// Add to queue directly (periodic scan assumes files are stable)
+			// Add to queue directly (periodic scan assumes files are stable),
+			// blocking until it's accepted or ctx ends.
 			select {
 			case w.fileQueue <- FileEvent{
 				Path:      path,
 				Operation: "scan",
 				Timestamp: time.Now(),
 			}:
-			default:
-				log.Printf("Warning: file queue is full during scan")
+				metrics.FilesDiscovered.Inc(dir)
+			case <-ctx.Done():
+				return ctx.Err()
 			}
 
 			return nil
 		})
 
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			return err
+		}
 		if err != nil {
 			log.Printf("Error scanning directory %s: %v", dir, err)
 		}
 	}
 
-	log.Println("Periodic directory scan completed")
+	log.Println("Directory scan completed")
+	return nil
+}
+
+// reconcileAfterOverflow responds to fsnotify.ErrEventOverflow (the kernel's
+// inotify queue filled up and silently dropped events, so an unknown number
+// of Create/Write/Remove/Rename events never reached processEvents) by
+// assuming nothing about the current watch state: it runs a full
+// scanDirectories() to pick up any Create/Write the overflow may have lost,
+// then re-adds every configured watch root via addRecursive (a no-op for
+// directories already in watchedDirs) in case a dropped event left a newly
+// created subdirectory unwatched.
+func (w *Watcher) reconcileAfterOverflow() {
+	w.scanDirectories()
+
+	for _, dir := range w.watchDirsSnapshot() {
+		if err := w.addRecursive(dir); err != nil {
+			log.Printf("Warning: failed to re-add watch directory %s during overflow reconciliation: %v", dir, err)
+		}
+	}
+}
+
+// matchWatchDir returns whichever entry in w.watchDirs contains path, for
+// labeling the jpeg2heif_files_discovered_total counter by watch directory.
+// Returns "" if path isn't under any configured watch directory (shouldn't
+// normally happen, since events only originate from watched trees).
+func (w *Watcher) matchWatchDir(path string) string {
+	for _, dir := range w.watchDirsSnapshot() {
+		if strings.HasPrefix(path, dir) {
+			return dir
+		}
+	}
+	return ""
 }
 
 // isMediaFile checks if a file is a media file we should process
 func (w *Watcher) isMediaFile(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	mediaExtensions := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tiff"}
+	if w.filter.IgnoreHidden && w.isHidden(path) {
+		return false
+	}
+	if w.matchesIgnoreGlob(path) {
+		return false
+	}
 
-	for _, mediaExt := range mediaExtensions {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, mediaExt := range w.extensions() {
 		if ext == mediaExt {
 			return true
 		}
@@ -336,7 +691,61 @@ func (w *Watcher) isMediaFile(path string) bool {
 	return false
 }
 
-// TriggerScan triggers an immediate scan
+// extensions returns the configured media extension set, falling back to
+// defaultMediaExtensions when FilterConfig.Extensions is empty.
+func (w *Watcher) extensions() []string {
+	if len(w.filter.Extensions) > 0 {
+		return w.filter.Extensions
+	}
+	return defaultMediaExtensions
+}
+
+// isHidden reports whether path's base name starts with ".", the usual
+// Unix convention for a file or directory meant to be left alone by casual
+// tooling.
+func (w *Watcher) isHidden(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
+
+// matchesIgnoreGlob reports whether path's base name or full path matches
+// any of FilterConfig.IgnoreGlobs.
+func (w *Watcher) matchesIgnoreGlob(path string) bool {
+	base := filepath.Base(path)
+	for _, glob := range w.filter.IgnoreGlobs {
+		if ok, _ := filepath.Match(glob, base); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(glob, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldIgnoreDir reports whether addRecursive, ScanNow, or pollSnapshot
+// should skip descending into the directory at path (filepath.SkipDir),
+// per FilterConfig.IgnoreHidden and FilterConfig.IgnoreGlobs. Never called
+// against a watch root itself, only the directories under it.
+func (w *Watcher) shouldIgnoreDir(path string) bool {
+	if w.filter.IgnoreHidden && w.isHidden(path) {
+		return true
+	}
+	return w.matchesIgnoreGlob(path)
+}
+
+// WatchedCount returns the number of directories currently registered with
+// the underlying fsnotify watcher (always 0 in poll mode, where there's no
+// kernel watch to register), so operators can monitor usage against
+// fs.inotify.max_user_watches.
+func (w *Watcher) WatchedCount() int {
+	w.watchedDirsMu.RLock()
+	defer w.watchedDirsMu.RUnlock()
+	return len(w.watchedDirs)
+}
+
+// TriggerScan triggers an immediate scan in the background. Any error
+// ScanNow returns (including ErrWatcherNotRunning, if called after Stop)
+// is discarded; callers that need the result should call ScanNow directly.
 func (w *Watcher) TriggerScan() {
-	go w.scanDirectories()
+	go w.ScanNow(w.ctx)
 }
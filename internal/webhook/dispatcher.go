@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/db"
+)
+
+// SignatureHeader is the header carrying the HMAC-SHA256 signature of the
+// delivered body, computed with the webhook's configured secret.
+const SignatureHeader = "X-JPEG2HEIF-Signature"
+
+// deliveryBatchSize bounds how many due deliveries are pulled from the
+// database per poll, so one slow webhook endpoint can't starve the others
+// of a chance to run in the same tick.
+const deliveryBatchSize = 50
+
+// Dispatcher is the default Publisher: it queues a db.WebhookDelivery row
+// per matching, enabled webhook and polls for due deliveries on a
+// background goroutine, sending each as a signed HTTP POST with
+// exponential backoff on failure.
+type Dispatcher struct {
+	db           *db.DB
+	client       *http.Client
+	pollInterval time.Duration
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+// NewDispatcher creates a Dispatcher backed by database. Call Start to
+// begin polling for due deliveries.
+func NewDispatcher(database *db.DB, pollInterval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		db:           database,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		pollInterval: pollInterval,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Publish looks up enabled webhooks subscribed to eventType and queues a
+// durable delivery for each. The delivery is persisted before this call
+// returns, so the event is never lost even if the process crashes before
+// the background sender picks it up.
+func (d *Dispatcher) Publish(eventType string, payload interface{}) {
+	webhooks, err := d.db.ListEnabledWebhooksForEvent(eventType)
+	if err != nil {
+		log.Printf("webhook: failed to list webhooks for event %s: %v", eventType, err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("webhook: failed to marshal payload for event %s: %v", eventType, err)
+		return
+	}
+
+	for _, wh := range webhooks {
+		delivery := &db.WebhookDelivery{
+			WebhookID: wh.ID,
+			EventType: eventType,
+			Payload:   string(body),
+			Status:    "pending",
+		}
+		if err := d.db.CreateWebhookDelivery(delivery); err != nil {
+			log.Printf("webhook: failed to queue delivery for webhook %d, event %s: %v", wh.ID, eventType, err)
+		}
+	}
+}
+
+// Start begins the background polling loop that sends due deliveries.
+func (d *Dispatcher) Start() {
+	go d.run()
+}
+
+// Stop signals the polling loop to exit and waits for it to finish.
+func (d *Dispatcher) Stop() {
+	close(d.stop)
+	<-d.done
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.sendDue()
+		}
+	}
+}
+
+func (d *Dispatcher) sendDue() {
+	deliveries, err := d.db.ListDueWebhookDeliveries(deliveryBatchSize)
+	if err != nil {
+		log.Printf("webhook: failed to list due deliveries: %v", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		wh, err := d.db.GetWebhook(delivery.WebhookID)
+		if err != nil || wh == nil {
+			// The webhook was deleted after this delivery was queued; drop it.
+			delivery.Status = "dead_letter"
+			delivery.LastError = "webhook no longer exists"
+			_ = d.db.UpdateWebhookDelivery(delivery)
+			continue
+		}
+		d.attempt(wh, delivery)
+	}
+}
+
+func (d *Dispatcher) attempt(wh *db.Webhook, delivery *db.WebhookDelivery) {
+	delivery.Attempts++
+
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		d.recordFailure(wh, delivery, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, sign(wh.Secret, []byte(delivery.Payload)))
+	if wh.AuthHeader != "" && wh.AuthToken != "" {
+		req.Header.Set(wh.AuthHeader, wh.AuthToken)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		d.recordFailure(wh, delivery, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		d.recordFailure(wh, delivery, fmt.Errorf("unexpected status %d", resp.StatusCode))
+		return
+	}
+
+	now := time.Now()
+	delivery.Status = "delivered"
+	delivery.LastError = ""
+	delivery.DeliveredAt = &now
+	if err := d.db.UpdateWebhookDelivery(delivery); err != nil {
+		log.Printf("webhook: failed to record successful delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (d *Dispatcher) recordFailure(wh *db.Webhook, delivery *db.WebhookDelivery, sendErr error) {
+	delivery.LastError = sendErr.Error()
+
+	maxAttempts := wh.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = "dead_letter"
+	} else {
+		delivery.Status = "retrying"
+		delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts))
+	}
+
+	if err := d.db.UpdateWebhookDelivery(delivery); err != nil {
+		log.Printf("webhook: failed to record failed delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// backoff returns an exponential delay (capped at 5 minutes) with up to 20%
+// jitter, so a burst of failing deliveries to the same endpoint doesn't
+// retry in lockstep.
+func backoff(attempts int) time.Duration {
+	base := time.Second * time.Duration(1<<uint(attempts))
+	if base > 5*time.Minute {
+		base = 5 * time.Minute
+	}
+	jitter := time.Duration(rand.Int63n(int64(base) / 5))
+	return base + jitter
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
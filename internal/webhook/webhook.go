@@ -0,0 +1,33 @@
+// Package webhook delivers outbound HTTP notifications for lifecycle events
+// (files discovered/converted/failed, tasks started/completed, rebuilds and
+// workflow runs finishing) to subscriber URLs stored in the database, with
+// durable retry so a delivery survives a restart between being queued and
+// being delivered.
+package webhook
+
+// Event type constants used both as the free-text values stored in a
+// db.Webhook's comma-separated Events column and as the event_type recorded
+// on each db.WebhookDelivery.
+const (
+	EventFileDiscovered    = "file.discovered"
+	EventFileConverted     = "file.converted"
+	EventFileFailed        = "file.failed"
+	EventFileRemoved       = "file.removed"
+	EventTaskStarted       = "task.started"
+	EventTaskCompleted     = "task.completed"
+	EventRebuildCompleted  = "rebuild.completed"
+	EventWorkflowRunFinish = "workflow.run.finished"
+)
+
+// Publisher receives lifecycle events to fan out to subscribed webhooks.
+// payload is marshaled to JSON as the delivered request body, so it should
+// be a value (or pointer to a value) with appropriate json tags.
+type Publisher interface {
+	Publish(eventType string, payload interface{})
+}
+
+// NopPublisher discards every event. It is the default when no dispatcher
+// is configured, so existing callers keep working unchanged.
+type NopPublisher struct{}
+
+func (NopPublisher) Publish(string, interface{}) {}
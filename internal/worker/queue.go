@@ -1,39 +1,198 @@
 package worker
 
 import (
+	"container/heap"
 	"context"
 	"sync"
+	"time"
 )
 
+// Priority is a FileIndex ID's place in Queue's multi-level priority
+// queue. Higher values are serviced first, but not exclusively: see
+// priorityWeight and Queue.popWeightedLocked.
+type Priority int
+
+const (
+	PriorityLow    Priority = 0
+	PriorityNormal Priority = 1
+	PriorityHigh   Priority = 2
+)
+
+// priorityOrder is the fixed scan order popWeightedLocked walks each
+// round; priorityWeight is how many items each priority gets per round
+// before the scheduler moves on, so PriorityHigh items are serviced 4-for-1
+// against PriorityLow and 2-for-1 against PriorityNormal, while
+// PriorityLow still gets its turn every round instead of starving outright.
+var priorityOrder = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+var priorityWeight = map[Priority]int{
+	PriorityHigh:   4,
+	PriorityNormal: 2,
+	PriorityLow:    1,
+}
+
+func clampPriority(prio int) Priority {
+	switch {
+	case prio >= int(PriorityHigh):
+		return PriorityHigh
+	case prio <= int(PriorityLow):
+		return PriorityLow
+	default:
+		return PriorityNormal
+	}
+}
+
+// requeueItem is a FileIndex ID scheduled to re-enter its priority lane at
+// readyAt, for cooperative backoff after a transient converter failure.
+type requeueItem struct {
+	id       uint
+	priority Priority
+	readyAt  time.Time
+}
+
+// requeueHeap is a min-heap of requeueItem ordered by readyAt, so the root
+// is always the next item due to rejoin its lane.
+type requeueHeap []*requeueItem
+
+func (h requeueHeap) Len() int           { return len(h) }
+func (h requeueHeap) Less(i, j int) bool { return h[i].readyAt.Before(h[j].readyAt) }
+func (h requeueHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *requeueHeap) Push(x interface{}) {
+	*h = append(*h, x.(*requeueItem))
+}
+
+func (h *requeueHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// Queue is a multi-level priority queue of FileIndex IDs awaiting
+// conversion, dequeued through a weighted fair scheduler so a flood of
+// low-priority work can't starve PriorityHigh items while PriorityLow
+// still makes steady progress. A single enqueued set dedups IDs across
+// every priority lane, the requeue heap, and items currently in flight
+// (enqueued but not yet Dequeued).
 type Queue struct {
-	ch        chan uint // FileIndex IDs
 	mu        sync.Mutex
+	cond      *sync.Cond
+	out       chan uint
+	lanes     map[Priority][]uint
+	credits   map[Priority]int
 	enqueued  map[uint]struct{}
 	accepting bool
+	paused    bool
+
+	requeue requeueHeap
+	timer   *time.Timer
 }
 
+// NewQueue creates a Queue whose output channel buffers up to buf*2+10
+// ready items, matching the original FIFO Queue's buffering.
 func NewQueue(buf int) *Queue {
-	return &Queue{
-		ch:        make(chan uint, buf*2+10),
+	q := &Queue{
+		out:       make(chan uint, buf*2+10),
+		lanes:     make(map[Priority][]uint),
+		credits:   make(map[Priority]int),
 		enqueued:  make(map[uint]struct{}),
 		accepting: true,
 	}
+	q.cond = sync.NewCond(&q.mu)
+	go q.dispatch()
+	return q
 }
 
+// Enqueue adds id at PriorityNormal. Kept for callers that don't care
+// about priority.
 func (q *Queue) Enqueue(id uint) bool {
+	return q.EnqueueWithPriority(id, int(PriorityNormal))
+}
+
+// EnqueueWithPriority adds id to the lane for prio (clamped to
+// PriorityLow..PriorityHigh). It returns false if id is already enqueued
+// or requeued, or the queue has stopped accepting new work.
+func (q *Queue) EnqueueWithPriority(id uint, prio int) bool {
+	p := clampPriority(prio)
+
 	q.mu.Lock()
 	defer q.mu.Unlock()
+
 	if !q.accepting {
 		return false
 	}
 	if _, ok := q.enqueued[id]; ok {
 		return false
 	}
+
 	q.enqueued[id] = struct{}{}
-	q.ch <- id
+	q.lanes[p] = append(q.lanes[p], id)
+	q.cond.Signal()
 	return true
 }
 
+// Requeue schedules id (already Dequeued by the caller) to re-enter its
+// priority lane after delay, for cooperative backoff when a converter
+// fails transiently. A single timer goroutine wakes exactly when the next
+// scheduled item becomes ready, rather than polling.
+func (q *Queue) Requeue(id uint, prio int, delay time.Duration) bool {
+	p := clampPriority(prio)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.accepting {
+		return false
+	}
+	if _, ok := q.enqueued[id]; ok {
+		return false
+	}
+
+	q.enqueued[id] = struct{}{}
+	heap.Push(&q.requeue, &requeueItem{id: id, priority: p, readyAt: time.Now().Add(delay)})
+	q.rescheduleTimerLocked()
+	return true
+}
+
+// rescheduleTimerLocked arms q.timer to fire when the requeue heap's
+// earliest item becomes ready. Callers must hold q.mu.
+func (q *Queue) rescheduleTimerLocked() {
+	if len(q.requeue) == 0 {
+		return
+	}
+
+	delay := time.Until(q.requeue[0].readyAt)
+	if delay < 0 {
+		delay = 0
+	}
+
+	if q.timer == nil {
+		q.timer = time.AfterFunc(delay, q.promoteReady)
+		return
+	}
+	q.timer.Reset(delay)
+}
+
+// promoteReady moves every requeue item whose readyAt has passed into its
+// priority lane, then rearms the timer for whatever is next.
+func (q *Queue) promoteReady() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for len(q.requeue) > 0 && !q.requeue[0].readyAt.After(now) {
+		item := heap.Pop(&q.requeue).(*requeueItem)
+		q.lanes[item.priority] = append(q.lanes[item.priority], item.id)
+	}
+
+	if len(q.requeue) > 0 {
+		q.rescheduleTimerLocked()
+	}
+	q.cond.Signal()
+}
+
 func (q *Queue) Dequeued(id uint) {
 	q.mu.Lock()
 	delete(q.enqueued, id)
@@ -43,17 +202,136 @@ func (q *Queue) Dequeued(id uint) {
 func (q *Queue) StopAccepting() {
 	q.mu.Lock()
 	q.accepting = false
+	q.cond.Signal()
 	q.mu.Unlock()
 }
 
-func (q *Queue) Chan() <-chan uint { return q.ch }
+// Pause blocks the dispatcher from handing out any more items until
+// Resume is called. Lanes and the requeue heap keep accumulating as usual;
+// nothing is drained or dropped.
+func (q *Queue) Pause() {
+	q.mu.Lock()
+	q.paused = true
+	q.mu.Unlock()
+}
 
+// Resume wakes the dispatcher back up after Pause.
+func (q *Queue) Resume() {
+	q.mu.Lock()
+	q.paused = false
+	q.cond.Signal()
+	q.mu.Unlock()
+}
+
+func (q *Queue) Chan() <-chan uint { return q.out }
+
+// Len reports the total number of IDs the queue is tracking: waiting in a
+// priority lane, parked in the requeue heap, or in flight (enqueued but
+// not yet Dequeued).
 func (q *Queue) Len() int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	return len(q.enqueued)
 }
 
+// LenByPriority reports how many IDs are currently waiting in each
+// priority lane. It doesn't count items parked in the requeue heap or
+// already handed out to Chan().
+func (q *Queue) LenByPriority() map[Priority]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	counts := make(map[Priority]int, len(priorityOrder))
+	for _, p := range priorityOrder {
+		counts[p] = len(q.lanes[p])
+	}
+	return counts
+}
+
 func (q *Queue) Drain(ctx context.Context) {
 	// nothing to do explicitly; pool will finish
 }
+
+// dispatch feeds q.out for the Queue's lifetime via the weighted fair
+// scheduler, blocking when nothing is ready or the queue is paused. It
+// returns once the queue has stopped accepting and fully drained.
+func (q *Queue) dispatch() {
+	for {
+		id, ok := q.next()
+		if !ok {
+			return
+		}
+		q.out <- id
+	}
+}
+
+// next blocks until an item is ready (respecting Pause), or returns false
+// once the queue has stopped accepting and emptied out.
+func (q *Queue) next() (uint, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if !q.paused {
+			if id, ok := q.popWeightedLocked(); ok {
+				return id, true
+			}
+		}
+		if !q.accepting && q.emptyLocked() {
+			return 0, false
+		}
+		q.cond.Wait()
+	}
+}
+
+func (q *Queue) emptyLocked() bool {
+	if len(q.requeue) > 0 {
+		return false
+	}
+	for _, lane := range q.lanes {
+		if len(lane) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// popWeightedLocked implements the weighted fair dequeue described on
+// Queue: each priority gets up to priorityWeight[p] items per round before
+// the scheduler moves to the next priority in priorityOrder; a lane with
+// nothing to give forfeits its remaining credits for the round instead of
+// blocking the others. Callers must hold q.mu.
+func (q *Queue) popWeightedLocked() (uint, bool) {
+	for attempt := 0; attempt < 2*len(priorityOrder); attempt++ {
+		if q.creditsExhaustedLocked() {
+			for _, p := range priorityOrder {
+				q.credits[p] = priorityWeight[p]
+			}
+		}
+
+		for _, p := range priorityOrder {
+			if q.credits[p] <= 0 {
+				continue
+			}
+			lane := q.lanes[p]
+			if len(lane) == 0 {
+				q.credits[p] = 0
+				continue
+			}
+			id := lane[0]
+			q.lanes[p] = lane[1:]
+			q.credits[p]--
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func (q *Queue) creditsExhaustedLocked() bool {
+	for _, p := range priorityOrder {
+		if q.credits[p] > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -10,23 +11,53 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ah-its-andy/jpeg2heif/internal/cluster"
+	"github.com/ah-its-andy/jpeg2heif/internal/contenthash"
 	"github.com/ah-its-andy/jpeg2heif/internal/converter"
 	"github.com/ah-its-andy/jpeg2heif/internal/db"
+	"github.com/ah-its-andy/jpeg2heif/internal/lock"
+	"github.com/ah-its-andy/jpeg2heif/internal/logging"
+	"github.com/ah-its-andy/jpeg2heif/internal/metrics"
+	"github.com/ah-its-andy/jpeg2heif/internal/progress"
 	"github.com/ah-its-andy/jpeg2heif/internal/util"
+	"github.com/ah-its-andy/jpeg2heif/internal/utils"
 	"github.com/ah-its-andy/jpeg2heif/internal/watcher"
+	"github.com/ah-its-andy/jpeg2heif/internal/webhook"
 )
 
 // Worker processes file conversion tasks
 type Worker struct {
-	db           *db.DB
+	db *db.DB
+
+	// maxWorkers, quality and preserveMeta are read from many goroutines and
+	// can be changed live via SetMaxWorkers/SetQuality, so every access goes
+	// through runtimeMu rather than reading the fields directly.
+	runtimeMu    sync.Mutex
 	maxWorkers   int
 	quality      int
 	preserveMeta bool
-	taskQueue    chan *Task
-	wg           sync.WaitGroup
-	ctx          context.Context
-	cancel       context.CancelFunc
-	md5ChunkSize int
+
+	taskQueue      chan *Task
+	wg             sync.WaitGroup
+	ctx            context.Context
+	cancel         context.CancelFunc
+	md5ChunkSize   int
+	hasher         util.FileHasher
+	reporter       progress.Reporter
+	webhooks       webhook.Publisher
+	logger         logging.Logger
+	chunker        *contenthash.Chunker
+	dedupCache     *contenthash.Manager
+	cluster        *cluster.Registry
+	locker         lock.Locker
+	stabilityDelay time.Duration
+
+	// taskEvents publishes live per-task JobEvents for SSE streaming,
+	// keyed by file path (there is no stable task ID until a TaskHistory
+	// row is inserted on completion). rebuildEvents does the same for
+	// rebuild-index jobs, keyed by the job ID the API layer generates.
+	taskEvents    *progress.Registry
+	rebuildEvents *progress.Registry
 }
 
 // Task represents a conversion task
@@ -36,22 +67,157 @@ type Task struct {
 	Timestamp time.Time
 }
 
-// New creates a new worker pool
-func New(database *db.DB, maxWorkers, quality int, preserveMeta bool, md5ChunkSize int) *Worker {
+// New creates a new worker pool. hashAlgorithm selects the FileHasher used
+// to detect whether a file's content has changed since it was last indexed
+// (see util.NewFileHasher); an unrecognized value falls back to "md5".
+func New(database *db.DB, maxWorkers, quality int, preserveMeta bool, md5ChunkSize int, hashAlgorithm string) *Worker {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	hasher, err := util.NewFileHasher(hashAlgorithm)
+	if err != nil {
+		log.Printf("Warning: %v, falling back to md5", err)
+		hasher, _ = util.NewFileHasher("md5")
+	}
+
 	return &Worker{
-		db:           database,
-		maxWorkers:   maxWorkers,
-		quality:      quality,
-		preserveMeta: preserveMeta,
-		taskQueue:    make(chan *Task, 1000),
-		ctx:          ctx,
-		cancel:       cancel,
-		md5ChunkSize: md5ChunkSize,
+		db:            database,
+		maxWorkers:    maxWorkers,
+		quality:       quality,
+		preserveMeta:  preserveMeta,
+		taskQueue:     make(chan *Task, 1000),
+		ctx:           ctx,
+		cancel:        cancel,
+		md5ChunkSize:  md5ChunkSize,
+		hasher:        hasher,
+		reporter:      progress.NopReporter{},
+		webhooks:      webhook.NopPublisher{},
+		logger:        logging.NewNop(),
+		chunker:       contenthash.NewChunker(0),
+		dedupCache:    contenthash.NewManager(1024),
+		locker:        lock.NewLocalLocker(),
+		taskEvents:    progress.NewRegistry(200),
+		rebuildEvents: progress.NewRegistry(200),
 	}
 }
 
+// SetReporter wires a progress.Reporter to receive lifecycle events for
+// every task processed by this worker pool. Must be called before Start.
+func (w *Worker) SetReporter(r progress.Reporter) {
+	if r == nil {
+		r = progress.NopReporter{}
+	}
+	w.reporter = r
+}
+
+// SetWebhooks wires a webhook.Publisher to receive lifecycle events for
+// every file event and task processed by this worker pool. Must be called
+// before Start.
+func (w *Worker) SetWebhooks(p webhook.Publisher) {
+	if p == nil {
+		p = webhook.NopPublisher{}
+	}
+	w.webhooks = p
+}
+
+// SetLogger wires a structured logging.Logger for this worker pool's
+// lifecycle and per-task log lines. Must be called before Start.
+func (w *Worker) SetLogger(l logging.Logger) {
+	if l == nil {
+		l = logging.NewNop()
+	}
+	w.logger = l
+}
+
+// SetClusterRegistry wires a cluster.Registry consulted before every
+// conversion, so a file already converted by another node in the cluster
+// can be fetched instead of recomputed locally. Pass nil (the default) to
+// disable cluster lookups. Must be called before Start.
+func (w *Worker) SetClusterRegistry(reg *cluster.Registry) {
+	w.cluster = reg
+}
+
+// SetLocker wires the lock.Locker every conversion runs under, so the same
+// file can't be converted by the same converter twice at once. Pass nil to
+// restore the default LocalLocker (in-process only). Pass a
+// lock.NewSQLiteLocker when this watch directory is served by more than one
+// jpeg2heif process, so the lease is honored cluster-wide. Must be called
+// before Start.
+func (w *Worker) SetLocker(l lock.Locker) {
+	if l == nil {
+		l = lock.NewLocalLocker()
+	}
+	w.locker = l
+}
+
+// SetStabilityDelay wires how long processTask waits, via
+// utils.WaitFileStableCtx, for a file to stop changing before hashing and
+// converting it. Zero (the default) skips the wait entirely, relying on
+// whatever stability check already happened upstream (e.g. the watcher's
+// own debounce before it ever enqueues a Task). Must be called before
+// Start.
+func (w *Worker) SetStabilityDelay(d time.Duration) {
+	w.stabilityDelay = d
+}
+
+// SetQuality changes the HEIC quality and metadata-preservation settings
+// applied to conversions started after this call returns. Safe to call
+// after Start, unlike the other Set* methods, since every conversion reads
+// these through getRuntimeOptions rather than capturing them once.
+func (w *Worker) SetQuality(quality int, preserveMeta bool) {
+	w.runtimeMu.Lock()
+	defer w.runtimeMu.Unlock()
+	w.quality = quality
+	w.preserveMeta = preserveMeta
+}
+
+// getRuntimeOptions returns the quality and preserveMeta settings currently
+// in effect, reflecting the latest SetQuality call if any.
+func (w *Worker) getRuntimeOptions() (quality int, preserveMeta bool) {
+	w.runtimeMu.Lock()
+	defer w.runtimeMu.Unlock()
+	return w.quality, w.preserveMeta
+}
+
+// SetMaxWorkers grows the worker pool to n goroutines competing for the same
+// taskQueue. Shrinking isn't supported: a running worker goroutine only
+// exits via Stop, so n below the current count is ignored. Safe to call
+// after Start.
+func (w *Worker) SetMaxWorkers(n int) {
+	w.runtimeMu.Lock()
+	defer w.runtimeMu.Unlock()
+	if n <= w.maxWorkers {
+		return
+	}
+	for id := w.maxWorkers; id < n; id++ {
+		w.wg.Add(1)
+		go w.worker(id)
+	}
+	w.maxWorkers = n
+	w.logger.Info("worker pool grown", "max_workers", n)
+}
+
+// TaskEvents returns the Publisher carrying live progress for the
+// in-progress (or most recently finished) task converting filePath, for
+// use by the API layer's per-task SSE endpoint.
+func (w *Worker) TaskEvents(filePath string) progress.Publisher {
+	return w.taskEvents.Get(filePath)
+}
+
+// RebuildEvents returns the Publisher carrying live progress for the
+// rebuild-index job identified by jobID, for use by the API layer's
+// rebuild-status SSE endpoint. The job ID is owned by the caller that
+// starts the rebuild (see api.Server.handleRebuildIndex).
+func (w *Worker) RebuildEvents(jobID string) progress.Publisher {
+	return w.rebuildEvents.Get(jobID)
+}
+
+// RemoveRebuildEvents drops jobID's buffered rebuild-index events, freeing
+// the Publisher once the API layer's janitor has evicted the corresponding
+// RebuildJob record.
+func (w *Worker) RemoveRebuildEvents(jobID string) {
+	w.rebuildEvents.Remove(jobID)
+}
+
 // Start starts the worker pool
 func (w *Worker) Start() {
 	for i := 0; i < w.maxWorkers; i++ {
@@ -59,6 +225,7 @@ func (w *Worker) Start() {
 		go w.worker(i)
 	}
 	log.Printf("Started %d conversion workers", w.maxWorkers)
+	w.logger.Info("worker pool started", "max_workers", w.maxWorkers)
 }
 
 // Stop stops the worker pool
@@ -67,22 +234,92 @@ func (w *Worker) Stop() {
 	close(w.taskQueue)
 	w.wg.Wait()
 	log.Println("All workers stopped")
+	w.logger.Info("worker pool stopped")
+}
+
+// StopWithTimeout is Stop bounded by grace: it still cancels the pool and
+// closes taskQueue immediately, but gives up waiting on w.wg after grace
+// elapses instead of blocking until every worker goroutine returns. This
+// matters because a worker partway through a task doesn't return at once:
+// cancelling w.ctx reaches the conversion only through the lease context
+// handed to the converter, and the worker still records the task's outcome
+// (file index, task history) before it returns, so a shutdown sequence
+// needs a bound on how long it's willing to wait for that before moving on. Returns true if
+// every worker finished within grace, false if it gave up early (workers
+// left running are still running; the caller has no way to force-kill
+// them short of process exit).
+func (w *Worker) StopWithTimeout(grace time.Duration) bool {
+	w.cancel()
+	close(w.taskQueue)
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("All workers stopped")
+		w.logger.Info("worker pool stopped")
+		return true
+	case <-time.After(grace):
+		log.Printf("Warning: worker pool did not drain within %v, proceeding with shutdown", grace)
+		w.logger.Warn("worker pool drain timed out", "grace_period", grace.String())
+		return false
+	}
 }
 
 // EnqueueEvent enqueues a file event for processing
 func (w *Worker) EnqueueEvent(event watcher.FileEvent) {
+	if event.Operation == "remove" {
+		w.handleRemoveEvent(event)
+		return
+	}
+
 	select {
 	case w.taskQueue <- &Task{
 		FilePath:  event.Path,
 		Operation: event.Operation,
 		Timestamp: event.Timestamp,
 	}:
+		w.reporter.Report(progress.Event{
+			Type:      progress.EventTaskQueued,
+			FilePath:  event.Path,
+			Timestamp: time.Now(),
+		})
+		w.webhooks.Publish(webhook.EventFileDiscovered, map[string]interface{}{
+			"file_path": event.Path,
+			"operation": event.Operation,
+			"timestamp": event.Timestamp,
+		})
+		metrics.QueueDepth.Set(float64(len(w.taskQueue)))
 	case <-w.ctx.Done():
 	default:
 		log.Printf("Warning: task queue is full, dropping task for %s", event.Path)
+		w.logger.Warn("task queue full, dropping task", "file_path", event.Path)
 	}
 }
 
+// handleRemoveEvent handles a watcher.FileEvent with Operation "remove"
+// (see watcher.Watcher.handleRemove): there's no file left to convert, so
+// instead of queuing a doomed conversion task, it drops the file's index
+// entry (and whatever cached artifacts DeleteFileIndex cleans up with it)
+// and publishes EventFileRemoved, giving webhook subscribers - the
+// established extension point for cache/thumbnail invalidation elsewhere in
+// this codebase - a chance to react.
+func (w *Worker) handleRemoveEvent(event watcher.FileEvent) {
+	if err := w.db.DeleteFileIndex(event.Path); err != nil {
+		log.Printf("Failed to remove file index entry for %s: %v", event.Path, err)
+		w.logger.Warn("failed to remove file index entry", "file_path", event.Path, "error", err.Error())
+	}
+	w.webhooks.Publish(webhook.EventFileRemoved, map[string]interface{}{
+		"file_path": event.Path,
+		"timestamp": event.Timestamp,
+	})
+	w.logger.Info("file removed", "file_path", event.Path)
+}
+
 // worker is the worker goroutine
 func (w *Worker) worker(id int) {
 	defer w.wg.Done()
@@ -95,147 +332,602 @@ func (w *Worker) worker(id int) {
 			if !ok {
 				return
 			}
+			metrics.QueueDepth.Set(float64(len(w.taskQueue)))
+			metrics.WorkerBusy.Inc()
 			w.processTask(id, task)
+			metrics.WorkerBusy.Dec()
 		}
 	}
 }
 
 // processTask processes a single conversion task
 func (w *Worker) processTask(workerID int, task *Task) {
+	quality, preserveMeta := w.getRuntimeOptions()
 	startTime := time.Now()
-	log.Printf("[Worker %d] ========== Processing Task ==========", workerID)
-	log.Printf("[Worker %d] File: %s", workerID, task.FilePath)
-	log.Printf("[Worker %d] Operation: %s", workerID, task.Operation)
+	tlog := w.logger.With("task_id", task.FilePath)
+	tlog.Info("task started", "worker_id", workerID, "operation", task.Operation)
+	w.reporter.Report(progress.Event{
+		Type:      progress.EventTaskStarted,
+		WorkerID:  workerID,
+		FilePath:  task.FilePath,
+		Message:   task.Operation,
+		Timestamp: startTime,
+	})
+
+	pub := w.taskEvents.Get(task.FilePath)
+	pub.Publish(progress.JobEvent{
+		Type:      progress.JobEventStateChange,
+		State:     "started",
+		Path:      task.FilePath,
+		Timestamp: startTime,
+	})
+	w.webhooks.Publish(webhook.EventTaskStarted, map[string]interface{}{
+		"file_path": task.FilePath,
+		"timestamp": startTime,
+	})
+	defer func() {
+		// Give a client that's mid-subscribe a moment to pick up the final
+		// event before the buffer is reclaimed.
+		jobID := task.FilePath
+		time.AfterFunc(30*time.Second, func() { w.taskEvents.Remove(jobID) })
+	}()
 
-	// Calculate file MD5
-	log.Printf("[Worker %d] Calculating MD5 hash...", workerID)
-	md5Hash, err := util.CalculateMD5(task.FilePath, w.md5ChunkSize)
+	// Wait for the file to stop changing before reading it, in case it's
+	// still being written (e.g. a Task enqueued by something other than
+	// the watcher's own debounced events, such as ResetStaleProcessing
+	// resuming a file that was mid-write when the process last crashed).
+	if w.stabilityDelay > 0 {
+		if err := utils.WaitFileStableCtx(w.ctx, task.FilePath, w.stabilityDelay); err != nil {
+			w.finish(workerID, task.FilePath, "failed", time.Since(startTime), fmt.Sprintf("wait for stable file: %v", err))
+			w.recordFailure(task.FilePath, "", err, time.Since(startTime), "")
+			pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "failed", Path: task.FilePath, Message: err.Error(), Timestamp: time.Now()})
+			w.publishFileFailed(task.FilePath, "", err)
+			return
+		}
+	}
+
+	// Calculate file content hash
+	md5Hash, err := w.hasher.Hash(task.FilePath, w.md5ChunkSize)
 	if err != nil {
-		log.Printf("[Worker %d] ❌ Failed to calculate MD5: %v", workerID, err)
+		w.finish(workerID, task.FilePath, "failed", time.Since(startTime), fmt.Sprintf("hash failed: %v", err))
 		w.recordFailure(task.FilePath, "", err, time.Since(startTime), "")
+		pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "failed", Path: task.FilePath, Message: err.Error(), Timestamp: time.Now()})
+		w.publishFileFailed(task.FilePath, "", err)
 		return
 	}
-	log.Printf("[Worker %d] MD5: %s", workerID, md5Hash)
+	w.reporter.Report(progress.Event{
+		Type:      progress.EventHashProgress,
+		WorkerID:  workerID,
+		FilePath:  task.FilePath,
+		Message:   md5Hash,
+		Timestamp: time.Now(),
+	})
 
 	// Check if file already processed
-	log.Printf("[Worker %d] Checking if file already processed...", workerID)
 	existingFile, err := w.db.GetFileIndex(task.FilePath)
 	if err != nil {
-		log.Printf("[Worker %d] ❌ Database error: %v", workerID, err)
-		w.recordFailure(task.FilePath, "", fmt.Errorf("database error: %w", err), time.Since(startTime), "")
+		err = fmt.Errorf("database error: %w", err)
+		w.finish(workerID, task.FilePath, "failed", time.Since(startTime), err.Error())
+		w.recordFailure(task.FilePath, "", err, time.Since(startTime), "")
+		pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "failed", Path: task.FilePath, Message: err.Error(), Timestamp: time.Now()})
+		w.publishFileFailed(task.FilePath, "", err)
 		return
 	}
 
-	if existingFile != nil && existingFile.Status == "success" && existingFile.FileMD5 == md5Hash {
-		log.Printf("[Worker %d] ⏭️  File already processed successfully (MD5 match)", workerID)
-		// Record as skipped task
+	if existingFile != nil && existingFile.Status == "success" && existingFile.HashAlgo == w.hasher.Algorithm() && existingFile.FileMD5 == md5Hash {
+		w.finish(workerID, task.FilePath, "skipped", time.Since(startTime), "already processed (hash match)")
 		w.recordSkipped(task.FilePath, existingFile.ConverterName, time.Since(startTime), "File already processed successfully")
+		pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "skipped", Path: task.FilePath, Timestamp: time.Now()})
+		w.webhooks.Publish(webhook.EventTaskCompleted, map[string]interface{}{
+			"file_path": task.FilePath,
+			"status":    "skipped",
+			"timestamp": time.Now(),
+		})
 		return
 	}
 
-	if existingFile != nil {
-		log.Printf("[Worker %d] Existing file index: status=%s, md5=%s", workerID, existingFile.Status, existingFile.FileMD5)
-	} else {
-		log.Printf("[Worker %d] No existing file index found", workerID)
-	}
-
-	// Find appropriate converter
-	log.Printf("[Worker %d] Finding converter...", workerID)
-	conv, err := converter.FindConverter(task.FilePath, "")
+	// Find appropriate converter. The watcher only knows task.FilePath's
+	// extension, so sniff the actual content here to give CanConvert and
+	// MatchPriority rules keyed on mime type (e.g. a workflow's
+	// inputs.match) something real to match against.
+	srcMime := util.SniffMime(task.FilePath)
+	conv, err := converter.FindConverter(task.FilePath, srcMime)
 	if err != nil {
-		log.Printf("[Worker %d] ❌ No converter found: %v", workerID, err)
+		w.finish(workerID, task.FilePath, "failed", time.Since(startTime), err.Error())
 		w.recordFailure(task.FilePath, "", err, time.Since(startTime), "")
+		pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "failed", Path: task.FilePath, Message: err.Error(), Timestamp: time.Now()})
+		w.publishFileFailed(task.FilePath, "", err)
 		return
 	}
 
 	converterName := conv.Name()
-	log.Printf("[Worker %d] ✅ Using converter: %s", workerID, converterName)
-	log.Printf("[Worker %d] Target format: %s", workerID, conv.TargetFormat())
-
-	// Log if this is a workflow converter
-	if strings.HasPrefix(converterName, "workflow:") {
-		log.Printf("[Worker %d] 📋 This is a WORKFLOW converter", workerID)
-	} else {
-		log.Printf("[Worker %d] 🔧 This is a BUILTIN converter", workerID)
-	}
+	optionsHash := converter.ConvertOptions{Quality: quality, PreserveMetadata: preserveMeta}.OptionsHash()
 
 	// Update status to processing
-	log.Printf("[Worker %d] Updating file index to 'processing' status...", workerID)
 	fileIndex := &db.FileIndex{
 		FilePath:      task.FilePath,
 		FileMD5:       md5Hash,
+		HashAlgo:      w.hasher.Algorithm(),
 		Status:        "processing",
 		ConverterName: converterName,
+		OptionsHash:   optionsHash,
 	}
 	if err := w.db.UpsertFileIndex(fileIndex); err != nil {
-		log.Printf("[Worker %d] ⚠️  Failed to update file index: %v", workerID, err)
+		log.Printf("[Worker %d] failed to update file index to processing: %v", workerID, err)
 	}
 
 	// Generate output path
 	outputPath := w.generateOutputPath(task.FilePath, conv.TargetFormat())
-	log.Printf("[Worker %d] Output path: %s", workerID, outputPath)
+
+	// Consult the cluster first: if another node already converted this
+	// exact content through this converter, fetch its output instead of
+	// running the (possibly expensive) conversion ourselves.
+	if peerNode, ok := w.tryClusterCache(workerID, task.FilePath, md5Hash, converterName, outputPath); ok {
+		duration := time.Since(startTime)
+
+		fileIndex.Status = "success"
+		if err := w.db.UpsertFileIndex(fileIndex); err != nil {
+			log.Printf("[Worker %d] failed to update file index to success: %v", workerID, err)
+		}
+
+		taskHistory := &db.TaskHistory{
+			FilePath:      task.FilePath,
+			ConverterName: converterName,
+			Status:        "success",
+			DurationMs:    duration.Milliseconds(),
+			ConsoleOutput: fmt.Sprintf("source=peer:%s reused cluster conversion for content hash %s", peerNode, md5Hash),
+		}
+		if err := w.db.InsertTaskHistory(taskHistory); err != nil {
+			log.Printf("[Worker %d] failed to insert task history: %v", workerID, err)
+		}
+
+		w.finish(workerID, task.FilePath, "success", duration, outputPath)
+		pub.Publish(progress.JobEvent{Type: progress.JobEventFileDone, Path: task.FilePath, Message: "reused cluster peer conversion", Timestamp: time.Now()})
+		pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "completed", Path: task.FilePath, Timestamp: time.Now()})
+		w.publishFileConverted(task.FilePath, converterName, outputPath)
+		return
+	}
+
+	// Check the content-addressable artifact cache: if a prior run already
+	// converted a byte-identical source file (fileIndex.CacheHit, set by the
+	// UpsertFileIndex call above) through this converter with these exact
+	// options, reuse its output instead of re-running the converter. Unlike
+	// the visual dedup cache below, this only matches exact file duplicates,
+	// not re-exports with the same pixels, but it's cheaper to check since
+	// md5Hash is already computed.
+	if fileIndex.CacheHit {
+		if ok := w.tryContentCache(workerID, task.FilePath, md5Hash, converterName, optionsHash, outputPath); ok {
+			duration := time.Since(startTime)
+
+			fileIndex.Status = "success"
+			if err := w.db.UpsertFileIndex(fileIndex); err != nil {
+				log.Printf("[Worker %d] failed to update file index to success: %v", workerID, err)
+			}
+
+			taskHistory := &db.TaskHistory{
+				FilePath:      task.FilePath,
+				ConverterName: converterName,
+				Status:        "success",
+				DurationMs:    duration.Milliseconds(),
+				ConsoleOutput: fmt.Sprintf("Reused cached conversion for content hash %s", md5Hash),
+			}
+			if err := w.db.InsertTaskHistory(taskHistory); err != nil {
+				log.Printf("[Worker %d] failed to insert task history: %v", workerID, err)
+			}
+
+			w.finish(workerID, task.FilePath, "success", duration, outputPath)
+			pub.Publish(progress.JobEvent{Type: progress.JobEventFileDone, Path: task.FilePath, Message: "reused cached conversion", Timestamp: time.Now()})
+			pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "completed", Path: task.FilePath, Timestamp: time.Now()})
+			w.publishFileConverted(task.FilePath, converterName, outputPath)
+			return
+		}
+	}
+
+	// Check the visual dedup cache: if a prior run already converted a file
+	// with the same pixels through this converter+quality, reuse its output
+	// instead of re-running the (often expensive) external conversion.
+	if visualDigest, ok := w.tryDedupCache(workerID, task.FilePath, converterName, outputPath); ok {
+		duration := time.Since(startTime)
+
+		fileIndex.Status = "success"
+		if err := w.db.UpsertFileIndex(fileIndex); err != nil {
+			log.Printf("[Worker %d] failed to update file index to success: %v", workerID, err)
+		}
+
+		taskHistory := &db.TaskHistory{
+			FilePath:      task.FilePath,
+			ConverterName: converterName,
+			Status:        "success",
+			DurationMs:    duration.Milliseconds(),
+			ConsoleOutput: fmt.Sprintf("Reused cached conversion for visual digest %s", visualDigest),
+		}
+		if err := w.db.InsertTaskHistory(taskHistory); err != nil {
+			log.Printf("[Worker %d] failed to insert task history: %v", workerID, err)
+		}
+
+		w.finish(workerID, task.FilePath, "success", duration, outputPath)
+		pub.Publish(progress.JobEvent{Type: progress.JobEventFileDone, Path: task.FilePath, Message: "reused cached conversion", Timestamp: time.Now()})
+		pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "completed", Path: task.FilePath, Timestamp: time.Now()})
+		w.publishFileConverted(task.FilePath, converterName, outputPath)
+		return
+	}
+
+	// Acquire a lease so no other process (or, with a LocalLocker, goroutine
+	// in this one) can convert task.FilePath via converterName at the same
+	// time. The lease's own context replaces w.ctx for the conversion call,
+	// so a lost lease (another owner stole it after this one's refreshes
+	// stopped) aborts the in-flight external tool process immediately
+	// rather than letting it run to completion and overwrite the winner's
+	// output.
+	lease, err := w.locker.Acquire(w.ctx, task.FilePath, converterName)
+	if err != nil {
+		w.finish(workerID, task.FilePath, "failed", time.Since(startTime), err.Error())
+		w.recordFailure(task.FilePath, converterName, err, time.Since(startTime), "")
+		pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "failed", Path: task.FilePath, Message: err.Error(), Timestamp: time.Now()})
+		w.publishFileFailed(task.FilePath, converterName, err)
+		return
+	}
+	defer lease.Release()
 
 	// Perform conversion
-	log.Printf("[Worker %d] Starting conversion...", workerID)
 	opts := converter.ConvertOptions{
-		Quality:          w.quality,
-		PreserveMetadata: w.preserveMeta,
+		Quality:          quality,
+		PreserveMetadata: preserveMeta,
 		TempDir:          os.TempDir(),
 		Timeout:          10 * time.Minute,
+		TaskID:           task.FilePath,
+		ContentHash:      md5Hash,
+		HashAlgorithm:    w.hasher.Algorithm(),
 	}
-	log.Printf("[Worker %d] Conversion options: quality=%d, preserveMetadata=%v", workerID, w.quality, w.preserveMeta)
 
-	result, err := conv.Convert(w.ctx, task.FilePath, outputPath, opts)
+	var result converter.MetaResult
+	if progConv, ok := conv.(converter.ProgressConverter); ok {
+		result, err = progConv.ConvertWithProgress(lease.Context(), task.FilePath, outputPath, opts, pub)
+	} else {
+		result, err = conv.Convert(lease.Context(), task.FilePath, outputPath, opts)
+	}
 	duration := time.Since(startTime)
 
+	for _, line := range strings.Split(strings.TrimRight(result.ConversionLog, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		w.reporter.Report(progress.Event{
+			Type:      progress.EventConvertStdout,
+			WorkerID:  workerID,
+			FilePath:  task.FilePath,
+			Converter: converterName,
+			Message:   line,
+			Timestamp: time.Now(),
+		})
+	}
+
 	if err != nil {
-		log.Printf("[Worker %d] ❌ Conversion failed (duration: %v): %v", workerID, duration, err)
+		w.finish(workerID, task.FilePath, "failed", duration, err.Error())
 		// Pass the conversion log even on failure for detailed error information
 		w.recordFailure(task.FilePath, converterName, err, duration, result.ConversionLog)
+		pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "failed", Path: task.FilePath, Message: err.Error(), Timestamp: time.Now()})
+		w.publishFileFailed(task.FilePath, converterName, err)
+		tlog.Error("task failed", "converter", converterName, "duration_ms", duration.Milliseconds(), "error", err.Error())
 		return
 	}
 
-	log.Printf("[Worker %d] ✅ Conversion successful (duration: %v)", workerID, duration)
-	log.Printf("[Worker %d] Metadata preserved: %v", workerID, result.MetadataPreserved)
-	if result.MetadataSummary != "" {
-		log.Printf("[Worker %d] Metadata summary: %s", workerID, result.MetadataSummary)
-	}
+	w.reporter.Report(progress.Event{
+		Type:      progress.EventMetadataVerified,
+		WorkerID:  workerID,
+		FilePath:  task.FilePath,
+		Converter: converterName,
+		Status:    fmt.Sprintf("preserved=%v", result.MetadataPreserved),
+		Message:   result.MetadataSummary,
+		Timestamp: time.Now(),
+	})
 
 	// Update file index with success
-	log.Printf("[Worker %d] Updating file index to 'success' status...", workerID)
 	fileIndex.Status = "success"
 	fileIndex.MetadataPreserved = result.MetadataPreserved
 	fileIndex.MetadataSummary = result.MetadataSummary
 	if err := w.db.UpsertFileIndex(fileIndex); err != nil {
-		log.Printf("[Worker %d] ⚠️  Failed to update file index: %v", workerID, err)
+		log.Printf("[Worker %d] failed to update file index to success: %v", workerID, err)
+	}
+
+	if len(result.TagDiffs) > 0 {
+		tags := make([]db.FileMetadata, len(result.TagDiffs))
+		for i, diff := range result.TagDiffs {
+			tags[i] = db.FileMetadata{
+				TagName:     diff.Tag,
+				SourceValue: diff.SourceValue,
+				OutputValue: diff.OutputValue,
+				Preserved:   diff.Preserved,
+			}
+		}
+		if err := w.db.ReplaceFileMetadata(fileIndex.ID, tags); err != nil {
+			log.Printf("[Worker %d] failed to record file metadata diff: %v", workerID, err)
+		}
 	}
 
 	// Record task history with console output
-	log.Printf("[Worker %d] Recording task history...", workerID)
 	taskHistory := &db.TaskHistory{
 		FilePath:      task.FilePath,
 		ConverterName: converterName,
 		Status:        "success",
 		DurationMs:    duration.Milliseconds(),
 		ConsoleOutput: result.ConversionLog,
+		Annotations:   result.Annotations,
 	}
 	if err := w.db.InsertTaskHistory(taskHistory); err != nil {
-		log.Printf("[Worker %d] ⚠️  Failed to insert task history: %v", workerID, err)
+		log.Printf("[Worker %d] failed to insert task history: %v", workerID, err)
+	}
+
+	w.storeDedupCache(task.FilePath, converterName, outputPath)
+	w.storeContentArtifact(md5Hash, converterName, optionsHash, outputPath, result.OutputMD5)
+	if w.cluster != nil {
+		if err := w.cluster.RecordOutput(task.FilePath, md5Hash, w.hasher.Algorithm(), converterName, outputPath); err != nil {
+			log.Printf("[Worker %d] failed to record cluster output: %v", workerID, err)
+		}
+	}
+
+	w.finish(workerID, task.FilePath, "success", duration, outputPath)
+	pub.Publish(progress.JobEvent{Type: progress.JobEventFileDone, Path: task.FilePath, Message: outputPath, Timestamp: time.Now()})
+	pub.Publish(progress.JobEvent{Type: progress.JobEventStateChange, State: "completed", Path: task.FilePath, Timestamp: time.Now()})
+	w.publishFileConverted(task.FilePath, converterName, outputPath)
+	tlog.Info("task completed", "converter", converterName, "duration_ms", duration.Milliseconds(), "output_path", outputPath)
+}
+
+// publishFileConverted notifies webhook subscribers that a file finished
+// converting successfully, followed by the generic task.completed event.
+func (w *Worker) publishFileConverted(filePath, converterName, outputPath string) {
+	now := time.Now()
+	w.webhooks.Publish(webhook.EventFileConverted, map[string]interface{}{
+		"file_path":      filePath,
+		"converter_name": converterName,
+		"output_path":    outputPath,
+		"timestamp":      now,
+	})
+	w.webhooks.Publish(webhook.EventTaskCompleted, map[string]interface{}{
+		"file_path": filePath,
+		"status":    "success",
+		"timestamp": now,
+	})
+}
+
+// publishFileFailed notifies webhook subscribers that a file failed to
+// convert, followed by the generic task.completed event.
+func (w *Worker) publishFileFailed(filePath, converterName string, taskErr error) {
+	now := time.Now()
+	w.webhooks.Publish(webhook.EventFileFailed, map[string]interface{}{
+		"file_path":      filePath,
+		"converter_name": converterName,
+		"error":          taskErr.Error(),
+		"timestamp":      now,
+	})
+	w.webhooks.Publish(webhook.EventTaskCompleted, map[string]interface{}{
+		"file_path": filePath,
+		"status":    "failed",
+		"timestamp": now,
+	})
+}
+
+// tryClusterCache consults the cluster registry (if wired via
+// SetClusterRegistry) for a peer that already converted srcPath's content
+// through converterName, fetching and verifying its output to outputPath on
+// a hit. It returns the peer's name and whether a hit was applied; the
+// caller falls back to a normal conversion (and the dedup cache below)
+// otherwise. Unlike tryDedupCache, entries are keyed by the source file's
+// exact content hash rather than a decoded-pixel visual digest, since
+// gossiped entries carry no decoded image data to digest.
+func (w *Worker) tryClusterCache(workerID int, srcPath, md5Hash, converterName, outputPath string) (string, bool) {
+	if w.cluster == nil {
+		return "", false
+	}
+
+	entry, ok := w.cluster.Consult(md5Hash, w.hasher.Algorithm(), converterName)
+	if !ok {
+		return "", false
+	}
+
+	if err := w.cluster.Fetch(w.ctx, entry, outputPath); err != nil {
+		log.Printf("[Worker %d] cluster fetch of %s from peer %s failed, falling back to local conversion: %v", workerID, srcPath, entry.PeerNode, err)
+		return "", false
+	}
+
+	w.cluster.RecordHit(md5Hash, converterName, entry.PeerNode)
+
+	w.reporter.Report(progress.Event{
+		Type:      progress.EventCacheHit,
+		WorkerID:  workerID,
+		FilePath:  srcPath,
+		Converter: converterName,
+		Message:   fmt.Sprintf("reused cluster peer %s's conversion (content hash %s)", entry.PeerNode, md5Hash),
+		Timestamp: time.Now(),
+	})
+
+	return entry.PeerNode, true
+}
+
+// tryContentCache looks up the converted artifact cache for the source
+// file's exact content hash, converterName and optionsHash and, on a hit
+// whose cached output still exists on disk, links or copies it to
+// outputPath so the caller can skip re-running the converter.
+func (w *Worker) tryContentCache(workerID int, srcPath, md5Hash, converterName, optionsHash, outputPath string) bool {
+	artifact, err := w.db.LookupArtifact(md5Hash, converterName, optionsHash)
+	if err != nil || artifact == nil {
+		return false
+	}
+
+	if artifact.OutputPath == outputPath {
+		return false
+	}
+
+	if err := linkOrCopyFile(artifact.OutputPath, outputPath); err != nil {
+		return false
+	}
+
+	w.reporter.Report(progress.Event{
+		Type:      progress.EventCacheHit,
+		WorkerID:  workerID,
+		FilePath:  srcPath,
+		Converter: converterName,
+		Message:   fmt.Sprintf("reused %s (content hash %s)", artifact.OutputPath, md5Hash),
+		Timestamp: time.Now(),
+	})
+
+	return true
+}
+
+// storeContentArtifact records a successful conversion's output under its
+// (source content hash, converter, options hash) key so future byte-identical
+// source files converted with the same settings can reuse it.
+func (w *Worker) storeContentArtifact(md5Hash, converterName, optionsHash, outputPath, outputMD5 string) {
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		return
+	}
+
+	if err := w.db.RegisterArtifact(&db.ConvertedArtifact{
+		FileMD5:       md5Hash,
+		ConverterName: converterName,
+		OptionsHash:   optionsHash,
+		OutputPath:    outputPath,
+		OutputSize:    info.Size(),
+		OutputMD5:     outputMD5,
+	}); err != nil {
+		log.Printf("failed to persist converted artifact for content hash %s: %v", md5Hash, err)
 	}
+}
 
-	log.Printf("[Worker %d] ✅ Task completed successfully: %s -> %s", workerID, task.FilePath, outputPath)
-	log.Printf("[Worker %d] ==========================================", workerID)
+// tryDedupCache looks up the visual dedup cache for task.FilePath and, on a
+// hit whose cached output still exists on disk, links or copies it to
+// outputPath so the caller can skip re-running the converter. It returns the
+// computed visual digest (for logging) and whether a cache hit was applied.
+func (w *Worker) tryDedupCache(workerID int, srcPath, converterName, outputPath string) (string, bool) {
+	visualDigest, err := contenthash.VisualDigest(srcPath, w.chunker)
+	if err != nil {
+		// Not every input is a decodable still image (or it may be
+		// corrupt); dedup is a best-effort optimization, so fall back to a
+		// normal conversion rather than failing the task.
+		return "", false
+	}
+
+	quality, _ := w.getRuntimeOptions()
+	entry, ok := w.dedupCache.Get(visualDigest, converterName, quality)
+	if !ok {
+		dbEntry, err := w.db.GetVisualCacheEntry(visualDigest, converterName, quality)
+		if err != nil || dbEntry == nil {
+			return visualDigest, false
+		}
+		entry = contenthash.CacheEntry{
+			VisualDigest: dbEntry.VisualDigest,
+			Converter:    dbEntry.Converter,
+			Quality:      dbEntry.Quality,
+			OutputPath:   dbEntry.OutputPath,
+		}
+		w.dedupCache.Put(entry)
+	}
+
+	if entry.OutputPath == outputPath {
+		return visualDigest, false
+	}
+
+	if err := linkOrCopyFile(entry.OutputPath, outputPath); err != nil {
+		return visualDigest, false
+	}
+
+	w.reporter.Report(progress.Event{
+		Type:      progress.EventCacheHit,
+		WorkerID:  workerID,
+		FilePath:  srcPath,
+		Converter: converterName,
+		Message:   fmt.Sprintf("reused %s (visual digest %s)", entry.OutputPath, visualDigest),
+		Timestamp: time.Now(),
+	})
+
+	return visualDigest, true
+}
+
+// storeDedupCache records a successful conversion's output under its visual
+// digest so future files with identical pixels can reuse it.
+func (w *Worker) storeDedupCache(srcPath, converterName, outputPath string) {
+	visualDigest, err := contenthash.VisualDigest(srcPath, w.chunker)
+	if err != nil {
+		return
+	}
+
+	quality, _ := w.getRuntimeOptions()
+	entry := contenthash.CacheEntry{
+		VisualDigest: visualDigest,
+		Converter:    converterName,
+		Quality:      quality,
+		OutputPath:   outputPath,
+	}
+	w.dedupCache.Put(entry)
+
+	if err := w.db.UpsertVisualCacheEntry(&db.VisualCacheEntry{
+		VisualDigest: visualDigest,
+		Converter:    converterName,
+		Quality:      quality,
+		OutputPath:   outputPath,
+		SourcePath:   srcPath,
+	}); err != nil {
+		log.Printf("failed to persist visual cache entry for %s: %v", srcPath, err)
+	}
+}
+
+// linkOrCopyFile hardlinks dst to src's contents, falling back to a byte
+// copy when the two paths aren't on the same filesystem.
+func linkOrCopyFile(src, dst string) error {
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	os.Remove(dst)
+
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// finish reports the terminal TaskFinished event for a task.
+func (w *Worker) finish(workerID int, filePath, status string, duration time.Duration, message string) {
+	w.reporter.Report(progress.Event{
+		Type:       progress.EventTaskFinished,
+		WorkerID:   workerID,
+		FilePath:   filePath,
+		Status:     status,
+		Message:    message,
+		DurationMs: duration.Milliseconds(),
+		Timestamp:  time.Now(),
+	})
 }
 
 // recordFailure records a conversion failure
 func (w *Worker) recordFailure(filePath, converterName string, err error, duration time.Duration, conversionLog string) {
-	// Calculate MD5 if not already done
-	md5Hash, _ := util.CalculateMD5(filePath, w.md5ChunkSize)
+	// Calculate hash if not already done
+	md5Hash, _ := w.hasher.Hash(filePath, w.md5ChunkSize)
 
 	// Update file index
 	fileIndex := &db.FileIndex{
 		FilePath:      filePath,
 		FileMD5:       md5Hash,
+		HashAlgo:      w.hasher.Algorithm(),
 		Status:        "failed",
 		ConverterName: converterName,
 	}
@@ -299,8 +991,23 @@ func (w *Worker) generateOutputPath(srcPath, targetFormat string) string {
 	return filepath.Join(outputDir, newFileName) // /a/b/heic/photo.heic
 }
 
-// RebuildIndex rebuilds the file index for all watched directories
-func (w *Worker) RebuildIndex(watchDirs []string, converterFilter string) error {
+// RebuildIndex rebuilds the file index for all watched directories. pub
+// receives live progress/file_done/log events as the scan proceeds; pass
+// progress.NopPublisher{} if no caller is attached to stream them. ctx lets
+// the caller cancel a rebuild already in flight (e.g. via the API's
+// POST .../cancel endpoint); RebuildIndex checks it between files and
+// returns ctx.Err() as soon as it's cancelled, rather than after walking
+// every remaining directory.
+func (w *Worker) RebuildIndex(ctx context.Context, watchDirs []string, converterFilter string, pub progress.Publisher) error {
+	if pub == nil {
+		pub = progress.NopPublisher{}
+	}
+	rebuildStart := time.Now()
+	w.reporter.Report(progress.Event{
+		Type:      progress.EventTaskStarted,
+		Message:   fmt.Sprintf("rebuild-index converter=%q dirs=%v", converterFilter, watchDirs),
+		Timestamp: rebuildStart,
+	})
 	log.Printf("Starting index rebuild (converter filter: %s)", converterFilter)
 	log.Printf("Watched directories: %v", watchDirs)
 
@@ -327,12 +1034,20 @@ func (w *Worker) RebuildIndex(watchDirs []string, converterFilter string) error
 	count := 0
 	scannedFiles := 0
 	skippedDirs := 0
+	cancelled := false
 
 	for _, dir := range watchDirs {
+		if ctx.Err() != nil {
+			cancelled = true
+			break
+		}
 		log.Printf("Scanning directory: %s", dir)
 		dirCount := 0
 
 		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if err != nil {
 				log.Printf("Error accessing path %s: %v", path, err)
 				return nil
@@ -348,17 +1063,24 @@ func (w *Worker) RebuildIndex(watchDirs []string, converterFilter string) error
 
 			scannedFiles++
 			log.Printf("  [FILE] Checking: %s (size: %d bytes)", path, info.Size())
+			pub.Publish(progress.JobEvent{
+				Type:      progress.JobEventProgress,
+				Processed: scannedFiles,
+				Path:      path,
+				Timestamp: time.Now(),
+			})
 
 			// Check if file should be processed
+			srcMime := util.SniffMime(path)
 			if conv != nil {
-				if !conv.CanConvert(path, "") {
+				if !conv.CanConvert(path, srcMime) {
 					log.Printf("    ❌ Skipped: converter '%s' cannot convert this file", conv.Name())
 					return nil
 				}
 				log.Printf("    ✅ Converter '%s' can convert this file", conv.Name())
 			} else {
 				// Check if any converter can handle it
-				foundConv, err := converter.FindConverter(path, "")
+				foundConv, err := converter.FindConverter(path, srcMime)
 				if err != nil {
 					log.Printf("    ❌ Skipped: no converter found for this file - %v", err)
 					return nil
@@ -366,19 +1088,19 @@ func (w *Worker) RebuildIndex(watchDirs []string, converterFilter string) error
 				log.Printf("    ✅ Found converter: %s", foundConv.Name())
 			}
 
-			// Calculate MD5
-			log.Printf("    📊 Calculating MD5...")
-			md5Hash, err := util.CalculateMD5(path, w.md5ChunkSize)
+			// Calculate content hash
+			log.Printf("    📊 Calculating %s...", w.hasher.Algorithm())
+			md5Hash, err := w.hasher.Hash(path, w.md5ChunkSize)
 			if err != nil {
-				log.Printf("    ❌ Failed to calculate MD5: %v", err)
+				log.Printf("    ❌ Failed to calculate %s: %v", w.hasher.Algorithm(), err)
 				return nil
 			}
-			log.Printf("    📊 MD5: %s", md5Hash)
+			log.Printf("    📊 %s: %s", w.hasher.Algorithm(), md5Hash)
 
-			// Check if already indexed with same MD5
+			// Check if already indexed with same hash
 			existing, err := w.db.GetFileIndex(path)
-			if err == nil && existing != nil && existing.FileMD5 == md5Hash {
-				log.Printf("    ⏭️  Skipped: already indexed with same MD5 (status: %s)", existing.Status)
+			if err == nil && existing != nil && existing.HashAlgo == w.hasher.Algorithm() && existing.FileMD5 == md5Hash {
+				log.Printf("    ⏭️  Skipped: already indexed with same hash (status: %s)", existing.Status)
 				return nil
 			}
 
@@ -387,7 +1109,7 @@ func (w *Worker) RebuildIndex(watchDirs []string, converterFilter string) error
 			if conv != nil {
 				converterName = conv.Name()
 			} else {
-				if c, err := converter.FindConverter(path, ""); err == nil {
+				if c, err := converter.FindConverter(path, srcMime); err == nil {
 					converterName = c.Name()
 				}
 			}
@@ -396,6 +1118,7 @@ func (w *Worker) RebuildIndex(watchDirs []string, converterFilter string) error
 			fileIndex := &db.FileIndex{
 				FilePath:      path,
 				FileMD5:       md5Hash,
+				HashAlgo:      w.hasher.Algorithm(),
 				Status:        "pending",
 				ConverterName: converterName,
 			}
@@ -406,23 +1129,54 @@ func (w *Worker) RebuildIndex(watchDirs []string, converterFilter string) error
 			}
 
 			log.Printf("    ✅ Successfully indexed")
+			pub.Publish(progress.JobEvent{
+				Type:      progress.JobEventFileDone,
+				Path:      path,
+				Message:   fmt.Sprintf("indexed (converter: %s)", converterName),
+				Timestamp: time.Now(),
+			})
 			count++
 			dirCount++
 			return nil
 		})
 
 		if err != nil {
+			if err == context.Canceled || err == context.DeadlineExceeded {
+				cancelled = true
+				log.Printf("Rebuild cancelled while scanning %s", dir)
+				break
+			}
 			log.Printf("Error walking directory %s: %v", dir, err)
 		}
 
 		log.Printf("Completed scanning %s: %d files indexed from this directory", dir, dirCount)
 	}
 
+	if cancelled {
+		log.Printf("Index rebuild cancelled after scanning %d files (%d indexed)", scannedFiles, count)
+		w.reporter.Report(progress.Event{
+			Type:       progress.EventTaskFinished,
+			Status:     "cancelled",
+			Message:    fmt.Sprintf("rebuild-index cancelled scanned=%d indexed=%d", scannedFiles, count),
+			DurationMs: time.Since(rebuildStart).Milliseconds(),
+			Timestamp:  time.Now(),
+		})
+		return ctx.Err()
+	}
+
 	log.Printf("Index rebuild completed:")
 	log.Printf("  - Total files scanned: %d", scannedFiles)
 	log.Printf("  - Total directories: %d", skippedDirs)
 	log.Printf("  - Files indexed: %d", count)
 	log.Printf("  - Files skipped: %d", scannedFiles-count)
 
+	w.reporter.Report(progress.Event{
+		Type:       progress.EventTaskFinished,
+		Status:     "success",
+		Message:    fmt.Sprintf("rebuild-index scanned=%d indexed=%d", scannedFiles, count),
+		DurationMs: time.Since(rebuildStart).Milliseconds(),
+		Timestamp:  time.Now(),
+	})
+
 	return nil
 }
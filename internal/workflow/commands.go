@@ -0,0 +1,303 @@
+package workflow
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxCommandLineSize bounds the per-line buffer used when scanning a step's
+// stdout/stderr for workflow commands, so a step that (accidentally or not)
+// writes one enormous line can't grow the scanner's buffer without limit.
+// Lines longer than this are passed through unrecognized rather than erroring.
+const maxCommandLineSize = 1 << 20 // 1 MiB
+
+// commandLineRe matches a GitHub Actions-style workflow command:
+// "::name key=val,key=val::value". The parameter segment is optional.
+var commandLineRe = regexp.MustCompile(`^::([a-zA-Z][a-zA-Z0-9_-]*)(?:\s+([^:]*))?::(.*)$`)
+
+// Annotation is a warning/error/notice/debug raised by a step via
+// "::error::", "::warning::", "::notice::", or "::debug::", surfaced on
+// WorkflowRun.Annotations.
+type Annotation struct {
+	Level   string `json:"level"` // error, warning, notice, debug
+	File    string `json:"file,omitempty"`
+	Line    string `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// commandState accumulates the effects of workflow commands across every
+// step of a run: masks and outputs apply run-wide once declared (not just
+// to the step that declared them), and the step summary is appended to
+// across steps exactly like $GITHUB_STEP_SUMMARY upstream.
+type commandState struct {
+	masks       []string
+	annotations []Annotation
+	summary     strings.Builder
+}
+
+// stepCommandFiles are the temp files backing $GITHUB_OUTPUT, $GITHUB_ENV,
+// and $GITHUB_STEP_SUMMARY for a single step, created fresh before the step
+// runs and read back (then removed) after it exits.
+type stepCommandFiles struct {
+	outputPath  string
+	envPath     string
+	summaryPath string
+}
+
+// newStepCommandFiles creates the three command files under dir (the
+// workflow's TempDir, which is bind-mounted into containers at
+// containerTmpDir, so paths resolve for both execution modes once
+// translated by the caller).
+func newStepCommandFiles(dir string) (*stepCommandFiles, error) {
+	create := func(prefix string) (string, error) {
+		f, err := os.CreateTemp(dir, prefix+"-*")
+		if err != nil {
+			return "", fmt.Errorf("failed to create %s file: %w", prefix, err)
+		}
+		path := f.Name()
+		f.Close()
+		return path, nil
+	}
+
+	outputPath, err := create("gha-output")
+	if err != nil {
+		return nil, err
+	}
+	envPath, err := create("gha-env")
+	if err != nil {
+		return nil, err
+	}
+	summaryPath, err := create("gha-summary")
+	if err != nil {
+		return nil, err
+	}
+
+	return &stepCommandFiles{outputPath: outputPath, envPath: envPath, summaryPath: summaryPath}, nil
+}
+
+// cleanup removes the command files. Errors are ignored: they're in
+// TempDir, which the caller tears down wholesale after the run anyway.
+func (cf *stepCommandFiles) cleanup() {
+	os.Remove(cf.outputPath)
+	os.Remove(cf.envPath)
+	os.Remove(cf.summaryPath)
+}
+
+// commandEnv returns the GITHUB_OUTPUT/GITHUB_ENV/GITHUB_STEP_SUMMARY environment
+// variables a step's process sees, using outputPath/envPath/summaryPath as
+// given so the caller can substitute in-container paths when the step runs
+// inside a container rather than directly on the host.
+func commandEnv(outputPath, envPath, summaryPath string) []string {
+	return []string{
+		"GITHUB_OUTPUT=" + outputPath,
+		"GITHUB_ENV=" + envPath,
+		"GITHUB_STEP_SUMMARY=" + summaryPath,
+	}
+}
+
+// applyEnvStyleFile reads a $GITHUB_OUTPUT or $GITHUB_ENV-style file and
+// returns the name/value pairs it defines. Each line is either "name=value"
+// or a multiline block "name<<DELIM" followed by the literal value (which
+// may span multiple lines) and a line containing exactly DELIM. A value
+// containing a line equal to its own delimiter can't be represented this
+// way: the block ends at that line, and the lines after it are parsed as
+// further entries (reported as malformed where they aren't name=value or
+// name<<DELIM). A block with no closing DELIM is reported as a parse error.
+func applyEnvStyleFile(path string) (map[string]string, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	var parseErrs []string
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxCommandLineSize)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		if name, delim, ok := strings.Cut(line, "<<"); ok && delim != "" {
+			var body []string
+			terminated := false
+			for scanner.Scan() {
+				bodyLine := scanner.Text()
+				if bodyLine == delim {
+					terminated = true
+					break
+				}
+				body = append(body, bodyLine)
+			}
+			if !terminated {
+				parseErrs = append(parseErrs, fmt.Sprintf("unterminated multiline value for %q (missing %q delimiter)", name, delim))
+				continue
+			}
+			values[name] = strings.Join(body, "\n")
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			parseErrs = append(parseErrs, fmt.Sprintf("malformed line (expected name=value or name<<DELIM): %q", line))
+			continue
+		}
+		values[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return values, parseErrs, err
+	}
+
+	return values, parseErrs, nil
+}
+
+// readStepSummary reads a $GITHUB_STEP_SUMMARY file's contents, returning
+// "" if the step never wrote to it.
+func readStepSummary(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// processInlineCommands scans text line-by-line for "::name params::value"
+// workflow commands (the line-prefixed form, as opposed to the file-based
+// $GITHUB_OUTPUT/$GITHUB_ENV/$GITHUB_STEP_SUMMARY commands handled by
+// applyEnvStyleFile/readStepSummary), applying their effect to state,
+// outputs and envVars, and returning text with recognized command lines
+// replaced by their rendered form (annotations, group markers) or removed
+// entirely (set-output, set-env, add-mask have no useful visual
+// rendering). Unrecognized lines, including lines too long for the
+// scanner's buffer, pass through unchanged.
+func processInlineCommands(text string, state *commandState, outputs map[string]string, envVars map[string]string) string {
+	var out strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxCommandLineSize)
+
+	first := true
+	writeLine := func(line string) {
+		if !first {
+			out.WriteByte('\n')
+		}
+		first = false
+		out.WriteString(line)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		match := commandLineRe.FindStringSubmatch(line)
+		if match == nil {
+			writeLine(line)
+			continue
+		}
+
+		name, params, value := match[1], parseCommandParams(match[2]), match[3]
+
+		switch name {
+		case "set-output":
+			if outName := params["name"]; outName != "" {
+				outputs[outName] = value
+			}
+		case "set-env":
+			if envName := params["name"]; envName != "" {
+				envVars[envName] = value
+			}
+		case "add-mask":
+			if value != "" {
+				state.masks = append(state.masks, value)
+			}
+		case "group":
+			writeLine("▶ " + value)
+		case "endgroup":
+			writeLine("◀")
+		case "error", "warning", "notice", "debug":
+			state.annotations = append(state.annotations, Annotation{
+				Level:   name,
+				File:    params["file"],
+				Line:    params["line"],
+				Message: value,
+			})
+			writeLine(renderAnnotation(name, params, value))
+		default:
+			// Unknown command: leave the line as-is rather than silently
+			// discarding output the author may still want to see.
+			writeLine(line)
+		}
+	}
+	if scanner.Err() != nil {
+		// A scan error (e.g. a line over maxCommandLineSize) just means the
+		// rest of text couldn't be re-split into lines; fall back to
+		// returning it untouched rather than losing output.
+		return text
+	}
+
+	return out.String()
+}
+
+// renderAnnotation formats an error/warning/notice/debug command as a
+// single display line, e.g. "[warning] path/to/file:12: message".
+func renderAnnotation(level string, params map[string]string, message string) string {
+	loc := params["file"]
+	if params["line"] != "" {
+		if loc != "" {
+			loc += ":" + params["line"]
+		} else {
+			loc = "line " + params["line"]
+		}
+	}
+	if loc != "" {
+		return fmt.Sprintf("[%s] %s: %s", level, loc, message)
+	}
+	return fmt.Sprintf("[%s] %s", level, message)
+}
+
+// parseCommandParams parses a command's "key=val,key=val" parameter
+// segment. A nil or empty segment yields an empty (non-nil) map so callers
+// can index it unconditionally.
+func parseCommandParams(segment string) map[string]string {
+	params := make(map[string]string)
+	segment = strings.TrimSpace(segment)
+	if segment == "" {
+		return params
+	}
+	for _, pair := range strings.Split(segment, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		params[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return params
+}
+
+// maskSecrets replaces every occurrence of each mask in s with "***". It's
+// applied once, across a run's full output, right before that output is
+// persisted (see Executor.Execute), so a secret masked partway through a
+// run is scrubbed from everything already logged, not just what follows.
+func maskSecrets(s string, masks []string) string {
+	if len(masks) == 0 || s == "" {
+		return s
+	}
+	for _, mask := range masks {
+		if mask == "" {
+			continue
+		}
+		s = strings.ReplaceAll(s, mask, "***")
+	}
+	return s
+}
@@ -1,18 +1,25 @@
 package workflow
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/blobstore"
+	"github.com/ah-its-andy/jpeg2heif/internal/livelog"
 )
 
 // ExecutionContext holds runtime context for workflow execution
@@ -23,6 +30,36 @@ type ExecutionContext struct {
 	TempDir      string
 	Variables    map[string]string
 	Quality      int
+	// StepProgress, if set, is called at the start and end of each step, so
+	// a caller can publish structured per-step progress (e.g. to an SSE
+	// stream) without Executor needing to know anything about how that's
+	// delivered.
+	StepProgress func(ev StepProgressEvent)
+	// ResumeFromStep skips the first ResumeFromStep steps without running
+	// them, treating them as already completed by a prior, paused
+	// execution of the same run. Zero means run from the beginning.
+	ResumeFromStep int
+	// PauseCheck, if set, is called before each step (after ResumeFromStep
+	// steps have been skipped); if it returns true, Execute stops without
+	// running that step and returns a result with Paused set, so a caller
+	// can cooperatively honor a pause request between steps rather than
+	// aborting mid-step.
+	PauseCheck func() bool
+	// Checkpoint, if set, is called after each step completes
+	// successfully, with the 0-based index of the highest step completed
+	// so far, so a later ResumeFromStep knows where to continue.
+	Checkpoint func(stepIndex int)
+	// DefaultStepTimeout bounds a step's run time when its own Timeout is
+	// unset (0), so a YAML spec that forgets to set one can't hang a worker
+	// slot forever. Zero leaves such steps unbounded, matching the prior
+	// behavior.
+	DefaultStepTimeout time.Duration
+	// BlobDir, if set, is the root of a blobstore.Store handleOutputs uses
+	// to split the primary output into content-defined chunks and
+	// reassemble it from them instead of a plain file copy, deduplicating
+	// chunks shared with previously produced outputs. Empty disables it:
+	// handleOutputs falls back to copyFile, matching the prior behavior.
+	BlobDir string
 }
 
 // ExecutionResult holds the result of workflow execution
@@ -37,9 +74,54 @@ type ExecutionResult struct {
 	MetadataPreserved bool
 	MetadataSummary   string
 	OutputFiles       map[string]string
+	// Annotations are the error/warning/notice/debug commands steps raised
+	// via "::error::"/"::warning::"/"::notice::"/"::debug::".
+	Annotations []Annotation
+	// Summary is the concatenation of everything steps wrote to
+	// $GITHUB_STEP_SUMMARY, in step order.
+	Summary string
+	// Paused is true when Execute stopped early because ExecutionContext's
+	// PauseCheck reported a pause request, rather than because a step
+	// failed or all steps completed. When true, Success and ExitCode are
+	// meaningless; PausedAtStep holds the resume point.
+	Paused bool
+	// PausedAtStep is the 0-based index of the step Execute stopped
+	// before, valid only when Paused is true. Resuming should set
+	// ExecutionContext.ResumeFromStep to this value.
+	PausedAtStep int
+	// OutputManifest is the blobstore.Manifest the primary output was
+	// split into, set only when ExecutionContext.BlobDir was non-empty. The
+	// caller (WorkflowConverter.Convert) persists it via
+	// db.UpdateFileIndexManifest and increments each chunk's refcount via
+	// db.UpsertBlob, since Executor has no database access of its own.
+	OutputManifest blobstore.Manifest
+	// StepDeps holds one StepDepRecord per step that ran, in step order.
+	// On a successful run, Execute also writes these to the
+	// "<OutputFile>.rec" sidecar (see stepdeps.go) so a later
+	// ExecuteIfChanged call can re-verify them without a database; the
+	// caller additionally indexes them into db.StepDep, the same
+	// Executor-produces/converter-persists split used for OutputManifest.
+	StepDeps []StepDepRecord
+}
+
+// StepProgressEvent describes a step's progress at a point in time, passed
+// to ExecutionContext.StepProgress. StdoutBytes, StderrBytes and Duration
+// are zero on Phase "start" (the step has just begun, nothing produced
+// yet); a caller driving a live progress bar can use them on "success"/
+// "failed" to show throughput and elapsed time for that step.
+type StepProgressEvent struct {
+	StepIndex   int
+	TotalSteps  int
+	StepName    string
+	Phase       string // "start", "success", "failed"
+	StdoutBytes int
+	StderrBytes int
+	Duration    time.Duration
 }
 
-// StepResult holds the result of a single step
+// StepResult holds the result of a single step. When the step has a Retry
+// policy, the fields above reflect the final attempt, and Attempts holds
+// one entry per attempt made (including the final one) in order.
 type StepResult struct {
 	StepName  string
 	Success   bool
@@ -47,11 +129,31 @@ type StepResult struct {
 	Duration  time.Duration
 	Stdout    string
 	Stderr    string
-	Error     string
-	StartTime time.Time
-	EndTime   time.Time
+	// StdoutBytes and StderrBytes are len(Stdout)/len(Stderr), tracked
+	// separately so a caller building a live progress bar doesn't need to
+	// measure the (possibly large, and masked after the fact) strings
+	// itself.
+	StdoutBytes int
+	StderrBytes int
+	Error       string
+	StartTime   time.Time
+	EndTime     time.Time
+	Attempts    []StepAttempt
+}
+
+// StepAttempt records the outcome of a single retry attempt of a step's
+// command. StderrTail is capped to maxAttemptStderrTail so a chatty failing
+// command doesn't blow up WorkflowRun.Logs across many retries.
+type StepAttempt struct {
+	Attempt    int
+	ExitCode   int
+	Duration   time.Duration
+	StderrTail string
+	Error      string
 }
 
+const maxAttemptStderrTail = 4096
+
 // Executor executes workflows
 type Executor struct {
 	spec    *WorkflowSpec
@@ -59,6 +161,21 @@ type Executor struct {
 	execCtx *ExecutionContext
 	result  *ExecutionResult
 	logBuf  *bytes.Buffer
+
+	// cmdState accumulates the run-wide effects of workflow commands a
+	// step's stdout/stderr emits (masking, annotations, step summary). See
+	// commands.go.
+	cmdState commandState
+	// dynamicEnv holds name/value pairs steps have written to $GITHUB_ENV
+	// (or "::set-env::"), applied to every subsequent step's process
+	// environment the same way spec.Env and step.Env are.
+	dynamicEnv map[string]string
+	// stepOutputs holds the outputs each step has declared via
+	// $GITHUB_OUTPUT or "::set-output::", keyed first by step name then by
+	// output name, so later steps can reference
+	// {{steps.<name>.outputs.<key>}} without colliding with another step's
+	// same-named output the way the flat e.execCtx.Variables merge would.
+	stepOutputs map[string]map[string]string
 }
 
 // NewExecutor creates a new workflow executor
@@ -71,7 +188,9 @@ func NewExecutor(spec *WorkflowSpec, ctx context.Context, execCtx *ExecutionCont
 			StepResults: []StepResult{},
 			OutputFiles: make(map[string]string),
 		},
-		logBuf: &bytes.Buffer{},
+		logBuf:      &bytes.Buffer{},
+		dynamicEnv:  make(map[string]string),
+		stepOutputs: make(map[string]map[string]string),
 	}
 }
 
@@ -105,7 +224,7 @@ func (e *Executor) Execute() (*ExecutionResult, error) {
 		e.log("âŒ ERROR: Failed to prepare variables: %v\n", err)
 		e.result.Success = false
 		e.result.ExitCode = 1
-		e.result.Logs = e.logBuf.String()
+		e.finalize()
 		e.result.Duration = time.Since(startTime)
 		return e.result, err
 	}
@@ -116,17 +235,71 @@ func (e *Executor) Execute() (*ExecutionResult, error) {
 	e.log("Total steps: %d\n\n", len(e.spec.Steps))
 
 	for i, step := range e.spec.Steps {
+		if i < e.execCtx.ResumeFromStep {
+			continue
+		}
+
+		if e.execCtx.PauseCheck != nil && e.execCtx.PauseCheck() {
+			e.log("â¸ï¸  Pause requested, stopping before step %d/%d: %s\n", i+1, len(e.spec.Steps), step.Name)
+			e.result.Paused = true
+			e.result.PausedAtStep = i
+			e.finalize()
+			e.result.Duration = time.Since(startTime)
+			return e.result, nil
+		}
+
 		e.log("â•”â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•\n")
 		e.log("â•‘ Step %d/%d: %s\n", i+1, len(e.spec.Steps), step.Name)
 		e.log("â•šâ•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•â•\n")
+		livelog.GetManager().AppendLog(e.execCtx.InputFile,
+			fmt.Sprintf("\n=== Step %d/%d: %s ===\n", i+1, len(e.spec.Steps), step.Name))
+		if e.execCtx.StepProgress != nil {
+			e.execCtx.StepProgress(StepProgressEvent{StepIndex: i, TotalSteps: len(e.spec.Steps), StepName: step.Name, Phase: "start"})
+		}
+
+		// Resolve the command/workdir/env and snapshot workdir before
+		// running, so the step dependency record below reflects exactly
+		// what executeStep used and which files it created, without
+		// executeStepAttempt needing to report any of that back up.
+		command, workdir := e.resolveStepCommand(&step)
+		env := e.resolveStepEnv(&step)
+		before, err := snapshotDir(workdir)
+		if err != nil {
+			e.log("⚠️  Failed to snapshot workdir before step '%s': %v\n", step.Name, err)
+		}
 
 		stepResult := e.executeStep(ctx, &step)
 		e.result.StepResults = append(e.result.StepResults, stepResult)
 
+		if dep, err := e.buildStepDepRecord(&step, command, workdir, env, stepResult, before); err != nil {
+			e.log("⚠️  Failed to record step dependency info for '%s': %v\n", step.Name, err)
+		} else {
+			e.result.StepDeps = append(e.result.StepDeps, dep)
+		}
+
 		if stepResult.Success {
 			e.log("âœ… Step completed successfully\n")
+			livelog.GetManager().AppendLog(e.execCtx.InputFile,
+				fmt.Sprintf("=== Step %d/%d: %s completed ===\n", i+1, len(e.spec.Steps), step.Name))
+			if e.execCtx.StepProgress != nil {
+				e.execCtx.StepProgress(StepProgressEvent{
+					StepIndex: i, TotalSteps: len(e.spec.Steps), StepName: step.Name, Phase: "success",
+					StdoutBytes: stepResult.StdoutBytes, StderrBytes: stepResult.StderrBytes, Duration: stepResult.Duration,
+				})
+			}
+			if e.execCtx.Checkpoint != nil {
+				e.execCtx.Checkpoint(i)
+			}
 		} else {
 			e.log("âŒ Step failed\n")
+			livelog.GetManager().AppendLog(e.execCtx.InputFile,
+				fmt.Sprintf("=== Step %d/%d: %s failed ===\n", i+1, len(e.spec.Steps), step.Name))
+			if e.execCtx.StepProgress != nil {
+				e.execCtx.StepProgress(StepProgressEvent{
+					StepIndex: i, TotalSteps: len(e.spec.Steps), StepName: step.Name, Phase: "failed",
+					StdoutBytes: stepResult.StdoutBytes, StderrBytes: stepResult.StderrBytes, Duration: stepResult.Duration,
+				})
+			}
 		}
 		e.log("   Exit code: %d\n", stepResult.ExitCode)
 		e.log("   Duration: %v\n", stepResult.Duration)
@@ -136,12 +309,19 @@ func (e *Executor) Execute() (*ExecutionResult, error) {
 		e.log("\n")
 
 		if !stepResult.Success {
+			if step.ContinueOnError {
+				e.log("⚠️  Step '%s' failed but continue_on_error is set, continuing\n", stepResult.StepName)
+				e.log("   Exit code: %d\n", stepResult.ExitCode)
+				e.log("   Duration: %v\n", stepResult.Duration)
+				continue
+			}
+
 			e.log("âŒ ERROR: Step '%s' failed, aborting workflow\n", stepResult.StepName)
 			e.log("   Exit code: %d\n", stepResult.ExitCode)
 			e.log("   Duration: %v\n", stepResult.Duration)
 			e.result.Success = false
 			e.result.ExitCode = stepResult.ExitCode
-			e.result.Logs = e.logBuf.String()
+			e.finalize()
 			e.result.Duration = time.Since(startTime)
 			return e.result, fmt.Errorf("step '%s' failed with exit code %d: %s", stepResult.StepName, stepResult.ExitCode, stepResult.Error)
 		}
@@ -153,12 +333,20 @@ func (e *Executor) Execute() (*ExecutionResult, error) {
 		e.log("âŒ ERROR: Failed to handle outputs: %v\n", err)
 		e.result.Success = false
 		e.result.ExitCode = 1
-		e.result.Logs = e.logBuf.String()
+		e.finalize()
 		e.result.Duration = time.Since(startTime)
 		return e.result, err
 	}
 	e.log("âœ… Outputs processed successfully\n\n")
 
+	if err := writeStepDepsFile(stepDepsPath(e.execCtx.OutputFile), e.result.StepDeps); err != nil {
+		// Not fatal: the sidecar only enables ExecuteIfChanged's fast path
+		// on a later run, it isn't part of this run's own output.
+		e.log("⚠️  Failed to write step dependency sidecar: %v\n", err)
+	} else {
+		e.log("Step dependency sidecar written to: %s\n", stepDepsPath(e.execCtx.OutputFile))
+	}
+
 	// Extract metadata
 	e.log("=== Extracting Metadata ===\n")
 	e.extractMetadata()
@@ -171,14 +359,103 @@ func (e *Executor) Execute() (*ExecutionResult, error) {
 
 	e.result.Success = true
 	e.result.ExitCode = 0
-	e.result.Logs = e.logBuf.String()
+	e.finalize()
 	e.result.Duration = time.Since(startTime)
 
 	return e.result, nil
 }
 
-// executeStep executes a single step
+// executeStep executes a single step, retrying its command per step.Retry
+// (exponential backoff with full jitter) until it succeeds, a non-retryable
+// exit code is hit, attempts are exhausted, or ctx is cancelled between
+// attempts. The returned StepResult's top-level fields reflect the final
+// attempt; Attempts holds the full retry history.
 func (e *Executor) executeStep(ctx context.Context, step *Step) StepResult {
+	maxAttempts := 1
+	if step.Retry != nil {
+		maxAttempts = step.Retry.MaxAttempts
+	}
+
+	var attempts []StepAttempt
+	var result StepResult
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			e.log("\nðŸ” Retry attempt %d/%d for step '%s'\n", attempt, maxAttempts, step.Name)
+		}
+
+		result = e.executeStepAttempt(ctx, step)
+		attempts = append(attempts, StepAttempt{
+			Attempt:    attempt,
+			ExitCode:   result.ExitCode,
+			Duration:   result.Duration,
+			StderrTail: tailString(result.Stderr, maxAttemptStderrTail),
+			Error:      result.Error,
+		})
+		result.Attempts = attempts
+
+		if result.Success || attempt == maxAttempts {
+			break
+		}
+		if step.Retry == nil || !shouldRetryExitCode(result.ExitCode, step.Retry.OnExitCodes) {
+			break
+		}
+
+		delay := backoffDelay(step.Retry, attempt)
+		e.log("â³ Attempt %d/%d failed (exit code %d), retrying in %v\n", attempt, maxAttempts, result.ExitCode, delay)
+
+		select {
+		case <-ctx.Done():
+			result.Error = ctx.Err().Error()
+			return result
+		case <-time.After(delay):
+		}
+	}
+
+	return result
+}
+
+// shouldRetryExitCode reports whether exitCode warrants another attempt per
+// onExitCodes; an empty onExitCodes means retry on any non-zero exit code.
+func shouldRetryExitCode(exitCode int, onExitCodes []int) bool {
+	if exitCode == 0 {
+		return false
+	}
+	if len(onExitCodes) == 0 {
+		return true
+	}
+	for _, code := range onExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// backoffDelay computes the exponential-backoff-with-full-jitter delay
+// before the next retry attempt, per retry.
+func backoffDelay(retry *RetrySpec, attempt int) time.Duration {
+	base := float64(retry.InitialDelay) * math.Pow(retry.Multiplier, float64(attempt-1))
+	capped := math.Min(base, float64(retry.MaxDelay))
+	delay := time.Duration(capped * float64(time.Second))
+
+	if retry.Jitter {
+		delay = time.Duration(rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+// tailString returns the last maxBytes of s, unchanged if s is already
+// within the limit.
+func tailString(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	return s[len(s)-maxBytes:]
+}
+
+// executeStepAttempt runs step's command exactly once (no retry handling)
+func (e *Executor) executeStepAttempt(ctx context.Context, step *Step) StepResult {
 	result := StepResult{
 		StepName:  step.Name,
 		StartTime: time.Now(),
@@ -187,11 +464,16 @@ func (e *Executor) executeStep(ctx context.Context, step *Step) StepResult {
 	e.log("Step: %s\n", step.Name)
 	e.log("Start time: %s\n", result.StartTime.Format(time.RFC3339))
 
-	// Apply step timeout
-	if step.Timeout > 0 {
-		e.log("Step timeout: %d seconds\n", step.Timeout)
+	// Apply step timeout, falling back to the execution context's default
+	// when the step itself doesn't set one.
+	stepTimeout := time.Duration(step.Timeout) * time.Second
+	if step.Timeout <= 0 {
+		stepTimeout = e.execCtx.DefaultStepTimeout
+	}
+	if stepTimeout > 0 {
+		e.log("Step timeout: %s\n", stepTimeout)
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, time.Duration(step.Timeout)*time.Second)
+		ctx, cancel = context.WithTimeout(ctx, stepTimeout)
 		defer cancel()
 	} else {
 		e.log("No step timeout set\n")
@@ -237,31 +519,73 @@ func (e *Executor) executeStep(ctx context.Context, step *Step) StepResult {
 		e.log("\n")
 	}
 
-	// Execute command
-	e.log("ðŸš€ Executing command...\n\n")
-	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
-	cmd.Dir = workdir
-
-	// Merge environment variables
-	cmd.Env = os.Environ()
-	for k, v := range e.spec.Env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, e.replaceVariables(v)))
+	// Create the $GITHUB_OUTPUT/$GITHUB_ENV/$GITHUB_STEP_SUMMARY files this
+	// step's process can write to, so it can produce outputs, set env for
+	// later steps, and append to the run summary without predeclaring
+	// everything in the YAML.
+	cmdFiles, err := newStepCommandFiles(e.execCtx.TempDir)
+	if err != nil {
+		result.Success = false
+		result.Error = fmt.Sprintf("failed to create workflow command files: %v", err)
+		result.ExitCode = 1
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		e.log("âŒ Failed to create workflow command files: %v\n", err)
+		return result
 	}
-	for k, v := range step.Env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, e.replaceVariables(v)))
+	defer cmdFiles.cleanup()
+
+	// Execute command, either directly on the host or inside an ephemeral
+	// container, depending on runs-on.
+	var stdoutStr, stderrStr string
+	if e.spec.RunsOn == "container" {
+		// TempDir is bind-mounted into the container at containerTmpDir, so
+		// the command files' in-container paths are the same basenames
+		// under containerTmpDir rather than their host paths.
+		containerEnv := map[string]string{
+			"GITHUB_OUTPUT":       filepath.Join(containerTmpDir, filepath.Base(cmdFiles.outputPath)),
+			"GITHUB_ENV":          filepath.Join(containerTmpDir, filepath.Base(cmdFiles.envPath)),
+			"GITHUB_STEP_SUMMARY": filepath.Join(containerTmpDir, filepath.Base(cmdFiles.summaryPath)),
+		}
+		for k, v := range e.dynamicEnv {
+			containerEnv[k] = v
+		}
+		stdoutStr, stderrStr, err = e.runContainerCommand(ctx, command, containerEnv)
+	} else {
+		e.log("ðŸš€ Executing command...\n\n")
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+		cmd.Dir = workdir
+
+		// Merge environment variables
+		cmd.Env = os.Environ()
+		for k, v := range e.spec.Env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, e.replaceVariables(v)))
+		}
+		for k, v := range e.dynamicEnv {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+		}
+		for k, v := range step.Env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, e.replaceVariables(v)))
+		}
+		cmd.Env = append(cmd.Env, commandEnv(cmdFiles.outputPath, cmdFiles.envPath, cmdFiles.summaryPath)...)
+
+		stdoutStr, stderrStr, err = e.runStreamed(cmd)
 	}
 
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Apply the file-based commands the step wrote, then scan stdout/stderr
+	// for line-prefixed "::name::value" commands; both feed outputs into
+	// e.execCtx.Variables so spec.Outputs' {{VAR}} templates can resolve
+	// values produced at runtime rather than only ones set ahead of time,
+	// and into e.stepOutputs[step.Name] so {{steps.<name>.outputs.<key>}}
+	// can resolve them without colliding across steps that reuse a name.
+	stdoutStr, stderrStr = e.applyWorkflowCommands(step.Name, cmdFiles, stdoutStr, stderrStr)
 
-	// Run command
-	err := cmd.Run()
 	result.EndTime = time.Now()
 	result.Duration = result.EndTime.Sub(result.StartTime)
-	result.Stdout = stdout.String()
-	result.Stderr = stderr.String()
+	result.Stdout = stdoutStr
+	result.Stderr = stderrStr
+	result.StdoutBytes = len(stdoutStr)
+	result.StderrBytes = len(stderrStr)
 
 	// Log output
 	e.log("ðŸ“¤ Command output:\n")
@@ -313,6 +637,162 @@ func (e *Executor) executeStep(ctx context.Context, step *Step) StepResult {
 	return result
 }
 
+// runStreamed runs cmd to completion, reading its stdout and stderr line by
+// line as they arrive via separate pipes rather than buffering the whole
+// output until the process exits (mirrors runContainerCommand, which
+// streams its combined output the same way). Each line is published to
+// e.execCtx.InputFile's live log immediately, so a client following the
+// file via the live-log SSE endpoint sees command output as it's produced
+// instead of one block once the step finishes.
+func (e *Executor) runStreamed(cmd *exec.Cmd) (stdout, stderr string, err error) {
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", "", err
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", "", fmt.Errorf("failed to start command: %w", err)
+	}
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go e.streamLines(&wg, stdoutPipe, &stdoutBuf)
+	go e.streamLines(&wg, stderrPipe, &stderrBuf)
+	wg.Wait()
+
+	err = cmd.Wait()
+	return stdoutBuf.String(), stderrBuf.String(), err
+}
+
+// streamLines copies r into dst line by line, publishing each line to
+// e.execCtx.InputFile's live log as it arrives. Must run in its own
+// goroutine; wg is marked Done once r is exhausted (the process's end of
+// the pipe closed, whether it exited or just closed that fd).
+func (e *Executor) streamLines(wg *sync.WaitGroup, r io.Reader, dst *bytes.Buffer) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		dst.WriteString(line)
+		dst.WriteString("\n")
+		livelog.GetManager().AppendLog(e.execCtx.InputFile, line+"\n")
+	}
+}
+
+// applyWorkflowCommands reads the step's $GITHUB_OUTPUT/$GITHUB_ENV/
+// $GITHUB_STEP_SUMMARY files and scans its stdout/stderr for line-prefixed
+// "::name::value" commands, merging everything they produce into
+// e.execCtx.Variables (so spec.Outputs' {{VAR}} templates and later steps'
+// {{VAR}} references resolve runtime-produced values), e.stepOutputs[stepName]
+// (so {{steps.<stepName>.outputs.<key>}} resolves without colliding across
+// steps that reuse an output name), e.dynamicEnv (so later steps' processes
+// see $GITHUB_ENV/"::set-env::"-set variables too), and e.cmdState (masks,
+// annotations, step summary, applied run-wide in finalize). It returns
+// stdout/stderr with recognized inline command lines replaced by their
+// rendered form.
+func (e *Executor) applyWorkflowCommands(stepName string, cmdFiles *stepCommandFiles, stdoutStr, stderrStr string) (string, string) {
+	outputs, outputErrs, err := applyEnvStyleFile(cmdFiles.outputPath)
+	if err != nil {
+		e.log("⚠️  Failed to read GITHUB_OUTPUT: %v\n", err)
+	}
+	for _, parseErr := range outputErrs {
+		e.log("⚠️  GITHUB_OUTPUT: %s\n", parseErr)
+	}
+	for name, value := range outputs {
+		e.execCtx.Variables[name] = value
+		e.recordStepOutput(stepName, name, value)
+	}
+
+	envVars, envErrs, err := applyEnvStyleFile(cmdFiles.envPath)
+	if err != nil {
+		e.log("⚠️  Failed to read GITHUB_ENV: %v\n", err)
+	}
+	for _, parseErr := range envErrs {
+		e.log("⚠️  GITHUB_ENV: %s\n", parseErr)
+	}
+	for name, value := range envVars {
+		e.execCtx.Variables[name] = value
+		e.dynamicEnv[name] = value
+	}
+
+	summary, err := readStepSummary(cmdFiles.summaryPath)
+	if err != nil {
+		e.log("⚠️  Failed to read GITHUB_STEP_SUMMARY: %v\n", err)
+	}
+	if summary != "" {
+		e.cmdState.summary.WriteString(summary)
+	}
+
+	inlineOutputs := make(map[string]string)
+	inlineEnv := make(map[string]string)
+	stdoutStr = processInlineCommands(stdoutStr, &e.cmdState, inlineOutputs, inlineEnv)
+	stderrStr = processInlineCommands(stderrStr, &e.cmdState, inlineOutputs, inlineEnv)
+	for name, value := range inlineOutputs {
+		e.execCtx.Variables[name] = value
+		e.recordStepOutput(stepName, name, value)
+	}
+	for name, value := range inlineEnv {
+		e.execCtx.Variables[name] = value
+		e.dynamicEnv[name] = value
+	}
+
+	return stdoutStr, stderrStr
+}
+
+// runContainerCommand runs command inside an ephemeral container per
+// e.spec.Container, streaming its combined stdout/stderr line-by-line into
+// both the execution log (so WorkflowRun.Logs ends up identical to the
+// shell path) and livelog.Manager (so a client following the workflow run
+// live sees container output as it arrives, the same way runStreamed does
+// for the shell path above).
+func (e *Executor) runContainerCommand(ctx context.Context, command string, extraEnv map[string]string) (stdout, stderr string, err error) {
+	e.log("ðŸš€ Executing command in container (image: %s)...\n\n", e.spec.Container.Image)
+
+	runtimeBin, err := containerRuntimeBinary()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := ensureImagePulled(ctx, runtimeBin, e.spec.Container); err != nil {
+		return "", "", err
+	}
+
+	args := buildContainerRunArgs(e.spec.Container, e.execCtx, command, extraEnv)
+	cmd := exec.CommandContext(ctx, runtimeBin, args...)
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	if err := cmd.Start(); err != nil {
+		return "", "", fmt.Errorf("failed to start container: %w", err)
+	}
+
+	var combined bytes.Buffer
+	scanDone := make(chan struct{})
+	go func() {
+		defer close(scanDone)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			line := scanner.Text()
+			combined.WriteString(line)
+			combined.WriteString("\n")
+			livelog.GetManager().AppendLog(e.execCtx.InputFile, line+"\n")
+		}
+	}()
+
+	waitErr := cmd.Wait()
+	pw.Close()
+	<-scanDone
+
+	return combined.String(), "", waitErr
+}
+
 // prepareVariables prepares all template variables
 func (e *Executor) prepareVariables() error {
 	vars := e.execCtx.Variables
@@ -365,6 +845,20 @@ func (e *Executor) prepareVariables() error {
 func (e *Executor) replaceVariables(text string) string {
 	result := text
 
+	// Resolve {{steps.<stepName>.outputs.<key>}} first, so a step name or
+	// output key that happens to look like a plain variable never gets
+	// double-processed by the [A-Z_][A-Z0-9_]* pass below.
+	result = stepOutputVarPattern.ReplaceAllStringFunc(result, func(match string) string {
+		sub := stepOutputVarPattern.FindStringSubmatch(match)
+		stepName, outputKey := sub[1], sub[2]
+		if outputs, ok := e.stepOutputs[stepName]; ok {
+			if value, ok := outputs[outputKey]; ok {
+				return shellEscape(value)
+			}
+		}
+		return match // Keep original if not found
+	})
+
 	// Use regex to find and replace variables
 	re := regexp.MustCompile(`\{\{([A-Z_][A-Z0-9_]*)\}\}`)
 	result = re.ReplaceAllStringFunc(result, func(match string) string {
@@ -379,6 +873,25 @@ func (e *Executor) replaceVariables(text string) string {
 	return result
 }
 
+// stepOutputVarPattern matches {{steps.<stepName>.outputs.<key>}}, the
+// namespaced form of a step's declared output ($GITHUB_OUTPUT or
+// "::set-output::"), letting later steps reference another step's output
+// by name without colliding with a same-named output from a different
+// step the way the flat e.execCtx.Variables merge would.
+var stepOutputVarPattern = regexp.MustCompile(`\{\{steps\.([a-zA-Z0-9_-]+)\.outputs\.([a-zA-Z0-9_-]+)\}\}`)
+
+// recordStepOutput records that step stepName declared output name with
+// value, so a later {{steps.<stepName>.outputs.<name>}} reference can
+// resolve it.
+func (e *Executor) recordStepOutput(stepName, name, value string) {
+	outputs, ok := e.stepOutputs[stepName]
+	if !ok {
+		outputs = make(map[string]string)
+		e.stepOutputs[stepName] = outputs
+	}
+	outputs[name] = value
+}
+
 // shellEscape escapes a string for safe use in shell commands
 func shellEscape(s string) string {
 	// Simple escape: wrap in single quotes and escape single quotes
@@ -397,7 +910,11 @@ func (e *Executor) handleOutputs() error {
 
 		// For primary output, copy to final destination
 		if key == "output_file" {
-			if err := copyFile(outputPath, e.execCtx.OutputFile); err != nil {
+			if e.execCtx.BlobDir != "" {
+				if err := e.storeOutputViaBlobStore(outputPath); err != nil {
+					return err
+				}
+			} else if err := copyFile(outputPath, e.execCtx.OutputFile); err != nil {
 				return fmt.Errorf("failed to copy output file: %w", err)
 			}
 			e.log("Copied output to: %s\n", e.execCtx.OutputFile)
@@ -407,6 +924,30 @@ func (e *Executor) handleOutputs() error {
 	return nil
 }
 
+// storeOutputViaBlobStore splits outputPath into content-defined chunks
+// under e.execCtx.BlobDir and reassembles e.execCtx.OutputFile from the
+// resulting manifest, instead of copying the file directly. A rerun that
+// produces a byte-identical (or merely near-identical) output reuses every
+// chunk blobstore.Store already has on disk.
+func (e *Executor) storeOutputViaBlobStore(outputPath string) error {
+	store, err := blobstore.New(e.execCtx.BlobDir)
+	if err != nil {
+		return fmt.Errorf("failed to open blob store: %w", err)
+	}
+
+	manifest, err := store.Split(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to split output into chunks: %w", err)
+	}
+
+	if err := store.Assemble(manifest, e.execCtx.OutputFile); err != nil {
+		return fmt.Errorf("failed to assemble output from chunks: %w", err)
+	}
+
+	e.result.OutputManifest = manifest
+	return nil
+}
+
 // extractMetadata extracts metadata from the output file
 func (e *Executor) extractMetadata() {
 	// Try to extract EXIF DateTimeOriginal using exiftool
@@ -429,6 +970,24 @@ func (e *Executor) log(format string, args ...interface{}) {
 	fmt.Fprintf(e.logBuf, format, args...)
 }
 
+// finalize applies the accumulated effects of workflow commands (masking,
+// annotations, step summary) to e.result and sets its Logs field from
+// logBuf. It must run exactly once, on every path out of Execute, so that a
+// secret masked partway through the run is scrubbed from everything already
+// logged (not just what follows) before anything is persisted.
+func (e *Executor) finalize() {
+	for i := range e.result.StepResults {
+		e.result.StepResults[i].Stdout = maskSecrets(e.result.StepResults[i].Stdout, e.cmdState.masks)
+		e.result.StepResults[i].Stderr = maskSecrets(e.result.StepResults[i].Stderr, e.cmdState.masks)
+	}
+	for i := range e.cmdState.annotations {
+		e.cmdState.annotations[i].Message = maskSecrets(e.cmdState.annotations[i].Message, e.cmdState.masks)
+	}
+	e.result.Annotations = e.cmdState.annotations
+	e.result.Summary = maskSecrets(e.cmdState.summary.String(), e.cmdState.masks)
+	e.result.Logs = maskSecrets(e.logBuf.String(), e.cmdState.masks)
+}
+
 // calculateFileMD5 calculates MD5 hash of a file
 func calculateFileMD5(filePath string) (string, error) {
 	file, err := os.Open(filePath)
@@ -509,18 +1068,25 @@ func (e *Executor) CanConvertCheck(filePath string) (bool, error) {
 		// Replace variables in command
 		command := e.replaceVariables(e.spec.CanConvert.Run)
 
-		// Execute command
-		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
-		cmd.Dir = e.execCtx.TempDir
+		// When runs-on is "container", run the probe inside the same
+		// sandboxed container steps use, rather than directly on the host
+		// shell, since can_convert.run is evaluated against files before
+		// they're otherwise trusted.
+		var err error
+		if e.spec.RunsOn == "container" {
+			_, _, err = e.runContainerCommand(ctx, command, nil)
+		} else {
+			cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+			cmd.Dir = e.execCtx.TempDir
 
-		// Set environment
-		cmd.Env = os.Environ()
-		for k, v := range e.spec.Env {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, e.replaceVariables(v)))
+			cmd.Env = os.Environ()
+			for k, v := range e.spec.Env {
+				cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, e.replaceVariables(v)))
+			}
+
+			err = cmd.Run()
 		}
 
-		// Run and check exit code
-		err := cmd.Run()
 		if err != nil {
 			if _, ok := err.(*exec.ExitError); ok {
 				// Non-zero exit code means not supported
@@ -0,0 +1,174 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// compileGlob turns a doublestar-style glob pattern into a regexp that
+// matches a full slash-separated path. It supports "**" (any number of path
+// segments, including zero), "*" (anything within a single segment), "?"
+// (a single non-separator character) and "{a,b,c}" brace alternation.
+//
+// This is a small hand-rolled subset of github.com/bmatcuk/doublestar's
+// syntax rather than a dependency, since there's no vendored module for it
+// here; it only needs to cover the patterns workflow specs use for input
+// selection.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	alternatives, err := expandBraces(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var reParts []string
+	for _, alt := range alternatives {
+		reParts = append(reParts, globToRegexp(alt))
+	}
+
+	return regexp.Compile("^(?:" + strings.Join(reParts, "|") + ")$")
+}
+
+// expandBraces expands a single level of "{a,b,c}" alternation into the
+// cartesian product of literal substitutions. Nested braces aren't
+// supported; that's more than workflow specs need.
+func expandBraces(pattern string) ([]string, error) {
+	start := strings.IndexByte(pattern, '{')
+	if start == -1 {
+		return []string{pattern}, nil
+	}
+
+	end := strings.IndexByte(pattern[start:], '}')
+	if end == -1 {
+		return nil, fmt.Errorf("unmatched '{' in pattern %q", pattern)
+	}
+	end += start
+
+	prefix := pattern[:start]
+	suffix := pattern[end+1:]
+	options := strings.Split(pattern[start+1:end], ",")
+
+	rest, err := expandBraces(suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, opt := range options {
+		for _, r := range rest {
+			out = append(out, prefix+opt+r)
+		}
+	}
+	return out, nil
+}
+
+// globToRegexp converts a single brace-free glob into a regexp fragment.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	runes := []rune(glob)
+
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				// "**/" matches zero or more whole path segments;
+				// otherwise "**" matches anything, including separators.
+				if i+2 < len(runes) && runes[i+2] == '/' {
+					b.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					b.WriteString(".*")
+					i++
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+
+	return b.String()
+}
+
+// Matches reports whether path satisfies this MatchSpec: the last matching
+// entry in Patterns wins (later patterns override earlier ones, and a "!"
+// prefix excludes), and mimeType/size predicates must also hold when set.
+func (m *MatchSpec) Matches(path string, mimeType string, size int64) bool {
+	if m == nil {
+		return false
+	}
+
+	matched := false
+	for _, pattern := range m.Patterns {
+		exclude := strings.HasPrefix(pattern, "!")
+		re, err := compileGlob(strings.TrimPrefix(pattern, "!"))
+		if err != nil {
+			continue
+		}
+		if re.MatchString(path) {
+			matched = !exclude
+		}
+	}
+	if !matched {
+		return false
+	}
+
+	if len(m.MimeTypes) > 0 {
+		found := false
+		for _, mt := range m.MimeTypes {
+			if mt == mimeType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if m.MinSize > 0 && size < m.MinSize {
+		return false
+	}
+	if m.MaxSize > 0 && size > m.MaxSize {
+		return false
+	}
+
+	return true
+}
+
+// MatchPriority reports whether spec's inputs.match rules select path, and
+// if so, the priority to use when resolving conflicts against other
+// matching workflows (normally the explicit MatchSpec.Priority, defaulting
+// to 0). spec.ConverterPriority, if set, wins over MatchSpec.Priority, and
+// also lets a workflow with no inputs.match block at all (one that selects
+// its inputs solely through can_convert) still report a priority instead of
+// always matching at the default 0. MatchPriority stats path itself for
+// the size predicates; if that fails, size is taken as 0, so a MinSize
+// predicate fails and a MaxSize predicate holds.
+func (spec *WorkflowSpec) MatchPriority(path string, mimeType string) (matched bool, priority int) {
+	if spec.Inputs == nil || spec.Inputs.Match == nil {
+		if spec.ConverterPriority != nil {
+			return true, *spec.ConverterPriority
+		}
+		return false, 0
+	}
+
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+
+	if !spec.Inputs.Match.Matches(path, mimeType, size) {
+		return false, 0
+	}
+
+	priority = spec.Inputs.Match.Priority
+	if spec.ConverterPriority != nil {
+		priority = *spec.ConverterPriority
+	}
+	return true, priority
+}
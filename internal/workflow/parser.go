@@ -12,12 +12,52 @@ import (
 type WorkflowSpec struct {
 	Name        string            `yaml:"name"`
 	Description string            `yaml:"description"`
-	RunsOn      string            `yaml:"runs-on"`     // shell or docker
+	RunsOn      string            `yaml:"runs-on"`     // shell or container
 	Timeout     int               `yaml:"timeout"`     // seconds, global timeout
 	CanConvert  *CanConvertSpec   `yaml:"can_convert"` // Optional: check if file is supported
-	Env         map[string]string `yaml:"env"`
-	Steps       []Step            `yaml:"steps"`
-	Outputs     map[string]string `yaml:"outputs"`
+	Inputs      *InputsSpec       `yaml:"inputs"`      // Optional: glob/size/mime based input selection
+	// ConverterPriority overrides the priority MatchPriority reports for
+	// this workflow, taking precedence over inputs.match.priority when both
+	// are set. Lets a workflow that selects its inputs entirely through
+	// can_convert (no inputs.match block at all) still state an explicit
+	// tiebreak against other converters, instead of always defaulting to 0.
+	ConverterPriority *int              `yaml:"converter_priority"`
+	Container         *ContainerSpec    `yaml:"container"` // Required when runs-on is "container"
+	Env               map[string]string `yaml:"env"`
+	Steps             []Step            `yaml:"steps"`
+	Outputs           map[string]string `yaml:"outputs"`
+}
+
+// ContainerSpec configures the ephemeral container each step runs in when
+// RunsOn is "container", driven via the Podman/Docker CLI rather than
+// linking a client library (see containerRuntimeBinary). INPUT_FILE,
+// OUTPUT_FILE, and TMP_DIR are bind-mounted automatically; Volumes adds
+// any further host:container mounts a step needs.
+type ContainerSpec struct {
+	Image      string            `yaml:"image"`
+	PullPolicy string            `yaml:"pull_policy"` // always, if-not-present (default), never
+	Entrypoint string            `yaml:"entrypoint"`
+	Env        map[string]string `yaml:"env"`
+	Volumes    []string          `yaml:"volumes"` // "host_path:container_path[:ro]" docker/podman -v strings
+}
+
+// InputsSpec groups input-selection rules for a workflow.
+type InputsSpec struct {
+	Match *MatchSpec `yaml:"match"`
+}
+
+// MatchSpec selects which files a workflow applies to using doublestar-style
+// glob patterns, evaluated in order so later patterns can override earlier
+// ones. A pattern prefixed with "!" excludes matching paths, gitignore-style
+// (e.g. "!**/thumbs/**" excludes anything under a "thumbs" directory even if
+// an earlier pattern matched it). Priority breaks ties when more than one
+// workflow's patterns match the same path; higher wins.
+type MatchSpec struct {
+	Patterns  []string `yaml:"patterns"`
+	MimeTypes []string `yaml:"mime_types"`
+	MinSize   int64    `yaml:"min_size"`
+	MaxSize   int64    `yaml:"max_size"`
+	Priority  int      `yaml:"priority"`
 }
 
 // CanConvertSpec defines how to check if a file can be converted
@@ -29,11 +69,27 @@ type CanConvertSpec struct {
 
 // Step represents a single workflow step
 type Step struct {
-	Name    string            `yaml:"name"`
-	Run     string            `yaml:"run"`
-	Env     map[string]string `yaml:"env"`
-	Workdir string            `yaml:"workdir"`
-	Timeout int               `yaml:"timeout"` // seconds, step-level timeout
+	Name            string            `yaml:"name"`
+	Run             string            `yaml:"run"`
+	Env             map[string]string `yaml:"env"`
+	Workdir         string            `yaml:"workdir"`
+	Timeout         int               `yaml:"timeout"` // seconds, step-level timeout
+	Retry           *RetrySpec        `yaml:"retry"`
+	ContinueOnError bool              `yaml:"continue_on_error"` // don't abort the workflow if this step ultimately fails
+}
+
+// RetrySpec configures exponential-backoff-with-full-jitter retries for a
+// step's command. delay = min(max_delay, initial_delay * multiplier^(attempt-1)),
+// then (if Jitter) randomized uniformly in [0, delay], so many steps
+// failing at once don't all retry in lockstep. OnExitCodes restricts
+// retries to specific exit codes; empty means retry on any non-zero exit.
+type RetrySpec struct {
+	MaxAttempts  int     `yaml:"max_attempts"`
+	InitialDelay int     `yaml:"initial_delay"` // seconds
+	MaxDelay     int     `yaml:"max_delay"`     // seconds
+	Multiplier   float64 `yaml:"multiplier"`
+	Jitter       bool    `yaml:"jitter"`
+	OnExitCodes  []int   `yaml:"on_exit_codes"`
 }
 
 // ParseWorkflow parses YAML into WorkflowSpec
@@ -63,8 +119,26 @@ func (spec *WorkflowSpec) Validate() []string {
 		spec.RunsOn = "shell" // Default
 	}
 
-	if spec.RunsOn != "shell" && spec.RunsOn != "docker" {
-		errors = append(errors, "runs-on must be 'shell' or 'docker'")
+	// "docker" predates container support and is accepted as a deprecated
+	// alias so existing workflow YAML doesn't break.
+	if spec.RunsOn == "docker" {
+		spec.RunsOn = "container"
+	}
+
+	if spec.RunsOn != "shell" && spec.RunsOn != "container" {
+		errors = append(errors, "runs-on must be 'shell' or 'container'")
+	}
+
+	if spec.RunsOn == "container" {
+		if spec.Container == nil || spec.Container.Image == "" {
+			errors = append(errors, "container: image is required when runs-on is 'container'")
+		} else {
+			switch spec.Container.PullPolicy {
+			case "", "always", "if-not-present", "never":
+			default:
+				errors = append(errors, fmt.Sprintf("container: pull_policy must be 'always', 'if-not-present', or 'never' (got %q)", spec.Container.PullPolicy))
+			}
+		}
 	}
 
 	if spec.Timeout < 0 {
@@ -102,6 +176,30 @@ func (spec *WorkflowSpec) Validate() []string {
 		}
 	}
 
+	// Validate inputs.match if present
+	if spec.Inputs != nil && spec.Inputs.Match != nil {
+		m := spec.Inputs.Match
+		if len(m.Patterns) == 0 {
+			errors = append(errors, "inputs.match: at least one pattern is required")
+		}
+
+		for i, pattern := range m.Patterns {
+			if _, err := compileGlob(strings.TrimPrefix(pattern, "!")); err != nil {
+				errors = append(errors, fmt.Sprintf("inputs.match: patterns[%d] (%q) is not a valid glob: %v", i, pattern, err))
+			}
+		}
+
+		if m.MinSize < 0 {
+			errors = append(errors, "inputs.match: min_size must be non-negative")
+		}
+		if m.MaxSize < 0 {
+			errors = append(errors, "inputs.match: max_size must be non-negative")
+		}
+		if m.MaxSize > 0 && m.MinSize > m.MaxSize {
+			errors = append(errors, "inputs.match: min_size must not exceed max_size")
+		}
+	}
+
 	// Validate steps
 	if len(spec.Steps) == 0 {
 		errors = append(errors, "at least one step is required")
@@ -119,6 +217,35 @@ func (spec *WorkflowSpec) Validate() []string {
 		if step.Timeout < 0 {
 			errors = append(errors, fmt.Sprintf("step %d (%s): timeout must be non-negative", i, step.Name))
 		}
+
+		if step.Retry != nil {
+			retry := step.Retry
+			if retry.MaxAttempts < 0 {
+				errors = append(errors, fmt.Sprintf("step %d (%s): retry.max_attempts must be non-negative", i, step.Name))
+			} else if retry.MaxAttempts == 0 {
+				retry.MaxAttempts = 3 // Default: try up to 3 times total
+			}
+			if retry.InitialDelay < 0 {
+				errors = append(errors, fmt.Sprintf("step %d (%s): retry.initial_delay must be non-negative", i, step.Name))
+			} else if retry.InitialDelay == 0 {
+				retry.InitialDelay = 1 // Default 1 second
+			}
+			if retry.MaxDelay < 0 {
+				errors = append(errors, fmt.Sprintf("step %d (%s): retry.max_delay must be non-negative", i, step.Name))
+			} else if retry.MaxDelay == 0 {
+				retry.MaxDelay = 30 // Default 30 seconds
+			}
+			if retry.Multiplier < 0 {
+				errors = append(errors, fmt.Sprintf("step %d (%s): retry.multiplier must be non-negative", i, step.Name))
+			} else if retry.Multiplier == 0 {
+				retry.Multiplier = 2 // Default doubling backoff
+			}
+			for j, code := range retry.OnExitCodes {
+				if code <= 0 {
+					errors = append(errors, fmt.Sprintf("step %d (%s): retry.on_exit_codes[%d] must be a positive exit code", i, step.Name, j))
+				}
+			}
+		}
 	}
 
 	// Validate template variables in outputs
@@ -0,0 +1,137 @@
+package workflow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A recfile record is an ordered list of "Key: value" fields, matching the
+// GNU recutils text format closely enough for our needs: records are
+// separated by a blank line, and a value spanning multiple lines continues
+// on following lines that start with "+ " instead of repeating the key. We
+// don't implement recutils' %rec: type descriptors or selection
+// expressions, just enough of the format to write and re-read our own step
+// dependency sidecars (see stepdeps.go) — the same "hand-roll the slice we
+// actually need" choice workflow/glob.go makes for path globbing instead of
+// vendoring a library.
+type recField struct {
+	Key   string
+	Value string
+}
+
+type recRecord []recField
+
+// get returns the value of the first field named key.
+func (r recRecord) get(key string) (string, bool) {
+	for _, f := range r {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// getAll returns the values of every field named key, in order.
+func (r recRecord) getAll(key string) []string {
+	var values []string
+	for _, f := range r {
+		if f.Key == key {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+// encodeRecfile renders records in recfile text format, with one blank
+// line between records.
+func encodeRecfile(records []recRecord) string {
+	var b strings.Builder
+	for i, rec := range records {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, f := range rec {
+			lines := strings.Split(f.Value, "\n")
+			fmt.Fprintf(&b, "%s: %s\n", f.Key, lines[0])
+			for _, line := range lines[1:] {
+				fmt.Fprintf(&b, "+ %s\n", line)
+			}
+		}
+	}
+	return b.String()
+}
+
+// decodeRecfile parses text produced by encodeRecfile back into records.
+func decodeRecfile(text string) ([]recRecord, error) {
+	var records []recRecord
+	var current recRecord
+	lastIdx := -1
+
+	flush := func() {
+		if len(current) > 0 {
+			records = append(records, current)
+		}
+		current = nil
+		lastIdx = -1
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if line == "+" || strings.HasPrefix(line, "+ ") {
+			if lastIdx < 0 {
+				return nil, fmt.Errorf("recfile: continuation line with no preceding field: %q", line)
+			}
+			cont := strings.TrimPrefix(strings.TrimPrefix(line, "+"), " ")
+			current[lastIdx].Value += "\n" + cont
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			return nil, fmt.Errorf("recfile: malformed field line: %q", line)
+		}
+		key := line[:idx]
+		value := strings.TrimPrefix(line[idx+1:], " ")
+		current = append(current, recField{Key: key, Value: value})
+		lastIdx = len(current) - 1
+	}
+	flush()
+
+	return records, nil
+}
+
+// tai64nOffset is the conventional TAI64 epoch offset (2^62), added to Unix
+// seconds so the label sorts and compares as an unsigned integer. We don't
+// track the TAI/UTC leap-second delta, which would need a leap second
+// table we don't have, so the seconds field here is Unix time rather than
+// true TAI seconds. That's fine for our purpose: ordering step timestamps
+// and detecting whether a rec file changed, not interoperating with other
+// TAI64N tooling.
+const tai64nOffset uint64 = 1 << 62
+
+// formatTAI64N renders t in external TAI64N format: "@" followed by 16 hex
+// digits of offset seconds and 8 hex digits of nanoseconds.
+func formatTAI64N(t time.Time) string {
+	sec := tai64nOffset + uint64(t.Unix())
+	return fmt.Sprintf("@%016x%08x", sec, uint32(t.Nanosecond()))
+}
+
+// parseTAI64N reverses formatTAI64N, returning the UTC time it encodes.
+func parseTAI64N(s string) (time.Time, error) {
+	if len(s) != 25 || s[0] != '@' {
+		return time.Time{}, fmt.Errorf("tai64n: malformed label %q", s)
+	}
+	sec, err := strconv.ParseUint(s[1:17], 16, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("tai64n: malformed seconds field: %w", err)
+	}
+	nsec, err := strconv.ParseUint(s[17:25], 16, 32)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("tai64n: malformed nanoseconds field: %w", err)
+	}
+	return time.Unix(int64(sec-tai64nOffset), int64(nsec)).UTC(), nil
+}
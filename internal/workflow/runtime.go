@@ -0,0 +1,103 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+)
+
+// containerRuntimeBinary returns the container CLI to drive, preferring
+// podman (rootless, no daemon required) and falling back to docker. This
+// mirrors can_convert.run's existing approach of shelling out to an
+// external tool rather than linking a client library.
+func containerRuntimeBinary() (string, error) {
+	for _, bin := range []string{"podman", "docker"} {
+		if _, err := exec.LookPath(bin); err == nil {
+			return bin, nil
+		}
+	}
+	return "", fmt.Errorf("no container runtime found on PATH (tried podman, docker)")
+}
+
+// containerInputDir/OutputDir/TmpDir are the fixed in-container paths
+// INPUT_FILE, OUTPUT_FILE, and TMP_DIR resolve to, bind-mounted from the
+// host directories holding execCtx.InputFile, execCtx.OutputFile, and
+// execCtx.TempDir respectively.
+const (
+	containerInputDir  = "/workspace/input"
+	containerOutputDir = "/workspace/output"
+	containerTmpDir    = "/workspace/tmp"
+)
+
+// ensureImagePulled pulls spec.Image according to spec.PullPolicy
+// ("always" always pulls, "never" never does, and the default
+// "if-not-present" only pulls when the image isn't already present
+// locally), so a step doesn't silently run a stale image once pinned.
+func ensureImagePulled(ctx context.Context, runtimeBin string, spec *ContainerSpec) error {
+	switch spec.PullPolicy {
+	case "never":
+		return nil
+	case "always":
+		// fall through to pull unconditionally
+	default: // "" or "if-not-present"
+		if err := exec.CommandContext(ctx, runtimeBin, "image", "inspect", spec.Image).Run(); err == nil {
+			return nil
+		}
+	}
+
+	out, err := exec.CommandContext(ctx, runtimeBin, "pull", spec.Image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w, output: %s", spec.Image, err, string(out))
+	}
+	return nil
+}
+
+// buildContainerRunArgs assembles the `<runtime> run` arguments for
+// executing command inside spec.Image, with execCtx's input/output/tmp
+// directories bind-mounted at the fixed containerInputDir/
+// containerOutputDir/containerTmpDir paths so INPUT_FILE/OUTPUT_FILE/
+// TMP_DIR resolve the same way regardless of where the host paths live.
+// The input mount is read-only; output and tmp are read-write, since a
+// step commonly needs to write its converted file and scratch data but
+// never the original. extraEnv is applied after spec.Env, for variables
+// the caller computes per-invocation (e.g. the in-container
+// GITHUB_OUTPUT/GITHUB_ENV/GITHUB_STEP_SUMMARY paths).
+func buildContainerRunArgs(spec *ContainerSpec, execCtx *ExecutionContext, command string, extraEnv map[string]string) []string {
+	args := []string{"run", "--rm", "-i"}
+
+	args = append(args,
+		"-v", fmt.Sprintf("%s:%s:ro", filepath.Dir(execCtx.InputFile), containerInputDir),
+		"-v", fmt.Sprintf("%s:%s:rw", filepath.Dir(execCtx.OutputFile), containerOutputDir),
+		"-v", fmt.Sprintf("%s:%s:rw", execCtx.TempDir, containerTmpDir),
+	)
+	for _, vol := range spec.Volumes {
+		args = append(args, "-v", vol)
+	}
+
+	args = append(args,
+		"-e", fmt.Sprintf("INPUT_FILE=%s", filepath.Join(containerInputDir, filepath.Base(execCtx.InputFile))),
+		"-e", fmt.Sprintf("OUTPUT_FILE=%s", filepath.Join(containerOutputDir, filepath.Base(execCtx.OutputFile))),
+		"-e", fmt.Sprintf("TMP_DIR=%s", containerTmpDir),
+	)
+	for k, v := range spec.Env {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	for k, v := range extraEnv {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	if spec.Entrypoint != "" {
+		args = append(args, "--entrypoint", spec.Entrypoint)
+	}
+
+	args = append(args, spec.Image)
+
+	if spec.Entrypoint == "" {
+		args = append(args, "sh", "-c", command)
+	} else {
+		args = append(args, command)
+	}
+
+	return args
+}
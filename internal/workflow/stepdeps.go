@@ -0,0 +1,389 @@
+package workflow
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StepDepRecord captures everything ExecuteIfChanged needs to decide
+// whether a step's inputs have changed since it last ran: the resolved
+// command and environment that produced its output, and the sha256 of
+// every file the step read (via {{INPUT_FILE}}/{{TMP_OUTPUT}}) or wrote
+// into its workdir. One is built per step as it completes (see
+// buildStepDepRecord); the full set for a run is written to
+// "<OutputFile>.rec" by Execute on success (see writeStepDepsFile) and
+// separately indexed into db.StepDep by WorkflowConverter, which holds the
+// database handle Executor itself doesn't have.
+type StepDepRecord struct {
+	StepName string
+	Command  string
+	Workdir  string
+	Env      map[string]string
+	ExitCode int
+	// StartTime and EndTime are TAI64N external format timestamps.
+	StartTime string
+	EndTime   string
+	// InputHashes maps the template variable name ("INPUT_FILE" or
+	// "TMP_OUTPUT") to the sha256 of the file it pointed to when the step
+	// ran, for every such variable step.Run actually referenced.
+	InputHashes map[string]string
+	// CreatedHashes maps a path relative to Workdir to its sha256, for
+	// every file that appeared (or changed) under Workdir while the step
+	// ran. Workdir is normally a fresh per-run temp directory, so on a
+	// later run these paths won't exist at the same location; that makes
+	// unchanged() conservatively report "changed" rather than wrongly skip
+	// a step whose side effects it can no longer observe.
+	CreatedHashes map[string]string
+}
+
+// inputVarPattern matches the subset of {{VAR}} template variables whose
+// value names a file ExecuteIfChanged should hash and compare: the
+// original input and the in-progress temp output. Other variables
+// (QUALITY, TIMESTAMP, ...) don't name files and so aren't dependencies.
+var inputVarPattern = regexp.MustCompile(`\{\{(INPUT_FILE|TMP_OUTPUT)\}\}`)
+
+// referencedInputVars returns which of INPUT_FILE/TMP_OUTPUT run
+// references, in the order each first appears.
+func referencedInputVars(run string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range inputVarPattern.FindAllStringSubmatch(run, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// hashFileSHA256 returns the lowercase hex sha256 of the file at path.
+func hashFileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// snapshotDir returns every regular file under dir (recursively), keyed by
+// path relative to dir, mapped to its modification time as a cheap change
+// fingerprint. A missing dir snapshots as empty rather than erroring, since
+// a step's workdir may not exist yet the first time it's snapshotted.
+func snapshotDir(dir string) (map[string]int64, error) {
+	snapshot := make(map[string]int64)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		snapshot[rel] = info.ModTime().UnixNano()
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// changedPaths returns the paths present in after that are new or whose
+// modtime changed relative to before, sorted for deterministic output.
+func changedPaths(before, after map[string]int64) []string {
+	var paths []string
+	for rel, modTime := range after {
+		if prior, ok := before[rel]; !ok || prior != modTime {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// resolveStepCommand returns the exact command and working directory
+// executeStepAttempt would run for step, mirroring its own variable
+// resolution so a StepDepRecord reflects what actually ran without
+// executeStepAttempt needing to report it back up.
+func (e *Executor) resolveStepCommand(step *Step) (command, workdir string) {
+	command = e.replaceVariables(step.Run)
+	workdir = e.execCtx.TempDir
+	if step.Workdir != "" {
+		workdir = strings.Trim(e.replaceVariables(step.Workdir), "'")
+	}
+	return command, workdir
+}
+
+// resolveStepEnv returns spec.Env, the dynamicEnv accumulated from prior
+// steps' $GITHUB_ENV writes, and step.Env merged in that precedence order
+// (step.Env wins on conflicts), matching executeStepAttempt's cmd.Env.
+func (e *Executor) resolveStepEnv(step *Step) map[string]string {
+	env := make(map[string]string, len(e.spec.Env)+len(e.dynamicEnv)+len(step.Env))
+	for k, v := range e.spec.Env {
+		env[k] = e.replaceVariables(v)
+	}
+	for k, v := range e.dynamicEnv {
+		env[k] = v
+	}
+	for k, v := range step.Env {
+		env[k] = e.replaceVariables(v)
+	}
+	return env
+}
+
+// buildStepDepRecord assembles the StepDepRecord for a step that just ran,
+// hashing its declared inputs and whatever files changed under workdir
+// while it executed. before is workdir's snapshotDir result taken just
+// before the step ran.
+func (e *Executor) buildStepDepRecord(step *Step, command, workdir string, env map[string]string, result StepResult, before map[string]int64) (StepDepRecord, error) {
+	dep := StepDepRecord{
+		StepName:      step.Name,
+		Command:       command,
+		Workdir:       workdir,
+		Env:           env,
+		ExitCode:      result.ExitCode,
+		StartTime:     formatTAI64N(result.StartTime),
+		EndTime:       formatTAI64N(result.EndTime),
+		InputHashes:   make(map[string]string),
+		CreatedHashes: make(map[string]string),
+	}
+
+	for _, varName := range referencedInputVars(step.Run) {
+		path, ok := e.execCtx.Variables[varName]
+		if !ok {
+			continue
+		}
+		hash, err := hashFileSHA256(path)
+		if err != nil {
+			// The file may not exist yet (e.g. TMP_OUTPUT, before the step
+			// that creates it has run) — not an error, just nothing to
+			// record for this variable.
+			continue
+		}
+		dep.InputHashes[varName] = hash
+	}
+
+	after, err := snapshotDir(workdir)
+	if err != nil {
+		return dep, fmt.Errorf("failed to snapshot workdir after step: %w", err)
+	}
+	for _, rel := range changedPaths(before, after) {
+		hash, err := hashFileSHA256(filepath.Join(workdir, rel))
+		if err != nil {
+			continue
+		}
+		dep.CreatedHashes[rel] = hash
+	}
+
+	return dep, nil
+}
+
+// recfile field names for StepDepRecord.
+const (
+	recKeyStep        = "Step"
+	recKeyCommand     = "Command"
+	recKeyWorkdir     = "Workdir"
+	recKeyEnv         = "Env"
+	recKeyExitCode    = "ExitCode"
+	recKeyStartTime   = "StartTime"
+	recKeyEndTime     = "EndTime"
+	recKeyInputHash   = "InputHash"
+	recKeyCreatedHash = "CreatedHash"
+)
+
+// sortedKeys returns m's keys in sorted order, so recfile output (and
+// therefore hashing it for change detection) is deterministic.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (dep StepDepRecord) toRecRecord() recRecord {
+	var r recRecord
+	r = append(r, recField{recKeyStep, dep.StepName})
+	r = append(r, recField{recKeyCommand, dep.Command})
+	r = append(r, recField{recKeyWorkdir, dep.Workdir})
+	for _, k := range sortedKeys(dep.Env) {
+		r = append(r, recField{recKeyEnv, k + "=" + dep.Env[k]})
+	}
+	r = append(r, recField{recKeyExitCode, strconv.Itoa(dep.ExitCode)})
+	r = append(r, recField{recKeyStartTime, dep.StartTime})
+	r = append(r, recField{recKeyEndTime, dep.EndTime})
+	for _, k := range sortedKeys(dep.InputHashes) {
+		r = append(r, recField{recKeyInputHash, k + "=sha256:" + dep.InputHashes[k]})
+	}
+	for _, k := range sortedKeys(dep.CreatedHashes) {
+		r = append(r, recField{recKeyCreatedHash, k + "=sha256:" + dep.CreatedHashes[k]})
+	}
+	return r
+}
+
+func stepDepRecordFromRec(r recRecord) (StepDepRecord, error) {
+	dep := StepDepRecord{
+		Env:           map[string]string{},
+		InputHashes:   map[string]string{},
+		CreatedHashes: map[string]string{},
+	}
+
+	name, ok := r.get(recKeyStep)
+	if !ok {
+		return dep, fmt.Errorf("recfile: record missing %s field", recKeyStep)
+	}
+	dep.StepName = name
+	dep.Command, _ = r.get(recKeyCommand)
+	dep.Workdir, _ = r.get(recKeyWorkdir)
+
+	for _, kv := range r.getAll(recKeyEnv) {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			dep.Env[k] = v
+		}
+	}
+
+	if exitCodeStr, ok := r.get(recKeyExitCode); ok {
+		exitCode, err := strconv.Atoi(exitCodeStr)
+		if err != nil {
+			return dep, fmt.Errorf("recfile: malformed %s field: %w", recKeyExitCode, err)
+		}
+		dep.ExitCode = exitCode
+	}
+	dep.StartTime, _ = r.get(recKeyStartTime)
+	dep.EndTime, _ = r.get(recKeyEndTime)
+
+	for _, kv := range r.getAll(recKeyInputHash) {
+		if k, v, ok := strings.Cut(kv, "=sha256:"); ok {
+			dep.InputHashes[k] = v
+		}
+	}
+	for _, kv := range r.getAll(recKeyCreatedHash) {
+		if k, v, ok := strings.Cut(kv, "=sha256:"); ok {
+			dep.CreatedHashes[k] = v
+		}
+	}
+
+	return dep, nil
+}
+
+// stepDepsPath returns the sidecar path Execute writes per-step dependency
+// records to: outputFile with ".rec" appended.
+func stepDepsPath(outputFile string) string {
+	return outputFile + ".rec"
+}
+
+// writeStepDepsFile writes deps to path in recfile format.
+func writeStepDepsFile(path string, deps []StepDepRecord) error {
+	records := make([]recRecord, len(deps))
+	for i, dep := range deps {
+		records[i] = dep.toRecRecord()
+	}
+	return os.WriteFile(path, []byte(encodeRecfile(records)), 0644)
+}
+
+// readStepDepsFile reverses writeStepDepsFile.
+func readStepDepsFile(path string) ([]StepDepRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	records, err := decodeRecfile(string(data))
+	if err != nil {
+		return nil, err
+	}
+	deps := make([]StepDepRecord, 0, len(records))
+	for _, r := range records {
+		dep, err := stepDepRecordFromRec(r)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// ExecuteIfChanged behaves like Execute, except it first checks whether
+// e.execCtx.OutputFile already has a step dependency sidecar (written by a
+// prior successful Execute) whose recorded input hashes still match what's
+// on disk now. If every hash matches and the output file still exists, it
+// returns immediately with a synthetic success result referencing the
+// existing output instead of re-running the workflow — a redo-style
+// incremental build. Any mismatch, missing sidecar, or unparsable sidecar
+// falls through to a normal Execute.
+func (e *Executor) ExecuteIfChanged() (*ExecutionResult, error) {
+	if e.unchanged() {
+		e.log("=== Skipping Execution: Inputs Unchanged ===\n")
+		e.log("Step dependency sidecar %s matches current inputs; reusing %s\n",
+			stepDepsPath(e.execCtx.OutputFile), e.execCtx.OutputFile)
+		e.result.Success = true
+		e.result.ExitCode = 0
+		e.result.OutputFiles["output_file"] = e.execCtx.OutputFile
+		e.result.Logs = e.logBuf.String()
+		return e.result, nil
+	}
+	return e.Execute()
+}
+
+// unchanged reports whether the output file a prior run produced still
+// exists and every hash its .rec sidecar recorded still matches what's on
+// disk. CreatedHashes entries almost always fail this check in practice,
+// since Workdir is normally a fresh per-run temp directory (see
+// StepDepRecord.CreatedHashes) — that's intentional: we can't prove a
+// step's side effects are unchanged if we can no longer see them, so we
+// conservatively report "changed" rather than risk skipping real work.
+func (e *Executor) unchanged() bool {
+	if _, err := os.Stat(e.execCtx.OutputFile); err != nil {
+		return false
+	}
+
+	deps, err := readStepDepsFile(stepDepsPath(e.execCtx.OutputFile))
+	if err != nil || len(deps) == 0 {
+		return false
+	}
+
+	if err := e.prepareVariables(); err != nil {
+		return false
+	}
+
+	for _, dep := range deps {
+		for varName, wantHash := range dep.InputHashes {
+			path, ok := e.execCtx.Variables[varName]
+			if !ok {
+				return false
+			}
+			gotHash, err := hashFileSHA256(path)
+			if err != nil || gotHash != wantHash {
+				return false
+			}
+		}
+		for rel, wantHash := range dep.CreatedHashes {
+			gotHash, err := hashFileSHA256(filepath.Join(dep.Workdir, rel))
+			if err != nil || gotHash != wantHash {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,179 @@
+// Package workflowcache caches workflow conversion outputs on disk, keyed
+// by a digest of everything that determines them (source file content,
+// workflow definition, quality), so re-running the same workflow over the
+// same input is nearly free even after the database's files_index has been
+// wiped, or when the same source file appears under more than one watched
+// directory.
+package workflowcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/db"
+)
+
+// Cache stores workflow run outputs under dir, content-addressed by Key,
+// with a persistent index in db and disk usage bounded by maxBytes via LRU
+// eviction.
+type Cache struct {
+	db       *db.DB
+	dir      string
+	maxBytes int64
+}
+
+// New creates a Cache rooted at dir, creating it if necessary. maxBytes
+// bounds the total size of cached outputs; once exceeded, Store evicts the
+// least recently used entries until back under budget.
+func New(database *db.DB, dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	return &Cache{db: database, dir: dir, maxBytes: maxBytes}, nil
+}
+
+// Key derives the cache key for a workflow run from the source file's
+// content hash, the workflow definition it was run through, and the
+// quality setting used. Two runs that agree on all three produce
+// byte-identical output, so they can safely share a cache entry.
+//
+// tool_versions isn't included: this tree has no mechanism for probing the
+// installed versions of the external tools a workflow's container/shell
+// steps shell out to, so a change there can't yet be detected and would
+// require a stale cache entry to be invalidated by hand (e.g. bumping the
+// workflow's YAML).
+func Key(fileHash, hashAlgorithm, workflowYAML string, quality int) string {
+	h := sha256.New()
+	io.WriteString(h, fileHash)
+	io.WriteString(h, "|")
+	io.WriteString(h, hashAlgorithm)
+	io.WriteString(h, "|")
+	io.WriteString(h, workflowYAML)
+	io.WriteString(h, "|")
+	io.WriteString(h, strconv.Itoa(quality))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Lookup returns the cached output path and JSON-encoded converter.MetaResult
+// for key, refreshing its LRU position on a hit. It reports a miss (without
+// error) if there's no entry, or if the entry's file has gone missing from
+// disk (e.g. CacheDir was cleared out from under the database).
+func (c *Cache) Lookup(key string) (outputPath string, metaJSON string, ok bool) {
+	entry, err := c.db.GetWorkflowCacheEntry(key)
+	if err != nil || entry == nil {
+		return "", "", false
+	}
+
+	if _, err := os.Stat(entry.OutputPath); err != nil {
+		_ = c.db.DeleteWorkflowCacheEntry(key)
+		return "", "", false
+	}
+
+	_ = c.db.TouchWorkflowCacheEntry(key)
+	return entry.OutputPath, entry.MetaJSON, true
+}
+
+// Store copies producedPath into the content-addressed cache directory
+// under key, records it (with metaJSON) in the database, and evicts the
+// least recently used entries if this push the cache over its size budget.
+// It returns the cache's own copy of the file, which the caller should link
+// or copy to the task's real output path rather than reusing producedPath
+// directly (producedPath is typically a temp file the caller is about to
+// remove).
+func (c *Cache) Store(key, producedPath, metaJSON string) (string, error) {
+	info, err := os.Stat(producedPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat produced output: %w", err)
+	}
+
+	cachedPath := c.pathFor(key, filepath.Ext(producedPath))
+	if err := os.MkdirAll(filepath.Dir(cachedPath), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create cache shard dir: %w", err)
+	}
+
+	if err := copyFile(producedPath, cachedPath); err != nil {
+		return "", fmt.Errorf("failed to store cache entry: %w", err)
+	}
+
+	if err := c.db.UpsertWorkflowCacheEntry(&db.WorkflowCacheEntry{
+		Key:        key,
+		OutputPath: cachedPath,
+		MetaJSON:   metaJSON,
+		SizeBytes:  info.Size(),
+	}); err != nil {
+		return cachedPath, fmt.Errorf("failed to persist cache entry: %w", err)
+	}
+
+	c.evict()
+	return cachedPath, nil
+}
+
+// pathFor returns the on-disk path for key, sharded two levels deep (like a
+// git object store) so a large cache doesn't pile every entry into one
+// directory.
+func (c *Cache) pathFor(key, ext string) string {
+	return filepath.Join(c.dir, key[0:2], key[2:4], key+ext)
+}
+
+// evict removes the least recently used entries until the cache's total
+// size is back under maxBytes. It's best-effort: a failure to remove one
+// entry's file is logged-by-return-value-ignored by the caller and doesn't
+// stop eviction from continuing to the next.
+func (c *Cache) evict() {
+	if c.maxBytes <= 0 {
+		return
+	}
+
+	total, err := c.db.WorkflowCacheTotalSize()
+	if err != nil || total <= c.maxBytes {
+		return
+	}
+
+	// Evicting one entry at a time against a live total is simplest to
+	// reason about; workflow caches are expected to hold at most a few
+	// thousand entries, so re-querying the oldest one repeatedly is cheap
+	// relative to the disk I/O it's protecting against.
+	for total > c.maxBytes {
+		oldest, err := c.db.ListWorkflowCacheEntriesLRU(1)
+		if err != nil || len(oldest) == 0 {
+			return
+		}
+
+		entry := oldest[0]
+		os.Remove(entry.OutputPath)
+		if err := c.db.DeleteWorkflowCacheEntry(entry.Key); err != nil {
+			return
+		}
+		total -= entry.SizeBytes
+	}
+}
+
+// copyFile copies src to dst, trying a hardlink first since cache entries
+// are never modified in place.
+func copyFile(src, dst string) error {
+	os.Remove(dst)
+
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/cluster"
+	"github.com/ah-its-andy/jpeg2heif/internal/db"
+)
+
+func TestParsePeers(t *testing.T) {
+	peers := cluster.ParsePeers([]string{"node-b=http://node-b:8080/", "not-a-peer", "node-c=http://node-c:9090"})
+
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 valid peers, got %d: %+v", len(peers), peers)
+	}
+	if peers[0].Name != "node-b" || peers[0].URL != "http://node-b:8080" {
+		t.Errorf("expected node-b's trailing slash to be trimmed, got %+v", peers[0])
+	}
+	if peers[1].Name != "node-c" || peers[1].URL != "http://node-c:9090" {
+		t.Errorf("unexpected second peer: %+v", peers[1])
+	}
+}
+
+// newClusterTestRegistry builds a Registry backed by a fresh database, with
+// no peers configured (the push/pull loop is tested via direct HTTP calls
+// against its handlers instead of a real background loop).
+func newClusterTestRegistry(t *testing.T) *cluster.Registry {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	database, err := db.New(dbPath)
+	if err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	return cluster.NewRegistry(database, "node-a", nil, "", time.Minute)
+}
+
+func TestRegistryHandleLookupAndFetch(t *testing.T) {
+	reg := newClusterTestRegistry(t)
+
+	outputPath := filepath.Join(t.TempDir(), "output.heic")
+	if err := os.WriteFile(outputPath, []byte("fake heic bytes"), 0644); err != nil {
+		t.Fatalf("failed to write fake output: %v", err)
+	}
+	if err := reg.RecordOutput("/watch/photo.jpg", "abc123", "md5", "jpeg2heic", outputPath); err != nil {
+		t.Fatalf("failed to record output: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/cluster/lookup", reg.HandleLookup)
+	mux.HandleFunc("/api/cluster/fetch", reg.HandleFetch)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	lookupResp, err := http.Get(srv.URL + "/api/cluster/lookup?md5=abc123&hash_algo=md5&converter=jpeg2heic")
+	if err != nil {
+		t.Fatalf("lookup request failed: %v", err)
+	}
+	defer lookupResp.Body.Close()
+	if lookupResp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from lookup, got %d", lookupResp.StatusCode)
+	}
+
+	missResp, err := http.Get(srv.URL + "/api/cluster/lookup?md5=doesnotexist&hash_algo=md5&converter=jpeg2heic")
+	if err != nil {
+		t.Fatalf("lookup request failed: %v", err)
+	}
+	defer missResp.Body.Close()
+	if missResp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown content hash, got %d", missResp.StatusCode)
+	}
+
+	fetchResp, err := http.Get(srv.URL + "/api/cluster/fetch?md5=abc123&hash_algo=md5&converter=jpeg2heic")
+	if err != nil {
+		t.Fatalf("fetch request failed: %v", err)
+	}
+	defer fetchResp.Body.Close()
+	body, err := io.ReadAll(fetchResp.Body)
+	if err != nil {
+		t.Fatalf("failed to read fetch response: %v", err)
+	}
+	if string(body) != "fake heic bytes" {
+		t.Errorf("expected fetch to stream the recorded output, got %q", string(body))
+	}
+}
+
+func TestRegistryHandleGossipAndHandleIndexDelta(t *testing.T) {
+	reg := newClusterTestRegistry(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/cluster/gossip", reg.HandleGossip)
+	mux.HandleFunc("/api/cluster/index", reg.HandleIndexDelta)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	gossipBody := `{"node_id":"node-b","entries":[{"file_path":"/watch/photo.jpg","file_md5":"abc123","hash_algo":"md5","status":"success","converter_name":"jpeg2heic","target_path":"/remote/output.heic","updated_at":"2026-01-01T00:00:00Z"}]}`
+	resp, err := http.Post(srv.URL+"/api/cluster/gossip", "application/json", strReader(gossipBody))
+	if err != nil {
+		t.Fatalf("gossip request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from gossip, got %d", resp.StatusCode)
+	}
+
+	entry, ok := reg.Consult("abc123", "md5", "jpeg2heic")
+	if !ok {
+		t.Fatal("expected the gossiped entry to be consultable locally")
+	}
+	if entry.PeerNode != "node-b" {
+		t.Errorf("expected peer node 'node-b', got %q", entry.PeerNode)
+	}
+
+	indexResp, err := http.Get(srv.URL + "/api/cluster/index")
+	if err != nil {
+		t.Fatalf("index request failed: %v", err)
+	}
+	defer indexResp.Body.Close()
+	if indexResp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from index, got %d", indexResp.StatusCode)
+	}
+}
+
+func strReader(s string) *stringReadCloser {
+	return &stringReadCloser{s: s}
+}
+
+// stringReadCloser adapts a string into an io.Reader for http.Post.
+type stringReadCloser struct {
+	s   string
+	pos int
+}
+
+func (r *stringReadCloser) Read(p []byte) (int, error) {
+	if r.pos >= len(r.s) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.s[r.pos:])
+	r.pos += n
+	return n, nil
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/ah-its-andy/jpeg2heif/internal/db"
 	"github.com/ah-its-andy/jpeg2heif/internal/util"
@@ -255,6 +256,92 @@ func TestMD5Calculation(t *testing.T) {
 	}
 }
 
+func TestClusterOutputAndPeerIndexOperations(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "test.db")
+	database, err := db.New(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	output := &db.ClusterOutput{
+		FilePath:      "/test/path/file.jpg",
+		FileMD5:       "abc123",
+		HashAlgo:      "md5",
+		ConverterName: "jpeg2heic",
+		TargetPath:    "/data/output/file.heic",
+	}
+	if err := database.UpsertClusterOutput(output); err != nil {
+		t.Fatalf("Failed to upsert cluster output: %v", err)
+	}
+
+	got, err := database.GetClusterOutput("abc123", "md5", "jpeg2heic")
+	if err != nil {
+		t.Fatalf("Failed to get cluster output: %v", err)
+	}
+	if got == nil || got.TargetPath != output.TargetPath {
+		t.Fatalf("expected to retrieve cluster output with target path %s, got %+v", output.TargetPath, got)
+	}
+
+	if _, err := database.GetClusterOutput("doesnotexist", "md5", "jpeg2heic"); err != nil {
+		t.Errorf("expected no error on a miss, got %v", err)
+	}
+
+	entry := &db.PeerIndexEntry{
+		PeerNode:      "node-b",
+		FilePath:      "/remote/path/file.jpg",
+		FileMD5:       "def456",
+		HashAlgo:      "md5",
+		Status:        "success",
+		ConverterName: "jpeg2heic",
+		TargetPath:    "/remote/data/output/file.heic",
+		UpdatedAt:     time.Now(),
+	}
+	if err := database.UpsertPeerIndexEntry(entry); err != nil {
+		t.Fatalf("Failed to upsert peer index entry: %v", err)
+	}
+
+	gotEntry, err := database.GetPeerIndexEntry("def456", "md5", "jpeg2heic")
+	if err != nil {
+		t.Fatalf("Failed to get peer index entry: %v", err)
+	}
+	if gotEntry == nil || gotEntry.PeerNode != "node-b" {
+		t.Fatalf("expected to retrieve peer index entry from node-b, got %+v", gotEntry)
+	}
+
+	watermark, err := database.GetPeerWatermark("node-b")
+	if err != nil {
+		t.Fatalf("Failed to get peer watermark: %v", err)
+	}
+	if !watermark.IsZero() {
+		t.Errorf("expected zero watermark before any reconciliation, got %v", watermark)
+	}
+
+	now := time.Now()
+	if err := database.SetPeerWatermark("node-b", now); err != nil {
+		t.Fatalf("Failed to set peer watermark: %v", err)
+	}
+	watermark, err = database.GetPeerWatermark("node-b")
+	if err != nil {
+		t.Fatalf("Failed to get peer watermark after set: %v", err)
+	}
+	if !watermark.Equal(now) {
+		t.Errorf("expected watermark %v, got %v", now, watermark)
+	}
+
+	if err := database.InsertClusterPeerHit("def456", "jpeg2heic", "node-b"); err != nil {
+		t.Fatalf("Failed to insert cluster peer hit: %v", err)
+	}
+	stats, err := database.GetStats()
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.PeerHitCount != 1 {
+		t.Errorf("expected PeerHitCount 1, got %d", stats.PeerHitCount)
+	}
+}
+
 func TestMD5ChunkSize(t *testing.T) {
 	// Create a larger test file
 	tempDir := t.TempDir()
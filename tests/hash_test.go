@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/util"
+)
+
+func TestFileHasherAlgorithms(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.txt")
+	content := []byte("Hello, World!")
+
+	if err := os.WriteFile(testFile, content, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Known-answer digest for the md5 algorithm; the others are checked only
+	// for returning a non-empty digest.
+	const expectedMD5 = "65a8e27d8879283831b664bd8b7f0ad4"
+
+	for _, algo := range []string{"md5", "sha256", "blake3", "xxh3"} {
+		hasher, err := util.NewFileHasher(algo)
+		if err != nil {
+			t.Fatalf("NewFileHasher(%q) failed: %v", algo, err)
+		}
+
+		if hasher.Algorithm() != algo {
+			t.Errorf("Algorithm() = %q, want %q", hasher.Algorithm(), algo)
+		}
+
+		digest, err := hasher.Hash(testFile, 8192)
+		if err != nil {
+			t.Fatalf("Hash with %q failed: %v", algo, err)
+		}
+		if digest == "" {
+			t.Errorf("Hash with %q returned an empty digest", algo)
+		}
+
+		if algo == "md5" && digest != expectedMD5 {
+			t.Errorf("md5 digest = %s, want %s", digest, expectedMD5)
+		}
+	}
+}
+
+func TestNewFileHasherUnknownAlgorithm(t *testing.T) {
+	if _, err := util.NewFileHasher("not-a-real-algorithm"); err == nil {
+		t.Error("expected an error for an unknown algorithm, got nil")
+	}
+}
+
+func TestNewFileHasherDefaultsToMD5(t *testing.T) {
+	hasher, err := util.NewFileHasher("")
+	if err != nil {
+		t.Fatalf("NewFileHasher(\"\") failed: %v", err)
+	}
+	if hasher.Algorithm() != "md5" {
+		t.Errorf("Algorithm() = %q, want %q", hasher.Algorithm(), "md5")
+	}
+}
+
+// TestFileHasherSerialDigestIndependentOfChunkSize hashes a file well below
+// parallelHashThreshold, so it takes the serial path, with two different
+// chunk sizes and checks both give the same digest.
+func TestFileHasherSerialDigestIndependentOfChunkSize(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "big.bin")
+
+	// A small file stays on the serial path regardless of chunk size, so
+	// hashing it with two different chunk sizes is a cheap way to confirm
+	// the digest doesn't depend on how the file was chunked while reading.
+	content := make([]byte, 256*1024)
+	for i := range content {
+		content[i] = byte(i % 256)
+	}
+	if err := os.WriteFile(testFile, content, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	hasher, err := util.NewFileHasher("sha256")
+	if err != nil {
+		t.Fatalf("NewFileHasher failed: %v", err)
+	}
+
+	small, err := hasher.Hash(testFile, 1024)
+	if err != nil {
+		t.Fatalf("Hash with chunk size 1024 failed: %v", err)
+	}
+
+	large, err := hasher.Hash(testFile, 65536)
+	if err != nil {
+		t.Fatalf("Hash with chunk size 65536 failed: %v", err)
+	}
+
+	if small != large {
+		t.Errorf("digest depends on chunk size: %s != %s", small, large)
+	}
+}
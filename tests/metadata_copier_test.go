@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/metadata"
+)
+
+// TestMetadataCopiers_RoundTrip exercises the three metadata.Copier backends
+// (exiftool, native, auto) against the same source/destination pair and
+// checks that each preserves DateTimeOriginal. GPS, Orientation, and an ICC
+// profile are also written onto the fixture so Copy has real EXIF/ICC
+// segments to carry over, even though only DateTimeOriginal is asserted
+// here (the one tag every backend can verify through the shared
+// VerifyDateTimeOriginal contract).
+func TestMetadataCopiers_RoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("exiftool"); err != nil {
+		t.Skip("exiftool not found")
+	}
+	if _, err := exec.LookPath("magick"); err != nil {
+		t.Skip("magick not found")
+	}
+
+	dto := time.Date(2022, 6, 1, 9, 15, 0, 0, time.UTC).Format("2006:01:02 15:04:05")
+
+	backends := []struct {
+		name    string
+		backend string
+	}{
+		{"exiftool", "exiftool"},
+		{"native", "native"},
+		{"auto", "auto"},
+	}
+
+	for _, b := range backends {
+		b := b
+		t.Run(b.name, func(t *testing.T) {
+			d := t.TempDir()
+			src := filepath.Join(d, "src.jpg")
+			createJPEG(t, src)
+
+			writeCmd := exec.Command("exiftool", "-overwrite_original",
+				"-DateTimeOriginal="+dto,
+				"-Orientation=6",
+				"-GPSLatitude=37.7749", "-GPSLatitudeRef=N",
+				"-GPSLongitude=122.4194", "-GPSLongitudeRef=W",
+				src)
+			if out, err := writeCmd.CombinedOutput(); err != nil {
+				t.Skipf("exiftool write failed: %v, %s", err, string(out))
+			}
+
+			dst := filepath.Join(d, "dst.heic")
+			convCmd := exec.Command("magick", src, "-quality", "90", dst)
+			if out, err := convCmd.CombinedOutput(); err != nil {
+				t.Skipf("magick convert failed: %v, %s", err, string(out))
+			}
+
+			copier := metadata.NewCopier(b.backend)
+			if _, err := copier.Copy(context.Background(), src, dst); err != nil {
+				t.Fatalf("%s copy failed: %v", b.name, err)
+			}
+
+			preserved, detail, err := copier.VerifyDateTimeOriginal(src, dst)
+			if err != nil {
+				t.Fatalf("%s verify failed: %v", b.name, err)
+			}
+			if !preserved {
+				t.Fatalf("%s: DateTimeOriginal not preserved: %s", b.name, detail)
+			}
+		})
+	}
+}
+
+// TestNativeCopier_ExtractsICC checks that NativeCopier's segment parser
+// picks up an ICC profile embedded via exiftool, independent of the HEIC
+// round trip covered above.
+func TestNativeCopier_ExtractsICC(t *testing.T) {
+	if _, err := exec.LookPath("exiftool"); err != nil {
+		t.Skip("exiftool not found")
+	}
+
+	d := t.TempDir()
+	src := filepath.Join(d, "src.jpg")
+	createJPEG(t, src)
+
+	// Embed the system sRGB ICC profile at /usr/share/color/icc/sRGB.icc; if
+	// it isn't installed (or exiftool can't embed it), skip rather than fail.
+	cmd := exec.Command("exiftool", "-overwrite_original", "-icc_profile<=/usr/share/color/icc/sRGB.icc", src)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("exiftool icc embed failed (no fixture profile available): %v, %s", err, string(out))
+	}
+
+	meta, err := metadata.ExtractSourceMetadata(src)
+	if err != nil {
+		t.Fatalf("ExtractSourceMetadata failed: %v", err)
+	}
+	if len(meta.ICC) == 0 {
+		t.Fatalf("expected ICC profile to be extracted")
+	}
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/worker"
+)
+
+func TestQueueDedupAcrossPriorities(t *testing.T) {
+	q := worker.NewQueue(4)
+
+	if !q.EnqueueWithPriority(1, int(worker.PriorityLow)) {
+		t.Fatalf("expected first enqueue of id 1 to succeed")
+	}
+	if q.EnqueueWithPriority(1, int(worker.PriorityHigh)) {
+		t.Fatalf("expected re-enqueue of already-queued id 1 at a different priority to fail")
+	}
+	if q.Len() != 1 {
+		t.Fatalf("expected Len() == 1, got %d", q.Len())
+	}
+
+	id := <-q.Chan()
+	if id != 1 {
+		t.Fatalf("expected to dequeue id 1, got %d", id)
+	}
+	q.Dequeued(1)
+
+	if !q.EnqueueWithPriority(1, int(worker.PriorityHigh)) {
+		t.Fatalf("expected id 1 to be enqueueable again after Dequeued")
+	}
+}
+
+func TestQueueFairnessUnderSaturation(t *testing.T) {
+	q := worker.NewQueue(100)
+
+	const highCount, normalCount, lowCount = 40, 40, 40
+
+	// Hold the dispatcher while the lanes fill, so it can't hand out highs
+	// before the normal and low lanes have anything in them.
+	q.Pause()
+	id := uint(1)
+	for i := 0; i < highCount; i++ {
+		q.EnqueueWithPriority(id, int(worker.PriorityHigh))
+		id++
+	}
+	for i := 0; i < normalCount; i++ {
+		q.EnqueueWithPriority(id, int(worker.PriorityNormal))
+		id++
+	}
+	for i := 0; i < lowCount; i++ {
+		q.EnqueueWithPriority(id, int(worker.PriorityLow))
+		id++
+	}
+	q.Resume()
+
+	// Drain the first 28 items (4 weighted rounds: 4 high + 2 normal + 1
+	// low each) and confirm the high lane is serviced well ahead of low,
+	// without low being starved entirely.
+	counts := map[uint]int{}
+	for i := 0; i < 28; i++ {
+		select {
+		case got := <-q.Chan():
+			if got <= highCount {
+				counts[0]++ // high
+			} else if got <= highCount+normalCount {
+				counts[1]++ // normal
+			} else {
+				counts[2]++ // low
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for queue to dispatch item %d", i)
+		}
+	}
+
+	if counts[0] <= counts[1] || counts[1] <= counts[2] {
+		t.Fatalf("expected high > normal > low dispatch counts, got high=%d normal=%d low=%d", counts[0], counts[1], counts[2])
+	}
+	if counts[2] == 0 {
+		t.Fatalf("expected low priority lane to make some progress, got 0")
+	}
+}
+
+func TestQueueRequeueDelaysReentry(t *testing.T) {
+	q := worker.NewQueue(4)
+
+	if !q.EnqueueWithPriority(1, int(worker.PriorityNormal)) {
+		t.Fatalf("expected enqueue to succeed")
+	}
+	<-q.Chan()
+	q.Dequeued(1)
+
+	if !q.Requeue(1, int(worker.PriorityHigh), 50*time.Millisecond) {
+		t.Fatalf("expected Requeue to succeed")
+	}
+
+	select {
+	case <-q.Chan():
+		t.Fatalf("expected requeued item to stay parked until its delay elapses")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	select {
+	case got := <-q.Chan():
+		if got != 1 {
+			t.Fatalf("expected requeued id 1, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for requeued item to reappear")
+	}
+}
+
+func TestQueuePauseResume(t *testing.T) {
+	q := worker.NewQueue(4)
+	q.Pause()
+
+	if !q.EnqueueWithPriority(1, int(worker.PriorityHigh)) {
+		t.Fatalf("expected enqueue to succeed while paused")
+	}
+
+	select {
+	case <-q.Chan():
+		t.Fatalf("expected no dispatch while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	q.Resume()
+
+	select {
+	case got := <-q.Chan():
+		if got != 1 {
+			t.Fatalf("expected id 1 after resume, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for dispatch after resume")
+	}
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/db"
+)
+
+func TestSearchTasks(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "test.db")
+	database, err := db.New(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	tasks := []*db.TaskHistory{
+		{FilePath: "/test/a.jpg", ConverterName: "jpeg2heic", Status: "success", ConsoleOutput: "converted alphamarkerxyz successfully"},
+		{FilePath: "/test/b.jpg", ConverterName: "jpeg2heic", Status: "failed", ConsoleOutput: "unrelated output, no match here"},
+	}
+	for _, task := range tasks {
+		if err := database.InsertTaskHistory(task); err != nil {
+			t.Fatalf("Failed to insert task history: %v", err)
+		}
+	}
+
+	hits, err := database.SearchTasks("alphamarkerxyz", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchTasks: %v", err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("Expected 1 hit, got %d", len(hits))
+	}
+	if hits[0].Task.ID != tasks[0].ID {
+		t.Errorf("Expected hit for task %d, got %d", tasks[0].ID, hits[0].Task.ID)
+	}
+	if hits[0].Snippet == "" {
+		t.Error("Expected a non-empty snippet")
+	}
+
+	if hits, err := database.SearchTasks("nonexistenttermzzz", 10, 0); err != nil {
+		t.Fatalf("SearchTasks: %v", err)
+	} else if len(hits) != 0 {
+		t.Errorf("Expected no hits for a term that appears nowhere, got %d", len(hits))
+	}
+}
+
+// TestSearchWorkflowRunsTriggerSyncAndFilters confirms the workflow_runs_fts
+// index stays in sync as a run is created, updated with its real stdout, and
+// eventually pruned (the AFTER INSERT/UPDATE/DELETE triggers from the
+// search_indexes migration), and that SearchFilters narrows matches using
+// workflow_runs_fts's UNINDEXED columns.
+func TestSearchWorkflowRunsTriggerSyncAndFilters(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "test.db")
+	database, err := db.New(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	wf := &db.Workflow{Name: "search-test-workflow", YAML: "name: search-test-workflow", Enabled: true, CreatedBy: "tester"}
+	if err := database.CreateWorkflow(wf); err != nil {
+		t.Fatalf("Failed to create workflow: %v", err)
+	}
+
+	run := &db.WorkflowRun{
+		WorkflowID:   wf.ID,
+		WorkflowName: wf.Name,
+		FilePath:     "/test/run.jpg",
+		Status:       "running",
+		StartTime:    time.Now().Add(-time.Hour),
+	}
+	if err := database.CreateWorkflowRun(run); err != nil {
+		t.Fatalf("Failed to create workflow run: %v", err)
+	}
+
+	// CreateWorkflowRun never sets stdout, so the row inserted into
+	// workflow_runs_fts by the AFTER INSERT trigger has nothing searchable
+	// yet - confirms the insert-time sync, not a stale index from some
+	// other row.
+	hits, err := database.SearchWorkflowRuns("betamarkerxyz", 10, 0, db.SearchFilters{})
+	if err != nil {
+		t.Fatalf("SearchWorkflowRuns: %v", err)
+	}
+	if len(hits) != 0 {
+		t.Fatalf("Expected no hits before stdout is set, got %d", len(hits))
+	}
+
+	run.Status = "success"
+	run.Stdout = "finished with betamarkerxyz in the log"
+	if err := database.UpdateWorkflowRun(run); err != nil {
+		t.Fatalf("Failed to update workflow run: %v", err)
+	}
+
+	hits, err = database.SearchWorkflowRuns("betamarkerxyz", 10, 0, db.SearchFilters{})
+	if err != nil {
+		t.Fatalf("SearchWorkflowRuns: %v", err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("Expected 1 hit after the update synced stdout into the index, got %d", len(hits))
+	}
+	if hits[0].Run.ID != run.ID {
+		t.Errorf("Expected hit for run %d, got %d", run.ID, hits[0].Run.ID)
+	}
+
+	if hits, err := database.SearchWorkflowRuns("betamarkerxyz", 10, 0, db.SearchFilters{WorkflowID: wf.ID + 1}); err != nil {
+		t.Fatalf("SearchWorkflowRuns: %v", err)
+	} else if len(hits) != 0 {
+		t.Errorf("Expected WorkflowID filter to exclude the run, got %d hits", len(hits))
+	}
+
+	if hits, err := database.SearchWorkflowRuns("betamarkerxyz", 10, 0, db.SearchFilters{Status: "failed"}); err != nil {
+		t.Fatalf("SearchWorkflowRuns: %v", err)
+	} else if len(hits) != 0 {
+		t.Errorf("Expected Status filter to exclude the run, got %d hits", len(hits))
+	}
+
+	if hits, err := database.SearchWorkflowRuns("betamarkerxyz", 10, 0, db.SearchFilters{WorkflowID: wf.ID, Status: "success"}); err != nil {
+		t.Fatalf("SearchWorkflowRuns: %v", err)
+	} else if len(hits) != 1 {
+		t.Errorf("Expected matching WorkflowID/Status filters to keep the run, got %d hits", len(hits))
+	}
+
+	if _, err := database.Prune(context.Background(), db.PruneOptions{WorkflowRunMaxAge: time.Minute}); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+
+	hits, err = database.SearchWorkflowRuns("betamarkerxyz", 10, 0, db.SearchFilters{})
+	if err != nil {
+		t.Fatalf("SearchWorkflowRuns: %v", err)
+	}
+	if len(hits) != 0 {
+		t.Fatalf("Expected Prune's delete to remove the fts row too, got %d hits", len(hits))
+	}
+}
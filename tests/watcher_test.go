@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/watcher"
+)
+
+// newTestWatcher creates a native-mode Watcher over dir with a short
+// stabilityDelay, a pollInterval long enough that the periodic scan never
+// fires again during a test, and no filtering beyond the default media
+// extensions, started and registered for cleanup. Start launches
+// periodicScan's unconditional initial scanDirectories() in its own
+// goroutine, racing whatever the caller does next; since dir is expected to
+// be empty at this point, that scan should find nothing, so this waits
+// long enough for it to actually run and drains any event it produced
+// anyway, so a test's own writes can't be mistaken for files the initial
+// scan discovered.
+func newTestWatcher(t *testing.T, dir string, stabilityDelay time.Duration) *watcher.Watcher {
+	t.Helper()
+	w, err := watcher.New([]string{dir}, stabilityDelay, time.Hour, watcher.ModeNative, watcher.FilterConfig{})
+	if err != nil {
+		t.Fatalf("watcher.New: %v", err)
+	}
+	if err := w.Start(); err != nil {
+		t.Fatalf("w.Start: %v", err)
+	}
+	t.Cleanup(func() { w.Stop() })
+
+	time.Sleep(300 * time.Millisecond)
+	drainEvents(w)
+
+	return w
+}
+
+// drainEvents discards every event already queued on w.Events() without
+// blocking, once the caller knows nothing further should be arriving.
+func drainEvents(w *watcher.Watcher) {
+	for {
+		select {
+		case <-w.Events():
+		default:
+			return
+		}
+	}
+}
+
+func waitForEvent(t *testing.T, w *watcher.Watcher, timeout time.Duration) (watcher.FileEvent, bool) {
+	t.Helper()
+	select {
+	case ev := <-w.Events():
+		return ev, true
+	case <-time.After(timeout):
+		return watcher.FileEvent{}, false
+	}
+}
+
+func expectNoEventYet(t *testing.T, w *watcher.Watcher, within time.Duration) {
+	t.Helper()
+	select {
+	case ev := <-w.Events():
+		t.Fatalf("expected no event yet, got %+v", ev)
+	case <-time.After(within):
+	}
+}
+
+// TestWatcherDebounceResetsOnWrite confirms a second write inside the
+// stability window pushes the debounce timer back out rather than letting
+// the first write's timer fire on schedule, per debounceFile.
+func TestWatcherDebounceResetsOnWrite(t *testing.T) {
+	dir := t.TempDir()
+	stabilityDelay := 200 * time.Millisecond
+	w := newTestWatcher(t, dir, stabilityDelay)
+
+	path := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatalf("write v1: %v", err)
+	}
+
+	// Rewrite partway through the original debounce window - if the timer
+	// weren't being reset, the event below would already have fired by now.
+	time.Sleep(stabilityDelay / 2)
+	if err := os.WriteFile(path, []byte("v2 - longer content"), 0644); err != nil {
+		t.Fatalf("write v2: %v", err)
+	}
+
+	// Nothing should arrive before the reset timer's own delay elapses.
+	expectNoEventYet(t, w, stabilityDelay)
+
+	// After the reset debounce window plus the one extra stability sample
+	// debounceStable takes before emitting, the create event should land.
+	ev, ok := waitForEvent(t, w, 3*time.Second)
+	if !ok {
+		t.Fatalf("timed out waiting for debounced event")
+	}
+	if ev.Path != path || ev.Operation != "create" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+// TestWatcherDebounceRequiresTwoStableSamples confirms a file that's still
+// being rewritten when debounceStable takes its first sample doesn't fire
+// until it has seen two consecutive samples, stabilitySampleInterval apart,
+// that actually agree.
+func TestWatcherDebounceRequiresTwoStableSamples(t *testing.T) {
+	dir := t.TempDir()
+	stabilityDelay := 150 * time.Millisecond
+	w := newTestWatcher(t, dir, stabilityDelay)
+
+	path := filepath.Join(dir, "growing.jpg")
+	if err := os.WriteFile(path, []byte("v0"), 0644); err != nil {
+		t.Fatalf("initial write: %v", err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		i := 0
+		ticker := time.NewTicker(300 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				i++
+				_ = os.WriteFile(path, []byte(time.Now().String()), 0644)
+				_ = i
+			}
+		}
+	}()
+
+	// The file keeps changing well past the first debounce window, so no
+	// event should appear while the rewrites are still happening.
+	expectNoEventYet(t, w, 900*time.Millisecond)
+
+	close(stop)
+	<-done
+
+	ev, ok := waitForEvent(t, w, 3*time.Second)
+	if !ok {
+		t.Fatalf("timed out waiting for event once the file stopped changing")
+	}
+	if ev.Path != path || ev.Operation != "create" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+// TestScanNowErrWatcherNotRunning confirms ScanNow rejects a context that's
+// already done before the scan starts, distinct from a scan canceled
+// partway through.
+func TestScanNowErrWatcherNotRunning(t *testing.T) {
+	dir := t.TempDir()
+	w, err := watcher.New([]string{dir}, time.Second, time.Hour, watcher.ModeNative, watcher.FilterConfig{})
+	if err != nil {
+		t.Fatalf("watcher.New: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := w.ScanNow(ctx); !errors.Is(err, watcher.ErrWatcherNotRunning) {
+		t.Fatalf("expected ErrWatcherNotRunning, got %v", err)
+	}
+}
+
+// TestScanNowCancelsMidWalk confirms ScanNow aborts once ctx is canceled
+// while it's blocked handing files to a full fileQueue, rather than
+// ignoring cancellation and draining everything it found.
+func TestScanNowCancelsMidWalk(t *testing.T) {
+	dir := t.TempDir()
+
+	// fileQueue buffers 1000 entries; create enough media files that the
+	// queue fills up and ScanNow blocks on a send before the walk finishes,
+	// with nothing reading from Events() to drain it.
+	const fileCount = 1200
+	for i := 0; i < fileCount; i++ {
+		name := filepath.Join(dir, "img"+itoa(i)+".jpg")
+		if err := os.WriteFile(name, []byte("x"), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	w, err := watcher.New([]string{dir}, time.Second, time.Hour, watcher.ModeNative, watcher.FilterConfig{})
+	if err != nil {
+		t.Fatalf("watcher.New: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err = w.ScanNow(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded once the queue filled and the deadline passed, got %v", err)
+	}
+}
+
+func itoa(i int) string {
+	if i == 0 {
+		return "0"
+	}
+	neg := i < 0
+	if neg {
+		i = -i
+	}
+	var buf [20]byte
+	pos := len(buf)
+	for i > 0 {
+		pos--
+		buf[pos] = byte('0' + i%10)
+		i /= 10
+	}
+	if neg {
+		pos--
+		buf[pos] = '-'
+	}
+	return string(buf[pos:])
+}
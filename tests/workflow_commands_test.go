@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ah-its-andy/jpeg2heif/internal/workflow"
+)
+
+// newCommandsTestExecCtx builds a minimal ExecutionContext backed by a real
+// temp dir and a small input file, since Executor.Execute calculates the
+// input file's MD5 and copies the (non-existent, for these tests) output
+// file only when an "output_file" output is declared.
+func newCommandsTestExecCtx(t *testing.T) *workflow.ExecutionContext {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	inputFile := filepath.Join(tmpDir, "input.txt")
+	if err := os.WriteFile(inputFile, []byte("hello"), 0644); err != nil {
+		t.Fatalf("failed to write input file: %v", err)
+	}
+
+	return &workflow.ExecutionContext{
+		WorkflowName: "commands-test",
+		InputFile:    inputFile,
+		OutputFile:   filepath.Join(tmpDir, "output.txt"),
+		TempDir:      tmpDir,
+		Quality:      80,
+		Variables:    make(map[string]string),
+	}
+}
+
+func TestWorkflowCommandsSetOutputFeedsOutputsTemplate(t *testing.T) {
+	yamlContent := `
+name: commands-set-output
+runs-on: shell
+steps:
+  - name: emit
+    run: echo "::set-output name=GREETING::hello-from-step"
+
+outputs:
+  greeting_file: "{{GREETING}}"
+`
+	spec, err := workflow.ParseWorkflow(yamlContent)
+	if err != nil {
+		t.Fatalf("failed to parse workflow: %v", err)
+	}
+
+	execCtx := newCommandsTestExecCtx(t)
+	executor := workflow.NewExecutor(spec, context.Background(), execCtx)
+	result, err := executor.Execute()
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	if got := result.OutputFiles["greeting_file"]; got != "hello-from-step" {
+		t.Errorf("expected outputs.greeting_file to resolve set-output value, got %q", got)
+	}
+}
+
+func TestWorkflowCommandsAddMaskScrubsLogsAndAnnotations(t *testing.T) {
+	yamlContent := `
+name: commands-add-mask
+runs-on: shell
+steps:
+  - name: leak
+    run: |
+      echo "::add-mask::s3kr1t"
+      echo "::error::token is s3kr1t"
+      echo "plain output mentions s3kr1t too"
+`
+	spec, err := workflow.ParseWorkflow(yamlContent)
+	if err != nil {
+		t.Fatalf("failed to parse workflow: %v", err)
+	}
+
+	execCtx := newCommandsTestExecCtx(t)
+	executor := workflow.NewExecutor(spec, context.Background(), execCtx)
+	result, err := executor.Execute()
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	if strings.Contains(result.Logs, "s3kr1t") {
+		t.Errorf("expected masked secret to be scrubbed from Logs, got: %s", result.Logs)
+	}
+	if len(result.Annotations) != 1 {
+		t.Fatalf("expected 1 annotation, got %d", len(result.Annotations))
+	}
+	if result.Annotations[0].Level != "error" {
+		t.Errorf("expected annotation level 'error', got %q", result.Annotations[0].Level)
+	}
+	if strings.Contains(result.Annotations[0].Message, "s3kr1t") {
+		t.Errorf("expected masked secret to be scrubbed from annotation message, got: %s", result.Annotations[0].Message)
+	}
+}
+
+func TestWorkflowCommandsGithubEnvPersistsToLaterSteps(t *testing.T) {
+	yamlContent := `
+name: commands-github-env
+runs-on: shell
+steps:
+  - name: set-env
+    run: echo "STAGE=converted" >> "$GITHUB_ENV"
+  - name: use-env
+    run: echo "stage is $STAGE"
+`
+	spec, err := workflow.ParseWorkflow(yamlContent)
+	if err != nil {
+		t.Fatalf("failed to parse workflow: %v", err)
+	}
+
+	execCtx := newCommandsTestExecCtx(t)
+	executor := workflow.NewExecutor(spec, context.Background(), execCtx)
+	result, err := executor.Execute()
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	if len(result.StepResults) != 2 {
+		t.Fatalf("expected 2 step results, got %d", len(result.StepResults))
+	}
+	if got := result.StepResults[1].Stdout; !strings.Contains(got, "stage is converted") {
+		t.Errorf("expected second step to see STAGE set by the first step's GITHUB_ENV, got stdout: %q", got)
+	}
+}
+
+func TestWorkflowCommandsStepSummaryAccumulatesAcrossSteps(t *testing.T) {
+	yamlContent := `
+name: commands-step-summary
+runs-on: shell
+steps:
+  - name: first
+    run: echo "## first step" >> "$GITHUB_STEP_SUMMARY"
+  - name: second
+    run: echo "## second step" >> "$GITHUB_STEP_SUMMARY"
+`
+	spec, err := workflow.ParseWorkflow(yamlContent)
+	if err != nil {
+		t.Fatalf("failed to parse workflow: %v", err)
+	}
+
+	execCtx := newCommandsTestExecCtx(t)
+	executor := workflow.NewExecutor(spec, context.Background(), execCtx)
+	result, err := executor.Execute()
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	if !strings.Contains(result.Summary, "## first step") || !strings.Contains(result.Summary, "## second step") {
+		t.Errorf("expected summary to contain both steps' contributions, got: %q", result.Summary)
+	}
+}
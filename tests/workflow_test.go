@@ -88,6 +88,71 @@ func TestValidateWorkflow(t *testing.T) {
 			},
 			expectValid: false,
 		},
+		{
+			name: "valid inputs.match",
+			spec: &workflow.WorkflowSpec{
+				Name:   "raw-passthrough",
+				RunsOn: "shell",
+				Inputs: &workflow.InputsSpec{
+					Match: &workflow.MatchSpec{
+						Patterns: []string{"raw/**/*.{cr2,CR2}", "!**/thumbs/**"},
+					},
+				},
+				Steps: []workflow.Step{
+					{Name: "step1", Run: "echo test"},
+				},
+				Outputs: map[string]string{"output_file": "{{TMP_OUTPUT}}"},
+			},
+			expectValid: true,
+		},
+		{
+			name: "inputs.match with no patterns",
+			spec: &workflow.WorkflowSpec{
+				Name:   "empty-match",
+				RunsOn: "shell",
+				Inputs: &workflow.InputsSpec{
+					Match: &workflow.MatchSpec{},
+				},
+				Steps: []workflow.Step{
+					{Name: "step1", Run: "echo test"},
+				},
+			},
+			expectValid: false,
+		},
+		{
+			name: "inputs.match with invalid glob",
+			spec: &workflow.WorkflowSpec{
+				Name:   "bad-glob",
+				RunsOn: "shell",
+				Inputs: &workflow.InputsSpec{
+					Match: &workflow.MatchSpec{
+						Patterns: []string{"raw/**/*.{cr2"},
+					},
+				},
+				Steps: []workflow.Step{
+					{Name: "step1", Run: "echo test"},
+				},
+			},
+			expectValid: false,
+		},
+		{
+			name: "inputs.match with min_size over max_size",
+			spec: &workflow.WorkflowSpec{
+				Name:   "bad-size-range",
+				RunsOn: "shell",
+				Inputs: &workflow.InputsSpec{
+					Match: &workflow.MatchSpec{
+						Patterns: []string{"**/*.jpg"},
+						MinSize:  1000,
+						MaxSize:  100,
+					},
+				},
+				Steps: []workflow.Step{
+					{Name: "step1", Run: "echo test"},
+				},
+			},
+			expectValid: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -103,6 +168,112 @@ func TestValidateWorkflow(t *testing.T) {
 	}
 }
 
+func TestMatchWorkflow(t *testing.T) {
+	t.Run("conflicting globs resolve to the last matching pattern", func(t *testing.T) {
+		m := &workflow.MatchSpec{
+			Patterns: []string{"**/*.jpg", "!**/raw/**/*.jpg"},
+		}
+
+		if m.Matches("photos/2024/beach.jpg", "", 0) != true {
+			t.Errorf("expected photos/2024/beach.jpg to match")
+		}
+		if m.Matches("photos/raw/2024/beach.jpg", "", 0) != false {
+			t.Errorf("expected photos/raw/2024/beach.jpg to be excluded by the later pattern")
+		}
+	})
+
+	t.Run("a later re-include pattern overrides an earlier exclusion", func(t *testing.T) {
+		m := &workflow.MatchSpec{
+			Patterns: []string{"**/*.jpg", "!**/thumbs/**", "**/thumbs/keep.jpg"},
+		}
+
+		if m.Matches("album/thumbs/small.jpg", "", 0) != false {
+			t.Errorf("expected album/thumbs/small.jpg to be excluded")
+		}
+		if m.Matches("album/thumbs/keep.jpg", "", 0) != true {
+			t.Errorf("expected album/thumbs/keep.jpg to be re-included by the final pattern")
+		}
+	})
+
+	t.Run("brace expansion matches any listed extension", func(t *testing.T) {
+		m := &workflow.MatchSpec{
+			Patterns: []string{"**/*.{jpg,jpeg,JPG}"},
+		}
+
+		for _, p := range []string{"a/b.jpg", "a/b.jpeg", "a/b.JPG"} {
+			if !m.Matches(p, "", 0) {
+				t.Errorf("expected %s to match brace pattern", p)
+			}
+		}
+		if m.Matches("a/b.png", "", 0) {
+			t.Errorf("expected a/b.png not to match brace pattern")
+		}
+	})
+
+	t.Run("size predicates gate the match", func(t *testing.T) {
+		m := &workflow.MatchSpec{
+			Patterns: []string{"**/*.jpg"},
+			MinSize:  1024,
+			MaxSize:  4096,
+		}
+
+		if m.Matches("a.jpg", "", 512) {
+			t.Errorf("expected file smaller than min_size not to match")
+		}
+		if m.Matches("a.jpg", "", 8192) {
+			t.Errorf("expected file larger than max_size not to match")
+		}
+		if !m.Matches("a.jpg", "", 2048) {
+			t.Errorf("expected file within size range to match")
+		}
+	})
+
+	t.Run("mime_types gates the match", func(t *testing.T) {
+		m := &workflow.MatchSpec{
+			Patterns:  []string{"**/*"},
+			MimeTypes: []string{"image/jpeg"},
+		}
+
+		if !m.Matches("a.jpg", "image/jpeg", 0) {
+			t.Errorf("expected matching mime type to match")
+		}
+		if m.Matches("a.jpg", "image/png", 0) {
+			t.Errorf("expected mismatched mime type not to match")
+		}
+	})
+
+	t.Run("per-directory overrides via priority", func(t *testing.T) {
+		rawWorkflow := &workflow.WorkflowSpec{
+			Name: "raw-passthrough",
+			Inputs: &workflow.InputsSpec{
+				Match: &workflow.MatchSpec{
+					Patterns: []string{"raw/**/*.cr2"},
+					Priority: 10,
+				},
+			},
+		}
+		catchAll := &workflow.WorkflowSpec{
+			Name: "generic-convert",
+			Inputs: &workflow.InputsSpec{
+				Match: &workflow.MatchSpec{
+					Patterns: []string{"**/*.cr2"},
+					Priority: 0,
+				},
+			},
+		}
+
+		rawMatched, rawPriority := rawWorkflow.MatchPriority("raw/2024/photo.cr2", "")
+		catchAllMatched, catchAllPriority := catchAll.MatchPriority("raw/2024/photo.cr2", "")
+
+		if !rawMatched || !catchAllMatched {
+			t.Fatalf("expected both workflows to match raw/2024/photo.cr2")
+		}
+		if rawPriority <= catchAllPriority {
+			t.Errorf("expected raw-passthrough's explicit priority (%d) to outrank the catch-all (%d)", rawPriority, catchAllPriority)
+		}
+	})
+}
+
 func TestGetVariables(t *testing.T) {
 	spec := &workflow.WorkflowSpec{
 		Name:   "test",